@@ -0,0 +1,94 @@
+package cassette
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/yamls"
+)
+
+// Cassette a list of recorded command executions which can be replayed in tests
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction the recorded details and results of running a command
+type Interaction struct {
+	// CLI the command line without the dir or env vars with any sensitive values masked
+	CLI string `json:"cli"`
+	// Dir the directory the command was run in
+	Dir string `json:"dir,omitempty"`
+	// EnvKeys the sorted names of the environment variables set on the command. Values are not recorded
+	EnvKeys []string `json:"envKeys,omitempty"`
+	// Output the text returned by the runner
+	Output string `json:"output,omitempty"`
+	// Stdout the captured standard output
+	Stdout string `json:"stdout,omitempty"`
+	// Stderr the captured standard error
+	Stderr string `json:"stderr,omitempty"`
+	// ExitCode the exit code of the command
+	ExitCode int `json:"exitCode,omitempty"`
+	// Error the error message if the command failed
+	Error string `json:"error,omitempty"`
+}
+
+// Load loads the cassette from the given file name
+func Load(fileName string) (*Cassette, error) {
+	exists, err := files.FileExists(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if file exists %s: %w", fileName, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("cassette file %s does not exist", fileName)
+	}
+	c := &Cassette{}
+	err = yamls.LoadFile(fileName, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cassette %s: %w", fileName, err)
+	}
+	return c, nil
+}
+
+// Save saves the cassette to the given file name creating the parent directory if required
+func (c *Cassette) Save(fileName string) error {
+	err := os.MkdirAll(filepath.Dir(fileName), files.DefaultDirWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for cassette %s: %w", fileName, err)
+	}
+	return yamls.SaveFile(c, fileName)
+}
+
+// NewInteraction creates the interaction for the given command and results masking any sensitive values
+func NewInteraction(c *cmdrunner.Command, output string, err error) Interaction {
+	answer := Interaction{
+		CLI:     cmdrunner.CLI(c),
+		Dir:     c.Dir,
+		EnvKeys: envKeys(c),
+		Output:  c.Redact(output),
+	}
+	if r := c.LastResult(); r != nil {
+		answer.Stdout = c.Redact(r.Stdout)
+		answer.Stderr = c.Redact(r.Stderr)
+		answer.ExitCode = r.ExitCode
+	}
+	if err != nil {
+		answer.Error = err.Error()
+		if answer.ExitCode == 0 {
+			answer.ExitCode = -1
+		}
+	}
+	return answer
+}
+
+func envKeys(c *cmdrunner.Command) []string {
+	var answer []string
+	for k := range c.Env {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
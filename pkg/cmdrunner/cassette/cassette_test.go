@@ -0,0 +1,75 @@
+//go:build unit
+// +build unit
+
+package cassette_test
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/cassette"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/helmer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	testhelpers.SkipForWindows(t, "uses a posix shell")
+
+	fileName := filepath.Join(t.TempDir(), "cassette.yaml")
+
+	recorder := cassette.NewRecorder(fileName, nil)
+	c := cmdrunner.NewCommand("", "sh", "-c", "echo hello; echo warn >&2; exit 2")
+	c.SetEnvVariable("SECRET_TOKEN", "abc")
+	output, err := recorder.Run(c)
+	require.Error(t, err)
+	require.NoError(t, recorder.Save())
+
+	player := cassette.NewPlayer(t, fileName)
+	require.Len(t, player.Cassette.Interactions, 1)
+	interaction := player.Cassette.Interactions[0]
+	assert.Equal(t, []string{"SECRET_TOKEN"}, interaction.EnvKeys)
+	assert.Equal(t, "hello\n", interaction.Stdout)
+	assert.Equal(t, "warn\n", interaction.Stderr)
+	assert.Equal(t, 2, interaction.ExitCode)
+
+	replayed := cmdrunner.NewCommand("", "sh", "-c", "echo hello; echo warn >&2; exit 2")
+	replayed.SetEnvVariable("SECRET_TOKEN", "def")
+	replayedOutput, replayedErr := player.Run(replayed)
+	assert.Equal(t, output, replayedOutput)
+
+	var commandError cmdrunner.CommandError
+	require.True(t, errors.As(replayedErr, &commandError), "should replay a CommandError")
+	assert.Equal(t, 2, commandError.ExitCode)
+	assert.Equal(t, "warn\n", commandError.Stderr)
+	assert.Equal(t, "hello\n", replayed.LastResult().Stdout)
+
+	var replayedError *cassette.ReplayedError
+	require.True(t, errors.As(replayedErr, &replayedError), "should have a ReplayedError cause")
+	assert.Equal(t, 2, replayedError.ExitCode)
+	assert.Equal(t, errors.Unwrap(err).Error(), errors.Unwrap(replayedErr).Error(), "replayed cause message")
+	player.ExpectAllPlayed()
+}
+
+func TestReplayHelm(t *testing.T) {
+	player := cassette.NewPlayer(t, filepath.Join("testdata", "helm_repo_list.yaml"))
+	player.MatchDir = true
+
+	h := helmer.NewHelmCLIWithRunner(player.Run, "helm", "/workspace", false)
+
+	repos, err := h.ListRepos()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"stable":    "https://charts.helm.sh/stable",
+		"jenkins-x": "https://jenkins-x-charts.github.io/v2",
+	}, repos)
+
+	err = h.RemoveRepo("missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `no repo named "missing" found`)
+
+	player.ExpectAllPlayed()
+}
@@ -0,0 +1,173 @@
+package cassette
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+)
+
+// RecordEnvVar the environment variable which when set to true makes NewRunner record new cassettes
+const RecordEnvVar = "CMDRUNNER_RECORD"
+
+// TestingT the methods of testing.TB used to report failures so that this package does not depend on the
+// testing package
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// ReplayedError the cause of a cmdrunner.CommandError replayed from a cassette
+type ReplayedError struct {
+	// ExitCode the recorded exit code of the command
+	ExitCode int
+	// Message the recorded error message
+	Message string
+}
+
+// Error returns the exit status like an exec.ExitError or the recorded message if the command did not exit
+func (e *ReplayedError) Error() string {
+	if e.ExitCode < 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("exit status %d", e.ExitCode)
+}
+
+// Player a command runner which replays the results of a Cassette by matching commands against its interactions
+type Player struct {
+	// Cassette the interactions to replay
+	Cassette *Cassette
+
+	// MatchDir if enabled the directory of the command must match the recorded directory
+	MatchDir bool
+
+	// Commands the commands which have been run
+	Commands []*cmdrunner.Command
+
+	t      TestingT
+	played []bool
+	lock   sync.Mutex
+}
+
+// NewPlayer creates a new player for the cassette in the given file name failing the test if it cannot be loaded
+func NewPlayer(t TestingT, fileName string) *Player {
+	t.Helper()
+	c, err := Load(fileName)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %s", err.Error())
+	}
+	return &Player{
+		Cassette: c,
+		t:        t,
+		played:   make([]bool, len(c.Interactions)),
+	}
+}
+
+// NewRunner returns a Player for the given cassette file unless the $CMDRUNNER_RECORD environment variable is true
+// in which case real commands are run using the given runner and recorded to the file when the test completes
+func NewRunner(t TestingT, fileName string, runner cmdrunner.CommandRunner) cmdrunner.CommandRunner {
+	t.Helper()
+	if os.Getenv(RecordEnvVar) == "true" {
+		r := NewRecorder(fileName, runner)
+		t.Cleanup(func() {
+			err := r.Save()
+			if err != nil {
+				t.Errorf("failed to save cassette %s: %s", fileName, err.Error())
+			}
+		})
+		return r.Run
+	}
+	return NewPlayer(t, fileName).Run
+}
+
+// Run replays the results of the first interaction which has not been played yet and matches the command.
+// If no interaction matches the test fails with a diff against the next unplayed interaction
+func (p *Player) Run(c *cmdrunner.Command) (string, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.Commands = append(p.Commands, c)
+
+	actual := p.toInteraction(c)
+	for i := range p.Cassette.Interactions {
+		if p.played[i] {
+			continue
+		}
+		interaction := p.Cassette.Interactions[i]
+		if cmp.Equal(p.toMatch(interaction), actual) {
+			p.played[i] = true
+			return replay(c, interaction)
+		}
+	}
+
+	expected := Interaction{}
+	for i := range p.Cassette.Interactions {
+		if !p.played[i] {
+			expected = p.toMatch(p.Cassette.Interactions[i])
+			break
+		}
+	}
+	p.t.Errorf("no recorded interaction matches command %s (-next unplayed interaction +actual):\n%s",
+		actual.CLI, cmp.Diff(expected, actual))
+	return "", fmt.Errorf("no recorded interaction matches command %s", actual.CLI)
+}
+
+// ExpectAllPlayed fails the test if any of the interactions were not played
+func (p *Player) ExpectAllPlayed() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var missing []string
+	for i, interaction := range p.Cassette.Interactions {
+		if !p.played[i] {
+			missing = append(missing, interaction.CLI)
+		}
+	}
+	if len(missing) > 0 {
+		p.t.Errorf("interactions were not played:\n%s", strings.Join(missing, "\n"))
+	}
+}
+
+// toInteraction returns the matchable fields of the given command
+func (p *Player) toInteraction(c *cmdrunner.Command) Interaction {
+	answer := Interaction{
+		CLI:     cmdrunner.CLI(c),
+		EnvKeys: envKeys(c),
+	}
+	if p.MatchDir {
+		answer.Dir = c.Dir
+	}
+	return answer
+}
+
+// toMatch returns the matchable fields of the given interaction
+func (p *Player) toMatch(interaction Interaction) Interaction {
+	answer := Interaction{
+		CLI:     interaction.CLI,
+		EnvKeys: interaction.EnvKeys,
+	}
+	if p.MatchDir {
+		answer.Dir = interaction.Dir
+	}
+	return answer
+}
+
+// replay populates the results of the command from the interaction
+func replay(c *cmdrunner.Command, interaction Interaction) (string, error) {
+	result := cmdrunner.Result{
+		Stdout:   interaction.Stdout,
+		Stderr:   interaction.Stderr,
+		ExitCode: interaction.ExitCode,
+	}
+	c.History = append(c.History, result)
+	if interaction.Error == "" && interaction.ExitCode == 0 {
+		return interaction.Output, nil
+	}
+	cause := &ReplayedError{ExitCode: interaction.ExitCode, Message: interaction.Error}
+	return interaction.Output, cmdrunner.NewCommandError(c, interaction.Output, result, cause)
+}
@@ -0,0 +1,50 @@
+package cassette
+
+import (
+	"sync"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+)
+
+// Recorder a command runner which runs real commands and records the results into a Cassette
+type Recorder struct {
+	// FileName the file the cassette is saved to
+	FileName string
+
+	// Runner the runner used to execute the commands
+	Runner cmdrunner.CommandRunner
+
+	// Cassette the recorded interactions
+	Cassette Cassette
+
+	lock sync.Mutex
+}
+
+// NewRecorder creates a new recorder saving to the given file name.
+// If no runner is supplied then cmdrunner.QuietCommandRunner is used
+func NewRecorder(fileName string, runner cmdrunner.CommandRunner) *Recorder {
+	if runner == nil {
+		runner = cmdrunner.QuietCommandRunner
+	}
+	return &Recorder{
+		FileName: fileName,
+		Runner:   runner,
+	}
+}
+
+// Run runs the command and records its results
+func (r *Recorder) Run(c *cmdrunner.Command) (string, error) {
+	output, err := r.Runner(c)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Cassette.Interactions = append(r.Cassette.Interactions, NewInteraction(c, output, err))
+	return output, err
+}
+
+// Save saves the recorded cassette to the file name
+func (r *Recorder) Save() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.Cassette.Save(r.FileName)
+}
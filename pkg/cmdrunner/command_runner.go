@@ -1,7 +1,7 @@
 package cmdrunner
 
 import (
-	"strings"
+	"context"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
@@ -10,6 +10,26 @@ import (
 // CommandRunner represents a command runner so that it can be stubbed out for testing
 type CommandRunner func(*Command) (string, error)
 
+// ContextCommandRunner represents a command runner which is given a context that cancels the command when done
+type ContextCommandRunner func(context.Context, *Command) (string, error)
+
+// WithContext returns a ContextCommandRunner which sets the context on the command before invoking the given runner
+// so that any CommandRunner, such as a fake runner in tests, can be used where a context is available
+func WithContext(runner CommandRunner) ContextCommandRunner {
+	if runner == nil {
+		runner = DefaultCommandRunner
+	}
+	return func(ctx context.Context, c *Command) (string, error) {
+		c.SetContext(ctx)
+		return runner(c)
+	}
+}
+
+// DefaultContextCommandRunner default runner using the given context to cancel the command
+func DefaultContextCommandRunner(ctx context.Context, c *Command) (string, error) {
+	return WithContext(DefaultCommandRunner)(ctx, c)
+}
+
 // DefaultCommandRunner default runner if none is set
 func DefaultCommandRunner(c *Command) (string, error) {
 	if c.Dir == "" {
@@ -19,7 +39,7 @@ func DefaultCommandRunner(c *Command) (string, error) {
 	}
 	result, err := c.RunWithoutRetry()
 	if result != "" {
-		log.Logger().Info(termcolor.ColorStatus(result))
+		log.Logger().Info(termcolor.ColorStatus(c.Redact(result)))
 	}
 	return result, err
 }
@@ -33,7 +53,7 @@ func QuietCommandRunner(c *Command) (string, error) {
 	}
 	result, err := c.RunWithoutRetry()
 	if result != "" {
-		log.Logger().Debug(termcolor.ColorStatus(result))
+		log.Logger().Debug(termcolor.ColorStatus(c.Redact(result)))
 	}
 	return result, err
 }
@@ -44,13 +64,12 @@ func DryRunCommandRunner(c *Command) (string, error) {
 	return "", nil
 }
 
-// CLI returns the CLI string without the dir or env vars
+// CLI returns the CLI string without the dir or env vars with any sensitive values masked
 func CLI(c *Command) string {
-	var builder strings.Builder
-	builder.WriteString(c.Name)
-	for _, arg := range c.Args {
-		builder.WriteString(" ")
-		builder.WriteString(arg)
-	}
-	return builder.String()
+	return c.CLI()
+}
+
+// RawCLI returns the CLI string without the dir or env vars including any sensitive values
+func RawCLI(c *Command) string {
+	return c.RawCLI()
 }
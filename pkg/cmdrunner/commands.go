@@ -1,10 +1,12 @@
 package cmdrunner
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
 	"os/exec"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,17 +15,36 @@ import (
 
 // Command is a struct containing the details of an external command to be executed
 type Command struct {
+	ctx                context.Context
 	attempts           int
 	Errors             []error
 	Dir                string
 	Name               string
 	Args               []string
 	ExponentialBackOff *backoff.ExponentialBackOff
+	RetryPolicy        *RetryPolicy
 	Timeout            time.Duration
 	Out                io.Writer
 	Err                io.Writer
 	In                 io.Reader
 	Env                map[string]string
+	History            []Result
+	// EnvInheritance how the command inherits the environment variables of the current process
+	EnvInheritance EnvInheritance
+	// EnvAllowList the names of the environment variables inherited when using InheritAllowedEnv.
+	// A name ending in '*' matches any variable with that prefix
+	EnvAllowList []string
+	// UnsetEnv the names of inherited environment variables which are removed. A name ending in '*' matches any
+	// variable with that prefix
+	UnsetEnv []string
+	// Secrets values which are masked when the command, its output or errors are logged
+	Secrets []string
+	// SensitiveEnv names of environment variables whose values are masked when logged
+	SensitiveEnv []string
+	// OnStdoutLine if specified is invoked with each line written to stdout while the command is running
+	OnStdoutLine func(line string)
+	// OnStderrLine if specified is invoked with each line written to stderr while the command is running
+	OnStderrLine func(line string)
 }
 
 // NewCommand helper to create a new command
@@ -35,33 +56,81 @@ func NewCommand(dir string, name string, args ...string) *Command {
 	}
 }
 
+// NewCommandWithContext helper to create a new command which is cancelled when the given context is done
+func NewCommandWithContext(ctx context.Context, dir string, name string, args ...string) *Command {
+	c := NewCommand(dir, name, args...)
+	c.SetContext(ctx)
+	return c
+}
+
 // CommandError is the error object encapsulating an error from a Command
 type CommandError struct {
 	Command Command
-	Output  string
+	// Output the combined stdout and stderr of the failed attempt
+	Output   string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Signal   string
+	Duration time.Duration
+	// History the results of every attempt including the failed one
+	History []Result
 	cause   error
 }
 
 func (c CommandError) Error() string {
-	// sanitise any password arguments before printing the error string. The actual sensitive argument is still present
-	// in the Command object
-	sanitisedArgs := make([]string, len(c.Command.Args))
-	copy(sanitisedArgs, c.Command.Args)
-	for i, arg := range sanitisedArgs {
-		if strings.Contains(strings.ToLower(arg), "password") && i < len(sanitisedArgs)-1 {
-			// sanitise the subsequent argument to any 'password' fields
-			sanitisedArgs[i+1] = "*****"
-		}
-	}
-
-	return fmt.Sprintf("failed to run '%s %s' command in directory '%s', output: '%s'",
-		c.Command.Name, strings.Join(sanitisedArgs, " "), c.Command.Dir, c.Output)
+	// sanitise any sensitive arguments before printing the error string. The actual sensitive arguments are still
+	// present in the Command object
+	return fmt.Sprintf("failed to run '%s' command in directory '%s', output: '%s'",
+		c.Command.CLI(), c.Command.Dir, c.Command.Redact(c.Output))
 }
 
 func (c CommandError) Cause() error {
 	return c.cause
 }
 
+func (c CommandError) Unwrap() error {
+	return c.cause
+}
+
+// CancelledError is the error returned when a Command is stopped because its context was cancelled
+// or its deadline was exceeded
+type CancelledError struct {
+	Command Command
+	cause   error
+}
+
+func (c CancelledError) Error() string {
+	return fmt.Sprintf("cancelled running '%s' command in directory '%s': %s", c.Command.Name, c.Command.Dir, c.cause)
+}
+
+func (c CancelledError) Cause() error {
+	return c.cause
+}
+
+func (c CancelledError) Unwrap() error {
+	return c.cause
+}
+
+// IsCancelled returns true if the error was caused by the command's context being cancelled
+func IsCancelled(err error) bool {
+	var cancelled CancelledError
+	return errors.As(err, &cancelled)
+}
+
+// SetContext Setter method for the context used to cancel the command
+func (c *Command) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Context returns the context of the command, defaulting to context.Background() if none is set
+func (c *Command) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
 // SetName Setter method for Name to enable use of interface instead of Command struct
 func (c *Command) SetName(name string) {
 	c.Name = name
@@ -102,6 +171,11 @@ func (c *Command) SetExponentialBackOff(backoff *backoff.ExponentialBackOff) {
 	c.ExponentialBackOff = backoff
 }
 
+// SetRetryPolicy Setter method for RetryPolicy to enable use of interface instead of Command struct
+func (c *Command) SetRetryPolicy(policy *RetryPolicy) {
+	c.RetryPolicy = policy
+}
+
 // SetEnv Setter method for Env to enable use of interface instead of Command struct
 func (c *Command) SetEnv(env map[string]string) {
 	c.Env = env
@@ -149,34 +223,67 @@ func (c *Command) Error() error {
 	return nil
 }
 
-// Run Execute the command and block waiting for return values
+// LastResult returns the result of the last execution of the command or nil if it has not been executed
+func (c *Command) LastResult() *Result {
+	if len(c.History) > 0 {
+		return &c.History[len(c.History)-1]
+	}
+	return nil
+}
+
+// Run Execute the command and block waiting for return values.
+// Failed attempts are retried using the RetryPolicy of the command if there is one, otherwise the ExponentialBackOff
+// or a default exponential backoff bounded by the Timeout. Retrying stops as soon as the context of the command is done.
 func (c *Command) Run() (string, error) {
 	var r string
 	var e error
 
+	ctx := c.Context()
+	policy := c.RetryPolicy
 	f := func() error {
 		r, e = c.run()
 		c.attempts++
 		if e != nil {
 			c.Errors = append(c.Errors, e)
+			if IsCancelled(e) || !policy.retriable(e) {
+				return backoff.Permanent(e)
+			}
 			return e
 		}
 		return nil
 	}
 
-	c.ExponentialBackOff = backoff.NewExponentialBackOff()
-	if c.Timeout == 0 {
-		c.Timeout = 3 * time.Minute
-	}
-	c.ExponentialBackOff.MaxElapsedTime = c.Timeout
-	c.ExponentialBackOff.Reset()
-	err := backoff.Retry(f, c.ExponentialBackOff)
+	err := backoff.Retry(f, backoff.WithContext(c.backOff(), ctx))
 	if err != nil {
+		if ctx.Err() != nil && !IsCancelled(err) {
+			err = c.cancelledError(ctx.Err())
+		}
 		return "", err
 	}
 	return r, nil
 }
 
+// backOff returns the backoff to use for retries, lazily creating an exponential backoff if none has been supplied
+func (c *Command) backOff() backoff.BackOff {
+	var b backoff.BackOff
+	if c.RetryPolicy != nil && c.RetryPolicy.BackOff != nil {
+		b = c.RetryPolicy.BackOff
+	} else {
+		if c.ExponentialBackOff == nil {
+			if c.Timeout == 0 {
+				c.Timeout = 3 * time.Minute
+			}
+			c.ExponentialBackOff = backoff.NewExponentialBackOff()
+			c.ExponentialBackOff.MaxElapsedTime = c.Timeout
+		}
+		b = c.ExponentialBackOff
+	}
+	if c.RetryPolicy != nil && c.RetryPolicy.MaxAttempts > 0 {
+		b = backoff.WithMaxRetries(b, uint64(c.RetryPolicy.MaxAttempts-1))
+	}
+	return b
+}
+
 // RunWithoutRetry Execute the command without retrying on failure and block waiting for return values
 func (c *Command) RunWithoutRetry() (string, error) {
 	r, e := c.run()
@@ -187,28 +294,39 @@ func (c *Command) RunWithoutRetry() (string, error) {
 	return r, e
 }
 
-// String returns the full string with env vars
+// String returns the full string with env vars with any sensitive values masked
 func (c *Command) String() string {
 	var builder strings.Builder
-	for k, v := range c.Env {
+	env := c.RedactedEnv()
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
 		builder.WriteString(k)
 		builder.WriteString("=")
-		builder.WriteString(v)
+		builder.WriteString(env[k])
 		builder.WriteString(" ")
 	}
-	builder.WriteString(c.Name)
-	for _, arg := range c.Args {
-		builder.WriteString(" ")
-		builder.WriteString(arg)
-	}
+	builder.WriteString(c.CLI())
 	return builder.String()
 }
 
-// CLI returns the command line without environment variables
+// CLI returns the command line without environment variables with any sensitive values masked
 func (c *Command) CLI() string {
+	return joinCommandLine(c.Name, c.RedactedArgs())
+}
+
+// RawCLI returns the command line without environment variables including any sensitive values
+func (c *Command) RawCLI() string {
+	return joinCommandLine(c.Name, c.Args)
+}
+
+func joinCommandLine(name string, args []string) string {
 	var builder strings.Builder
-	builder.WriteString(c.Name)
-	for _, arg := range c.Args {
+	builder.WriteString(name)
+	for _, arg := range args {
 		builder.WriteString(" ")
 		builder.WriteString(arg)
 	}
@@ -216,69 +334,89 @@ func (c *Command) CLI() string {
 }
 
 func (c *Command) run() (string, error) {
-	e := exec.Command(c.Name, c.Args...) // #nosec
+	ctx := c.Context()
+	if ctx.Err() != nil {
+		return "", c.cancelledError(ctx.Err())
+	}
+	e := exec.CommandContext(ctx, c.Name, c.Args...) // #nosec
+	if ctx.Done() != nil {
+		// lets make sure we kill any child processes too when the context is cancelled
+		killProcessGroupOnCancel(e)
+	}
 	if c.Dir != "" {
 		e.Dir = c.Dir
 	}
 
 	c.addEnvironmentVariables(e)
 
+	// lets capture stdout and stderr separately as well as combined in the order they were written
+	var stdout, stderr strings.Builder
+	combined := &syncBuilder{}
+	stdoutWriters := []io.Writer{&stdout, combined}
+	stderrWriters := []io.Writer{&stderr, combined}
 	if c.Out != nil {
-		e.Stdout = c.Out
+		stdoutWriters = append(stdoutWriters, c.Out)
 	}
-
 	if c.Err != nil {
-		e.Stderr = c.Err
+		stderrWriters = append(stderrWriters, c.Err)
+	}
+	lineWriters := c.lineWriters()
+	if lineWriters.stdout != nil {
+		stdoutWriters = append(stdoutWriters, lineWriters.stdout)
 	}
+	if lineWriters.stderr != nil {
+		stderrWriters = append(stderrWriters, lineWriters.stderr)
+	}
+	e.Stdout = io.MultiWriter(stdoutWriters...)
+	e.Stderr = io.MultiWriter(stderrWriters...)
 
 	if c.In != nil {
 		e.Stdin = c.In
 	}
 
-	var text string
-	var err error
+	start := time.Now()
+	err := e.Run()
+	lineWriters.flush()
+	result := newResult(stdout.String(), stderr.String(), time.Since(start), err)
+	c.History = append(c.History, result)
 
-	if c.Out != nil {
-		err := e.Run()
-		if err != nil {
-			return text, CommandError{
-				Command: *c,
-				cause:   err,
-			}
-		}
-	} else {
-		data, err := e.CombinedOutput()
-		output := string(data)
-		text = strings.TrimSpace(output)
-		if err != nil {
-			return text, CommandError{
-				Command: *c,
-				Output:  text,
-				cause:   err,
-			}
+	var text string
+	output := strings.TrimSpace(combined.String())
+	if c.Out == nil {
+		text = output
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return text, c.cancelledError(ctx.Err())
 		}
+		return text, NewCommandError(c, text, result, err)
+	}
+	return text, nil
+}
+
+// NewCommandError creates the error of a command which failed with the given output, result and cause such as
+// when replaying the recorded results of a command
+func NewCommandError(c *Command, output string, result Result, cause error) CommandError {
+	return CommandError{
+		Command:  *c,
+		Output:   output,
+		Stdout:   result.Stdout,
+		Stderr:   result.Stderr,
+		ExitCode: result.ExitCode,
+		Signal:   result.Signal,
+		Duration: result.Duration,
+		History:  append([]Result{}, c.History...),
+		cause:    cause,
 	}
+}
 
-	return text, err
+func (c *Command) cancelledError(err error) error {
+	return CancelledError{
+		Command: *c,
+		cause:   err,
+	}
 }
 
 func (c *Command) addEnvironmentVariables(e *exec.Cmd) {
-	if len(c.Env) > 0 {
-		m := map[string]string{}
-		environ := os.Environ()
-		for _, kv := range environ {
-			paths := strings.SplitN(kv, "=", 2)
-			if len(paths) == 2 {
-				m[paths[0]] = paths[1]
-			}
-		}
-		for k, v := range c.Env {
-			m[k] = v
-		}
-		var envVars []string
-		for k, v := range m {
-			envVars = append(envVars, k+"="+v)
-		}
-		e.Env = envVars
-	}
+	e.Env = c.Environ()
 }
@@ -4,15 +4,19 @@
 package cmdrunner_test
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 	"time"
 
+	"github.com/cenkalti/backoff"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -239,3 +243,190 @@ func getFailIteratorScript() string {
 	}
 	return ex
 }
+
+func TestRunCancelled(t *testing.T) {
+	testhelpers.SkipForWindows(t, "Windows doesn't have a decent sleep builtin to run no-interactively")
+	t.Parallel()
+	startPath, err := filepath.Abs("")
+	if err != nil {
+		panic(err)
+	}
+	exPath := filepath.Join(startPath, scriptsDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	cmd := cmdrunner.NewCommandWithContext(ctx, exPath, filepath.Join(exPath, "sleep.sh"), "10")
+	cmd.Timeout = 30 * time.Second
+
+	start := time.Now()
+	_, err = cmd.Run()
+
+	require.Error(t, err, "the command should have been cancelled")
+	assert.True(t, cmdrunner.IsCancelled(err), "expected a CancelledError but got %v", err)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded), "expected the error to wrap the context error")
+	assert.Less(t, time.Since(start), 5*time.Second, "the child processes should have been killed")
+	assert.Equal(t, 1, cmd.Attempts())
+}
+
+func TestRunWithRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		policy           *cmdrunner.RetryPolicy
+		expectedAttempts int
+	}{
+		{
+			name: "max-attempts",
+			policy: &cmdrunner.RetryPolicy{
+				BackOff:     backoff.NewConstantBackOff(10 * time.Millisecond),
+				MaxAttempts: 2,
+			},
+			expectedAttempts: 2,
+		},
+		{
+			name: "not-retriable",
+			policy: &cmdrunner.RetryPolicy{
+				BackOff:   backoff.NewConstantBackOff(10 * time.Millisecond),
+				Retriable: cmdrunner.RegexNoRetryFunction("FAILURE"),
+			},
+			expectedAttempts: 1,
+		},
+		{
+			name: "retriable-exit-code",
+			policy: &cmdrunner.RetryPolicy{
+				BackOff:     backoff.NewConstantBackOff(10 * time.Millisecond),
+				MaxAttempts: 3,
+				Retriable:   cmdrunner.ExitCodeRetryFunction(1),
+			},
+			expectedAttempts: 3,
+		},
+	}
+
+	startPath, err := filepath.Abs("")
+	require.NoError(t, err)
+	exPath := filepath.Join(startPath, scriptsDir)
+
+	for _, tc := range testCases {
+		tmpFileName := "test_run_retry_policy_" + tc.name + ".txt"
+		tempfile, err := os.Create(filepath.Join(exPath, tmpFileName))
+		require.NoError(t, err)
+		tempfile.Close()
+		defer os.Remove(tempfile.Name())
+
+		cmd := cmdrunner.Command{
+			Name:        filepath.Join(exPath, getFailIteratorScript()),
+			Dir:         exPath,
+			Args:        []string{tmpFileName, "100"},
+			RetryPolicy: tc.policy,
+		}
+
+		_, err = cmd.Run()
+
+		assert.Error(t, err, "for %s", tc.name)
+		assert.Equal(t, tc.expectedAttempts, cmd.Attempts(), "attempts for %s", tc.name)
+		assert.Equal(t, true, cmd.DidFail(), "for %s", tc.name)
+	}
+}
+
+func TestRunHonoursExponentialBackOff(t *testing.T) {
+	t.Parallel()
+
+	tmpFileName := "test_run_honours_backoff.txt"
+
+	startPath, err := filepath.Abs("")
+	require.NoError(t, err)
+	exPath := filepath.Join(startPath, scriptsDir)
+	tempfile, err := os.Create(filepath.Join(exPath, tmpFileName))
+	require.NoError(t, err)
+	tempfile.Close()
+	defer os.Remove(tempfile.Name())
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = 10 * time.Millisecond
+	b.MaxElapsedTime = 200 * time.Millisecond
+
+	cmd := cmdrunner.Command{
+		Name: filepath.Join(exPath, getFailIteratorScript()),
+		Dir:  exPath,
+		Args: []string{tmpFileName, "100"},
+	}
+	cmd.SetExponentialBackOff(b)
+
+	start := time.Now()
+	_, err = cmd.Run()
+
+	assert.Error(t, err, errorMessage)
+	assert.Less(t, time.Since(start), 5*time.Second, "the supplied backoff should limit the retries")
+	assert.Same(t, b, cmd.ExponentialBackOff)
+}
+
+func TestRunCapturesStdoutAndStderr(t *testing.T) {
+	testhelpers.SkipForWindows(t, "uses a posix shell")
+	t.Parallel()
+
+	cmd := cmdrunner.NewCommand("", "sh", "-c", `echo '{"a": 1}'; echo 'WARNING: deprecated' >&2; exit 3`)
+
+	res, err := cmd.RunWithoutRetry()
+	require.Error(t, err, "the command should fail")
+
+	assert.Contains(t, res, "WARNING: deprecated")
+	assert.Contains(t, res, `{"a": 1}`)
+
+	var commandError cmdrunner.CommandError
+	require.True(t, errors.As(err, &commandError), "should be a CommandError but was %v", err)
+	assert.Equal(t, "{\"a\": 1}\n", commandError.Stdout)
+	assert.Equal(t, "WARNING: deprecated\n", commandError.Stderr)
+	assert.Equal(t, 3, commandError.ExitCode)
+	assert.Empty(t, commandError.Signal)
+	assert.Len(t, commandError.History, 1)
+
+	result := cmd.LastResult()
+	require.NotNil(t, result, "should have a result")
+	assert.Equal(t, "{\"a\": 1}\n", result.Stdout)
+	assert.Equal(t, 3, result.ExitCode)
+	assert.True(t, result.Duration > 0, "should have a duration")
+}
+
+func TestRunStreamsLines(t *testing.T) {
+	testhelpers.SkipForWindows(t, "uses a posix shell")
+	t.Parallel()
+
+	var stdoutLines, stderrLines []string
+	cmd := cmdrunner.NewCommand("", "sh", "-c", `echo one; echo warn >&2; echo two; printf three`)
+	cmd.OnStdoutLine = func(line string) {
+		stdoutLines = append(stdoutLines, line)
+	}
+	cmd.OnStderrLine = func(line string) {
+		stderrLines = append(stderrLines, line)
+	}
+
+	res, err := cmd.RunWithoutRetry()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"one", "two", "three"}, stdoutLines)
+	assert.Equal(t, []string{"warn"}, stderrLines)
+	assert.Contains(t, res, "three", "should still return the captured output")
+	assert.Equal(t, "one\ntwo\nthree", cmd.LastResult().Stdout)
+}
+
+func TestRunStreamsCarriageReturnLines(t *testing.T) {
+	testhelpers.SkipForWindows(t, "uses a posix shell")
+	t.Parallel()
+
+	var stdoutLines, stderrLines []string
+	cmd := cmdrunner.NewCommand("", "sh", "-c", `printf 'one\r\n'; printf 'tw'; printf 'o\r'; printf '\nthree\n'; printf 'Receiving 50%%\rReceiving 100%%\rdone\n' >&2`)
+	cmd.OnStdoutLine = func(line string) {
+		stdoutLines = append(stdoutLines, line)
+	}
+	cmd.OnStderrLine = func(line string) {
+		stderrLines = append(stderrLines, line)
+	}
+
+	_, err := cmd.RunWithoutRetry()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"one", "two", "three"}, stdoutLines, "carriage return and new line should end a single line")
+	assert.Equal(t, []string{"Receiving 50%", "Receiving 100%", "done"}, stderrLines, "carriage returns should end progress lines")
+}
@@ -0,0 +1,183 @@
+package cmdrunner
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+var (
+	safeShellWordRegex = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+	shellNameRegex     = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// DryRunScript a dry run command runner which gathers the commands into a POSIX shell script that can be reviewed
+// and replayed later rather than running them
+type DryRunScript struct {
+	// Redact if enabled sensitive values are masked in the script
+	Redact bool
+
+	// Outputs canned outputs returned for commands with the given command line. Use this for commands whose output
+	// drives later logic
+	Outputs map[string]string
+
+	// OutputRunner if specified is invoked to return the output of commands which are not in Outputs
+	OutputRunner CommandRunner
+
+	// Commands the commands gathered so far
+	Commands []*Command
+
+	lock sync.Mutex
+}
+
+// NewDryRunScript creates a new dry run script runner
+func NewDryRunScript(redact bool) *DryRunScript {
+	return &DryRunScript{
+		Redact:  redact,
+		Outputs: map[string]string{},
+	}
+}
+
+// Run gathers the command into the script and returns any canned output without running it
+func (s *DryRunScript) Run(c *Command) (string, error) {
+	s.lock.Lock()
+	s.Commands = append(s.Commands, c)
+	s.lock.Unlock()
+
+	log.Logger().Info(CLI(c))
+
+	if output, ok := s.Outputs[RawCLI(c)]; ok {
+		return output, nil
+	}
+	if s.OutputRunner != nil {
+		return s.OutputRunner(c)
+	}
+	return "", nil
+}
+
+// Script returns the gathered commands as a POSIX shell script. Each command is run in a sub shell so that
+// changing directory and exporting environment variables do not affect later commands
+func (s *DryRunScript) Script() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var builder strings.Builder
+	builder.WriteString("#!/bin/sh\n")
+	builder.WriteString("set -e\n")
+	for _, c := range s.Commands {
+		builder.WriteString("\n")
+		builder.WriteString(s.commandScript(c))
+	}
+	return builder.String()
+}
+
+// WriteFile writes the script to the given file which can be run with 'sh'
+func (s *DryRunScript) WriteFile(fileName string) error {
+	err := os.WriteFile(fileName, []byte(s.Script()), files.DefaultFileWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to save script %s: %w", fileName, err)
+	}
+	return nil
+}
+
+func (s *DryRunScript) commandScript(c *Command) string {
+	args := c.Args
+	env := c.Env
+	if s.Redact {
+		args = c.RedactedArgs()
+		env = c.RedactedEnv()
+	}
+
+	var lines []string
+	if c.Dir != "" {
+		lines = append(lines, "cd "+ShellQuote(c.Dir))
+	}
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	words := []string{ShellQuote(c.Name)}
+	for _, arg := range args {
+		words = append(words, ShellQuote(arg))
+	}
+	command := strings.Join(words, " ")
+	if c.EnvInheritance == InheritAllEnv {
+		for _, name := range c.UnsetEnv {
+			if !strings.HasSuffix(name, "*") {
+				lines = append(lines, "unset "+name)
+			}
+		}
+		for _, k := range keys {
+			lines = append(lines, fmt.Sprintf("export %s=%s", k, ShellQuote(env[k])))
+		}
+	} else {
+		// the environment is passed to env so there is no need to export or unset variables
+		command = "env -i " + inheritedEnvAssignments(c) + envAssignments(keys, env) + command
+	}
+	lines = append(lines, command)
+	return "(\n  " + strings.Join(lines, "\n  ") + "\n)\n"
+}
+
+// inheritedEnvAssignments returns the env arguments which pass the allowed environment variables of the shell
+// running the script. Names ending in '*' are expanded using the environment of the current process
+func inheritedEnvAssignments(c *Command) string {
+	if c.EnvInheritance != InheritAllowedEnv {
+		return ""
+	}
+	names := map[string]bool{}
+	for _, name := range c.EnvAllowList {
+		if !strings.HasSuffix(name, "*") {
+			names[name] = true
+		}
+	}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if matchesEnvName(c.EnvAllowList, name) {
+			names[name] = true
+		}
+	}
+	var keys []string
+	for name := range names {
+		if _, ok := c.Env[name]; ok || matchesEnvName(c.UnsetEnv, name) || !shellNameRegex.MatchString(name) {
+			continue
+		}
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, k := range keys {
+		builder.WriteString(fmt.Sprintf(`%s="$%s" `, k, k))
+	}
+	return builder.String()
+}
+
+func envAssignments(keys []string, env map[string]string) string {
+	var builder strings.Builder
+	for _, k := range keys {
+		builder.WriteString(k)
+		builder.WriteString("=")
+		builder.WriteString(ShellQuote(env[k]))
+		builder.WriteString(" ")
+	}
+	return builder.String()
+}
+
+// ShellQuote quotes the text so that it is treated as a single word by a POSIX shell
+func ShellQuote(text string) string {
+	if text == "" {
+		return "''"
+	}
+	if safeShellWordRegex.MatchString(text) {
+		return text
+	}
+	return "'" + strings.ReplaceAll(text, "'", `'\''`) + "'"
+}
@@ -0,0 +1,102 @@
+//go:build unit
+// +build unit
+
+package cmdrunner_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDryRunScript(t *testing.T) {
+	testhelpers.SkipForWindows(t, "uses a posix shell")
+	t.Parallel()
+
+	tmpDir := t.TempDir()
+	subDir := filepath.Join(tmpDir, "my dir")
+	require.NoError(t, os.MkdirAll(subDir, 0755))
+
+	script := cmdrunner.NewDryRunScript(false)
+	script.Outputs["git rev-parse HEAD"] = "abc123"
+
+	sha, err := script.Run(cmdrunner.NewCommand(tmpDir, "git", "rev-parse", "HEAD"))
+	require.NoError(t, err)
+	assert.Equal(t, "abc123", sha)
+
+	c := cmdrunner.NewCommand(subDir, "sh", "-c", `echo "it's $GREETING in $(basename "$PWD")" > out.txt`)
+	c.SetEnvVariable("GREETING", "hello $world")
+	_, err = script.Run(c)
+	require.NoError(t, err)
+
+	text := script.Script()
+	t.Logf("generated script:\n%s\n", text)
+	assert.Contains(t, text, "cd "+cmdrunner.ShellQuote(subDir))
+	assert.Contains(t, text, "export GREETING='hello $world'")
+
+	// lets drop the git command and replay the script to check the quoting
+	script.Commands = script.Commands[1:]
+	fileName := filepath.Join(tmpDir, "script.sh")
+	require.NoError(t, script.WriteFile(fileName))
+	out, err := exec.Command("sh", fileName).CombinedOutput()
+	require.NoError(t, err, "failed to run script: %s", string(out))
+
+	data, err := os.ReadFile(filepath.Join(subDir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "it's hello $world in my dir\n", string(data))
+}
+
+func TestDryRunScriptRedacts(t *testing.T) {
+	t.Parallel()
+
+	script := cmdrunner.NewDryRunScript(true)
+	c := cmdrunner.NewCommand("", "helm", "repo", "add", "acme", "https://charts.acme.com", "--password", "s3cr3t")
+	c.SetEnvVariable("GIT_TOKEN", "ghp_abc")
+	_, err := script.Run(c)
+	require.NoError(t, err)
+
+	text := script.Script()
+	assert.NotContains(t, text, "s3cr3t")
+	assert.NotContains(t, text, "ghp_abc")
+	assert.Contains(t, text, "helm repo add acme https://charts.acme.com --password '*****'")
+}
+
+func TestDryRunScriptAllowedEnv(t *testing.T) {
+	testhelpers.SkipForWindows(t, "uses a posix shell")
+
+	t.Setenv("DRY_RUN_ALLOWED_ONE", "one")
+	t.Setenv("DRY_RUN_ALLOWED_TWO", "two $2")
+	t.Setenv("DRY_RUN_UNSET", "unset")
+	t.Setenv("DRY_RUN_OTHER", "other")
+
+	tmpDir := t.TempDir()
+	script := cmdrunner.NewDryRunScript(false)
+	c := cmdrunner.NewCommand(tmpDir, "sh", "-c", `env | grep '^DRY_RUN_' | sort > out.txt`)
+	c.SetEnvInheritance(cmdrunner.InheritAllowedEnv, "PATH", "DRY_RUN_ALLOWED_*", "DRY_RUN_UNSET")
+	c.UnsetEnvVariables("DRY_RUN_UNSET")
+	c.SetEnvVariable("DRY_RUN_EXPLICIT", "explicit")
+	_, err := script.Run(c)
+	require.NoError(t, err)
+
+	text := script.Script()
+	assert.Contains(t, text, `env -i DRY_RUN_ALLOWED_ONE="$DRY_RUN_ALLOWED_ONE" DRY_RUN_ALLOWED_TWO="$DRY_RUN_ALLOWED_TWO" PATH="$PATH" DRY_RUN_EXPLICIT=explicit sh -c`)
+	assert.NotContains(t, text, "export", "env -i makes exports redundant")
+
+	fileName := filepath.Join(tmpDir, "script.sh")
+	require.NoError(t, script.WriteFile(fileName))
+	info, err := os.Stat(fileName)
+	require.NoError(t, err)
+	assert.Zero(t, info.Mode().Perm()&0o111, "script should be written with the file permissions")
+
+	out, err := exec.Command("sh", fileName).CombinedOutput()
+	require.NoError(t, err, "failed to run script: %s", string(out))
+	data, err := os.ReadFile(filepath.Join(tmpDir, "out.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "DRY_RUN_ALLOWED_ONE=one\nDRY_RUN_ALLOWED_TWO=two $2\nDRY_RUN_EXPLICIT=explicit\n", string(data))
+}
@@ -0,0 +1,82 @@
+package cmdrunner
+
+import (
+	"os"
+	"sort"
+	"strings"
+)
+
+// EnvInheritance defines which environment variables of the current process a command inherits
+type EnvInheritance int
+
+const (
+	// InheritAllEnv the command inherits all of the environment variables of the current process
+	InheritAllEnv EnvInheritance = iota
+
+	// InheritNoEnv the command only has the environment variables in its Env
+	InheritNoEnv
+
+	// InheritAllowedEnv the command inherits only the environment variables in its EnvAllowList
+	InheritAllowedEnv
+)
+
+// SetEnvInheritance Setter method for EnvInheritance and EnvAllowList to enable use of interface instead of Command struct
+func (c *Command) SetEnvInheritance(inheritance EnvInheritance, allowList ...string) {
+	c.EnvInheritance = inheritance
+	c.EnvAllowList = allowList
+}
+
+// UnsetEnvVariables removes the given inherited environment variables from the environment of the command
+func (c *Command) UnsetEnvVariables(names ...string) {
+	c.UnsetEnv = append(c.UnsetEnv, names...)
+}
+
+// Environ returns the sorted environment of the command in the form used by os/exec or nil if the command
+// inherits the environment of the current process unchanged
+func (c *Command) Environ() []string {
+	if c.EnvInheritance == InheritAllEnv && len(c.UnsetEnv) == 0 && len(c.Env) == 0 {
+		return nil
+	}
+	m := map[string]string{}
+	if c.EnvInheritance != InheritNoEnv {
+		for _, kv := range os.Environ() {
+			paths := strings.SplitN(kv, "=", 2)
+			if len(paths) != 2 {
+				continue
+			}
+			name := paths[0]
+			if c.EnvInheritance == InheritAllowedEnv && !matchesEnvName(c.EnvAllowList, name) {
+				continue
+			}
+			if matchesEnvName(c.UnsetEnv, name) {
+				continue
+			}
+			m[name] = paths[1]
+		}
+	}
+	for k, v := range c.Env {
+		m[k] = v
+	}
+
+	// lets make sure we return a non nil slice so that an empty environment is not treated as inheriting everything
+	envVars := make([]string, 0, len(m))
+	for k, v := range m {
+		envVars = append(envVars, k+"="+v)
+	}
+	sort.Strings(envVars)
+	return envVars
+}
+
+// matchesEnvName returns true if the name matches any of the names or prefixes ending in '*'
+func matchesEnvName(names []string, name string) bool {
+	for _, n := range names {
+		if strings.HasSuffix(n, "*") {
+			if strings.HasPrefix(name, strings.TrimSuffix(n, "*")) {
+				return true
+			}
+		} else if n == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+//go:build unit
+// +build unit
+
+package cmdrunner_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnviron(t *testing.T) {
+	t.Setenv("HELM_NAMESPACE", "leaked")
+	t.Setenv("HELM_DEBUG", "true")
+	t.Setenv("GIT_DIR", "/tmp/leaked")
+	t.Setenv("PATH", "/usr/bin")
+
+	c := cmdrunner.NewCommand("", "helm", "version")
+	assert.Nil(t, c.Environ(), "should inherit the environment unchanged")
+
+	c.UnsetEnvVariables("HELM_*", "GIT_DIR")
+	env := c.Environ()
+	assert.Contains(t, env, "PATH=/usr/bin")
+	assert.NotContains(t, env, "HELM_NAMESPACE=leaked")
+	assert.NotContains(t, env, "HELM_DEBUG=true")
+	assert.NotContains(t, env, "GIT_DIR=/tmp/leaked")
+
+	c = cmdrunner.NewCommand("", "helm", "version")
+	c.SetEnvInheritance(cmdrunner.InheritNoEnv)
+	assert.NotNil(t, c.Environ(), "an empty environment should not be nil")
+	assert.Empty(t, c.Environ())
+
+	c.SetEnvVariable("HELM_NAMESPACE", "jx")
+	assert.Equal(t, []string{"HELM_NAMESPACE=jx"}, c.Environ())
+
+	c = cmdrunner.NewCommand("", "git", "status")
+	c.SetEnvInheritance(cmdrunner.InheritAllowedEnv, "PATH", "HELM_*")
+	c.UnsetEnvVariables("HELM_DEBUG")
+	c.SetEnvVariable("HOME", "/home/jx")
+	assert.Equal(t, []string{"HELM_NAMESPACE=leaked", "HOME=/home/jx", "PATH=/usr/bin"}, c.Environ())
+}
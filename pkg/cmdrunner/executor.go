@@ -0,0 +1,152 @@
+package cmdrunner
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/errorutil"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// DefaultMaxConcurrency the default maximum number of commands an Executor runs at the same time
+const DefaultMaxConcurrency = 4
+
+// Executor runs a batch of independent commands in parallel with bounded concurrency
+type Executor struct {
+	// Runner the runner used for each command. Defaults to QuietCommandRunner
+	Runner CommandRunner
+
+	// MaxConcurrency the maximum number of commands to run at the same time. Defaults to DefaultMaxConcurrency
+	MaxConcurrency int
+
+	// FailFast if enabled the first failure cancels any running commands and skips those not yet started.
+	// Otherwise all commands are run and all errors are collected
+	FailFast bool
+}
+
+// ExecutorResult the result of running a command in a batch
+type ExecutorResult struct {
+	// Command the command which was run
+	Command *Command
+
+	// Output the output returned by the runner
+	Output string
+
+	// Err the error returned by the runner
+	Err error
+
+	// Skipped true if the command was not run due to an earlier failure or the context being done
+	Skipped bool
+}
+
+// NewExecutor creates a new executor using the given runner and maximum concurrency
+func NewExecutor(runner CommandRunner, maxConcurrency int) *Executor {
+	return &Executor{
+		Runner:         runner,
+		MaxConcurrency: maxConcurrency,
+	}
+}
+
+// Run runs the commands returning the results in the same order as the commands along with an aggregate of the
+// errors which is nil if all of the commands succeeded. The commands are cancelled when the context is done or, in
+// fail fast mode, when a command fails. Any context already set on a command is kept and also cancels it
+func (e *Executor) Run(ctx context.Context, commands ...*Command) ([]ExecutorResult, errorutil.Aggregate) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	runner := e.Runner
+	if runner == nil {
+		runner = QuietCommandRunner
+	}
+	maxConcurrency := e.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = DefaultMaxConcurrency
+	}
+
+	// lets make sure the logger is lazily initialised before any runners log concurrently
+	log.Logger()
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	results := make([]ExecutorResult, len(commands))
+	semaphore := make(chan struct{}, maxConcurrency)
+	wg := sync.WaitGroup{}
+	lock := sync.Mutex{}
+	failed := false
+
+	for i, c := range commands {
+		results[i].Command = c
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			results[i].Skipped = true
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *ExecutorResult) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+
+			restore := setCommandContext(ctx, r.Command)
+			defer restore()
+			r.Output, r.Err = runner(r.Command)
+			if r.Err != nil && e.FailFast {
+				lock.Lock()
+				if !IsCancelled(r.Err) {
+					failed = true
+				}
+				lock.Unlock()
+				cancel()
+			}
+		}(&results[i])
+	}
+	wg.Wait()
+
+	var errs []error
+	for i := range results {
+		r := &results[i]
+		if r.Err == nil {
+			continue
+		}
+		// lets not report the commands cancelled by fail fast as failures
+		if failed && IsCancelled(r.Err) {
+			r.Skipped = true
+			continue
+		}
+		errs = append(errs, fmt.Errorf("command %s failed: %w", CLI(r.Command), r.Err))
+	}
+	if parent.Err() != nil && !failed {
+		errs = append(errs, fmt.Errorf("batch of commands did not complete: %w", parent.Err()))
+	}
+	return results, errorutil.NewAggregate(errs)
+}
+
+// setCommandContext sets the context of the command so that it is cancelled by the executor context. If the command
+// already has a context a child of it is used which is also cancelled by the executor context so that the context of
+// the caller is still honoured. The returned function restores the original context of the command
+func setCommandContext(ctx context.Context, c *Command) func() {
+	original := c.ctx
+	if original == nil {
+		c.SetContext(ctx)
+		return func() {
+			c.SetContext(original)
+		}
+	}
+	child, cancel := context.WithCancel(original)
+	stop := context.AfterFunc(ctx, cancel)
+	c.SetContext(child)
+	return func() {
+		stop()
+		cancel()
+		c.SetContext(original)
+	}
+}
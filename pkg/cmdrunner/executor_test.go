@@ -0,0 +1,124 @@
+//go:build unit
+// +build unit
+
+package cmdrunner_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/testhelpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutorCollectsAllErrors(t *testing.T) {
+	t.Parallel()
+
+	var running, maxRunning int32
+	runner := &fakerunner.FakeRunner{
+		CommandRunner: func(c *cmdrunner.Command) (string, error) {
+			n := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				m := atomic.LoadInt32(&maxRunning)
+				if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			if c.Args[0] == "fail" {
+				return "", errors.New("boom")
+			}
+			return "fetched " + c.Dir, nil
+		},
+	}
+
+	var commands []*cmdrunner.Command
+	for _, dir := range []string{"a", "b", "c", "d", "e", "f"} {
+		arg := "fetch"
+		if dir == "b" || dir == "e" {
+			arg = "fail"
+		}
+		commands = append(commands, cmdrunner.NewCommand(dir, "git", arg))
+	}
+
+	executor := cmdrunner.NewExecutor(runner.Run, 2)
+	results, err := executor.Run(context.Background(), commands...)
+
+	require.Error(t, err)
+	assert.Len(t, err.Errors(), 2)
+	require.Len(t, results, 6)
+	assert.Equal(t, "fetched a", results[0].Output)
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, "fetched f", results[5].Output)
+	assert.LessOrEqual(t, maxRunning, int32(2), "should not run more than 2 commands at once")
+	assert.Len(t, runner.OrderedCommands, 6)
+}
+
+func TestExecutorFailFast(t *testing.T) {
+	testhelpers.SkipForWindows(t, "uses a posix shell")
+	t.Parallel()
+
+	executor := &cmdrunner.Executor{
+		Runner:         cmdrunner.QuietCommandRunner,
+		MaxConcurrency: 2,
+		FailFast:       true,
+	}
+
+	start := time.Now()
+	results, err := executor.Run(context.Background(),
+		cmdrunner.NewCommand("", "sh", "-c", "sleep 10"),
+		cmdrunner.NewCommand("", "sh", "-c", "exit 1"),
+		cmdrunner.NewCommand("", "sh", "-c", "sleep 10"),
+	)
+
+	require.Error(t, err)
+	assert.Len(t, err.Errors(), 1, "only the failed command should be reported")
+	assert.Less(t, time.Since(start), 5*time.Second, "the running command should have been cancelled")
+	assert.True(t, results[0].Skipped, "the running command should have been cancelled")
+	assert.Error(t, results[1].Err)
+	assert.True(t, results[2].Skipped, "the last command should not have been started")
+}
+
+func TestExecutorKeepsCommandContext(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	commandCtx, cancelCommand := context.WithCancel(context.WithValue(context.Background(), key{}, "caller"))
+	cancelCommand()
+	c := cmdrunner.NewCommandWithContext(commandCtx, "", "git", "fetch")
+
+	executorCtx, cancelExecutor := context.WithCancel(context.Background())
+	runner := func(c *cmdrunner.Command) (string, error) {
+		ctx := c.Context()
+		assert.Equal(t, "caller", ctx.Value(key{}), "the command context value should be kept")
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	results, err := cmdrunner.NewExecutor(runner, 1).Run(executorCtx, c)
+	require.Error(t, err, "the cancelled command context should cancel the command")
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+	assert.Equal(t, commandCtx, c.Context(), "the command context should be restored")
+
+	// the executor context also cancels the command
+	c = cmdrunner.NewCommandWithContext(context.Background(), "", "git", "fetch")
+	started := make(chan struct{})
+	go func() {
+		<-started
+		cancelExecutor()
+	}()
+	runner = func(c *cmdrunner.Command) (string, error) {
+		close(started)
+		<-c.Context().Done()
+		return "", c.Context().Err()
+	}
+	results, err = cmdrunner.NewExecutor(runner, 1).Run(executorCtx, c)
+	require.Error(t, err, "the cancelled executor context should cancel the command")
+	assert.ErrorIs(t, results[0].Err, context.Canceled)
+}
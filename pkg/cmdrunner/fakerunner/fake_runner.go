@@ -1,7 +1,11 @@
 package fakerunner
 
 import (
+	"fmt"
+	"regexp"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
@@ -25,6 +29,94 @@ type FakeRunner struct {
 
 	// ResultError default error output if no CommandRunner
 	ResultError error
+
+	// Rules if specified the output and error of the first matching rule are returned
+	Rules []FakeRule
+
+	// Strict if enabled commands which do not match any rule return an error
+	Strict bool
+
+	// UnmatchedCommands the commands which did not match any rule
+	UnmatchedCommands []*cmdrunner.Command
+
+	lock sync.Mutex
+}
+
+// FakeRule returns the output and error for commands which match it.
+// All of the matchers which are specified must match the command
+type FakeRule struct {
+	// CLI matches the exact command line
+	CLI string
+
+	// Prefix matches the start of the command line
+	Prefix string
+
+	// Regex matches the command line against the regular expression
+	Regex string
+
+	// Dir matches the directory of the command
+	Dir string
+
+	// Env matches the environment variables of the command
+	Env map[string]string
+
+	// Output the output returned for matching commands
+	Output string
+
+	// Error the error returned for matching commands
+	Error error
+
+	// Times the maximum number of times the rule can be used. Zero means unlimited
+	Times int
+
+	used int
+}
+
+// Matches returns true if the command matches the rule
+func (r *FakeRule) Matches(c *cmdrunner.Command) bool {
+	cli := cmdrunner.RawCLI(c)
+	if r.CLI != "" && r.CLI != cli {
+		return false
+	}
+	if r.Prefix != "" && !strings.HasPrefix(cli, r.Prefix) {
+		return false
+	}
+	if r.Regex != "" {
+		re, err := regexp.Compile(r.Regex)
+		if err != nil || !re.MatchString(cli) {
+			return false
+		}
+	}
+	if r.Dir != "" && r.Dir != c.Dir {
+		return false
+	}
+	for k, v := range r.Env {
+		if c.Env == nil || c.Env[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns a description of the rule
+func (r *FakeRule) String() string {
+	var matchers []string
+	if r.CLI != "" {
+		matchers = append(matchers, fmt.Sprintf("cli=%q", r.CLI))
+	}
+	if r.Prefix != "" {
+		matchers = append(matchers, fmt.Sprintf("prefix=%q", r.Prefix))
+	}
+	if r.Regex != "" {
+		matchers = append(matchers, fmt.Sprintf("regex=%q", r.Regex))
+	}
+	if r.Dir != "" {
+		matchers = append(matchers, fmt.Sprintf("dir=%q", r.Dir))
+	}
+	if len(r.Env) > 0 {
+		matchers = append(matchers, fmt.Sprintf("env=%v", r.Env))
+	}
+	return strings.Join(matchers, " ")
 }
 
 // FakeResult the expected results
@@ -36,27 +128,77 @@ type FakeResult struct {
 
 // Run the default implementation
 func (f *FakeRunner) Run(c *cmdrunner.Command) (string, error) {
+	f.lock.Lock()
 	f.Commands = append(f.Commands, c)
 	f.OrderedCommands = append(f.OrderedCommands, c)
+	f.lock.Unlock()
 
 	if f.CommandRunner != nil {
 		return f.CommandRunner(c)
 	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if r.Times > 0 && r.used >= r.Times {
+			continue
+		}
+		if r.Matches(c) {
+			r.used++
+			return r.Output, r.Error
+		}
+	}
+	if len(f.Rules) > 0 || f.Strict {
+		f.UnmatchedCommands = append(f.UnmatchedCommands, c)
+	}
+	if f.Strict {
+		return "", fmt.Errorf("no fake rule matches command %s", cmdrunner.RawCLI(c))
+	}
 	return f.ResultOutput, f.ResultError
 }
 
-// Expects expects the given results
+// AddRule adds a rule to the runner
+func (f *FakeRunner) AddRule(rule FakeRule) *FakeRunner {
+	f.Rules = append(f.Rules, rule)
+	return f
+}
+
+// ExpectRulesUsed expects every rule to have been used and any rules with Times to have been used that many times
+func (f *FakeRunner) ExpectRulesUsed(t *testing.T) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if r.Times > 0 {
+			assert.Equal(t, r.Times, r.used, "invocations of rule %d: %s", i+1, r.String())
+		} else {
+			assert.True(t, r.used > 0, "rule %d was not used: %s", i+1, r.String())
+		}
+	}
+}
+
+// ExpectNoUnmatchedCommands expects every command to have matched a rule
+func (f *FakeRunner) ExpectNoUnmatchedCommands(t *testing.T) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	for _, c := range f.UnmatchedCommands {
+		assert.Fail(t, "unmatched command", "no fake rule matches command %s", cmdrunner.RawCLI(c))
+	}
+}
+
+// ExpectResults expects the given results in any order
 func (f *FakeRunner) ExpectResults(t *testing.T, results ...FakeResult) {
 	commands := f.Commands
 	for _, c := range commands {
-		t.Logf("got command %s\n", cmdrunner.CLI(c))
+		t.Logf("got command %s\n", cmdrunner.RawCLI(c))
 	}
 
 	require.Equal(t, len(results), len(commands), "expected command invocations")
 
 	sort.Slice(commands, func(i, j int) bool {
-		c1 := cmdrunner.CLI(commands[i])
-		c2 := cmdrunner.CLI(commands[j])
+		c1 := cmdrunner.RawCLI(commands[i])
+		c2 := cmdrunner.RawCLI(commands[j])
 		return c1 < c2
 	})
 
@@ -67,19 +209,36 @@ func (f *FakeRunner) ExpectResults(t *testing.T, results ...FakeResult) {
 	})
 
 	for i, r := range results {
-		c := commands[i]
-		assert.Equal(t, r.CLI, cmdrunner.CLI(c), "command line for command %d", i+1)
-		if r.Dir != "" {
-			assert.Equal(t, r.Dir, c.Dir, "directory line for command %d", i+1)
-		}
-		if r.Env != nil {
-			for k, v := range r.Env {
-				actual := ""
-				if c.Env != nil {
-					actual = c.Env[k]
-				}
-				assert.Equal(t, v, actual, "$%s for command %d", k, i+1)
+		assertResult(t, r, commands[i], i+1)
+	}
+}
+
+// ExpectOrderedResults expects the given results in the order the commands were run
+func (f *FakeRunner) ExpectOrderedResults(t *testing.T, results ...FakeResult) {
+	commands := f.OrderedCommands
+	for _, c := range commands {
+		t.Logf("got command %s\n", cmdrunner.RawCLI(c))
+	}
+
+	require.Equal(t, len(results), len(commands), "expected command invocations")
+
+	for i, r := range results {
+		assertResult(t, r, commands[i], i+1)
+	}
+}
+
+func assertResult(t *testing.T, r FakeResult, c *cmdrunner.Command, n int) {
+	assert.Equal(t, r.CLI, cmdrunner.RawCLI(c), "command line for command %d", n)
+	if r.Dir != "" {
+		assert.Equal(t, r.Dir, c.Dir, "directory line for command %d", n)
+	}
+	if r.Env != nil {
+		for k, v := range r.Env {
+			actual := ""
+			if c.Env != nil {
+				actual = c.Env[k]
 			}
+			assert.Equal(t, v, actual, "$%s for command %d", k, n)
 		}
 	}
 }
@@ -0,0 +1,52 @@
+//go:build unit
+// +build unit
+
+package fakerunner_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner/fakerunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeRunnerRules(t *testing.T) {
+	runner := &fakerunner.FakeRunner{Strict: true}
+	runner.AddRule(fakerunner.FakeRule{
+		CLI:    "git status -s",
+		Output: " M README.md",
+		Times:  1,
+	}).AddRule(fakerunner.FakeRule{
+		Prefix: "git commit",
+		Dir:    "/workspace",
+	}).AddRule(fakerunner.FakeRule{
+		Regex: `^git push origin .*`,
+		Error: errors.New("rejected"),
+	})
+
+	g := cli.NewCLIClient("", runner.Run)
+
+	err := gitclient.CommitIfChanges(g, "/workspace", "chore: update")
+	require.NoError(t, err)
+
+	changed, err := gitclient.HasChanges(g, "/workspace")
+	assert.Error(t, err, "the status rule should have been used up")
+	assert.False(t, changed)
+
+	err = gitclient.Push(g, "/workspace", "origin", false, "main")
+	assert.Error(t, err)
+
+	runner.ExpectRulesUsed(t)
+	assert.Len(t, runner.UnmatchedCommands, 1)
+
+	runner.ExpectOrderedResults(t,
+		fakerunner.FakeResult{CLI: "git status -s"},
+		fakerunner.FakeResult{CLI: "git commit -m chore: update", Dir: "/workspace"},
+		fakerunner.FakeResult{CLI: "git status -s"},
+		fakerunner.FakeResult{CLI: "git push origin main"},
+	)
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+// +build !windows
+
+package cmdrunner
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// killProcessGroupOnCancel runs the command in its own process group and kills the whole group
+// when the context of the command is done so that no orphaned child processes are left behind
+func killProcessGroupOnCancel(e *exec.Cmd) {
+	if e.SysProcAttr == nil {
+		e.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	e.SysProcAttr.Setpgid = true
+	e.Cancel = func() error {
+		if e.Process == nil {
+			return nil
+		}
+		return syscall.Kill(-e.Process.Pid, syscall.SIGKILL)
+	}
+}
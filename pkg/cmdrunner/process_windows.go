@@ -0,0 +1,9 @@
+package cmdrunner
+
+import (
+	"os/exec"
+)
+
+// killProcessGroupOnCancel uses the default behaviour of killing the process when the context of the command is done
+func killProcessGroupOnCancel(e *exec.Cmd) {
+}
@@ -0,0 +1,61 @@
+package cmdrunner
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Result the captured output and status of a single execution of a Command
+type Result struct {
+	// Stdout the captured standard output
+	Stdout string
+	// Stderr the captured standard error
+	Stderr string
+	// ExitCode the exit code of the process or -1 if it did not exit normally
+	ExitCode int
+	// Signal the name of the signal which terminated the process if any
+	Signal string
+	// Duration the wall-clock time the execution took
+	Duration time.Duration
+}
+
+func newResult(stdout, stderr string, duration time.Duration, err error) Result {
+	r := Result{
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Duration: duration,
+	}
+	if err != nil {
+		r.ExitCode = -1
+		var exitError *exec.ExitError
+		if errors.As(err, &exitError) {
+			r.ExitCode = exitError.ExitCode()
+			if ws, ok := exitError.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+				r.Signal = ws.Signal().String()
+			}
+		}
+	}
+	return r
+}
+
+// syncBuilder a strings.Builder which can be written to concurrently by the stdout and stderr copying goroutines
+type syncBuilder struct {
+	lock    sync.Mutex
+	builder strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.builder.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.builder.String()
+}
@@ -0,0 +1,81 @@
+package cmdrunner
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/cenkalti/backoff"
+)
+
+// RetryFunction returns true if a failed attempt of a command with the given exit code and combined stdout and
+// stderr output can be retried
+type RetryFunction func(exitCode int, output string) bool
+
+// RetryPolicy configures how Command.Run retries a command which fails
+type RetryPolicy struct {
+	// BackOff the backoff used between attempts. If nil the ExponentialBackOff of the command is used
+	BackOff backoff.BackOff
+
+	// MaxAttempts the maximum number of times the command is executed. Zero means there is no limit other than the backoff
+	MaxAttempts int
+
+	// Retriable decides if a failed attempt can be retried. If nil all failures are retried
+	Retriable RetryFunction
+}
+
+// retriable returns true if the given error of a failed attempt can be retried
+func (p *RetryPolicy) retriable(err error) bool {
+	if p == nil || p.Retriable == nil {
+		return true
+	}
+	var commandError CommandError
+	if errors.As(err, &commandError) {
+		output := commandError.Output
+		if output == "" {
+			// the output is not captured when the command writes to Out
+			output = commandError.Stdout + commandError.Stderr
+		}
+		return p.Retriable(commandError.ExitCode, output)
+	}
+	return p.Retriable(-1, "")
+}
+
+// RegexRetryFunction returns a RetryFunction which retries if the output matches any of the given regular expressions
+func RegexRetryFunction(retryErrorRegexes ...string) RetryFunction {
+	return func(_ int, output string) bool {
+		return matchesAnyRegex(retryErrorRegexes, output)
+	}
+}
+
+// RegexNoRetryFunction returns a RetryFunction which retries unless the output matches any of the given regular
+// expressions. This is useful for failing fast on permanent errors such as "repository not found"
+func RegexNoRetryFunction(permanentErrorRegexes ...string) RetryFunction {
+	return func(_ int, output string) bool {
+		return !matchesAnyRegex(permanentErrorRegexes, output)
+	}
+}
+
+// ExitCodeRetryFunction returns a RetryFunction which only retries if the command exited with one of the given exit codes
+func ExitCodeRetryFunction(exitCodes ...int) RetryFunction {
+	return func(exitCode int, _ string) bool {
+		for _, c := range exitCodes {
+			if c == exitCode {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func matchesAnyRegex(regexes []string, text string) bool {
+	for _, r := range regexes {
+		re, err := regexp.Compile(r)
+		if err != nil {
+			continue
+		}
+		if re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+//go:build unit
+// +build unit
+
+package cmdrunner_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryFunctions(t *testing.T) {
+	t.Parallel()
+
+	retry := cmdrunner.RegexRetryFunction("connection reset", "timed out")
+	assert.True(t, retry(128, "fatal: unable to access: connection reset by peer"))
+	assert.False(t, retry(128, "fatal: repository 'https://github.com/foo/bar' not found"))
+
+	noRetry := cmdrunner.RegexNoRetryFunction("repository .* not found", "unknown option")
+	assert.True(t, noRetry(128, "fatal: unable to access: connection reset by peer"))
+	assert.False(t, noRetry(128, "fatal: repository 'https://github.com/foo/bar' not found"))
+	assert.False(t, noRetry(129, "error: unknown option `foo'"))
+
+	exitCodes := cmdrunner.ExitCodeRetryFunction(1, 128)
+	assert.True(t, exitCodes(128, ""))
+	assert.False(t, exitCodes(129, ""))
+}
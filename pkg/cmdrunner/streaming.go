@@ -0,0 +1,117 @@
+package cmdrunner
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+	"github.com/sirupsen/logrus"
+)
+
+// LogLines returns a line callback which logs each line using the jx logger at the given level
+func LogLines(level logrus.Level) func(string) {
+	return func(line string) {
+		log.Logger().Log(level, line)
+	}
+}
+
+// TeeToLogger logs each line of stdout and stderr at the given level as the command runs while still capturing the output
+func (c *Command) TeeToLogger(level logrus.Level) {
+	c.OnStdoutLine = LogLines(level)
+	c.OnStderrLine = LogLines(level)
+}
+
+// StreamingCommandRunner returns a runner which logs the output of the command at the given level as it arrives
+// rather than when the command completes
+func StreamingCommandRunner(level logrus.Level) CommandRunner {
+	return func(c *Command) (string, error) {
+		if c.Dir == "" {
+			log.Logger().Logf(level, "about to run: %s", termcolor.ColorInfo(CLI(c)))
+		} else {
+			log.Logger().Logf(level, "about to run: %s in dir %s", termcolor.ColorInfo(CLI(c)), termcolor.ColorInfo(c.Dir))
+		}
+		if c.OnStdoutLine == nil && c.OnStderrLine == nil {
+			c.TeeToLogger(level)
+		}
+		return c.RunWithoutRetry()
+	}
+}
+
+// commandLineWriters the line writers for the stdout and stderr of an execution of a command
+type commandLineWriters struct {
+	stdout *lineWriter
+	stderr *lineWriter
+}
+
+// lineWriters creates the line writers for any line callbacks. The callbacks share a lock so they are never
+// invoked concurrently
+func (c *Command) lineWriters() commandLineWriters {
+	lock := &sync.Mutex{}
+	answer := commandLineWriters{}
+	if c.OnStdoutLine != nil {
+		answer.stdout = &lineWriter{lock: lock, fn: c.OnStdoutLine}
+	}
+	if c.OnStderrLine != nil {
+		answer.stderr = &lineWriter{lock: lock, fn: c.OnStderrLine}
+	}
+	return answer
+}
+
+func (w commandLineWriters) flush() {
+	if w.stdout != nil {
+		w.stdout.flush()
+	}
+	if w.stderr != nil {
+		w.stderr.flush()
+	}
+}
+
+// lineWriter an io.Writer which invokes a callback for each complete line written to it. Carriage returns also
+// terminate lines so that progress output such as from git is streamed as it is updated
+type lineWriter struct {
+	lock   *sync.Mutex
+	fn     func(string)
+	buffer []byte
+	// afterCR is true if the last byte written was a carriage return so that a following new line does not
+	// create an empty line
+	afterCR bool
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	// only the new bytes are scanned as any buffered text does not contain a line terminator
+	rest := p
+	for len(rest) > 0 {
+		if w.afterCR && rest[0] == '\n' {
+			w.afterCR = false
+			rest = rest[1:]
+			continue
+		}
+		idx := bytes.IndexAny(rest, "\r\n")
+		if idx < 0 {
+			w.buffer = append(w.buffer, rest...)
+			w.afterCR = false
+			break
+		}
+		w.buffer = append(w.buffer, rest[:idx]...)
+		w.fn(string(w.buffer))
+		w.buffer = w.buffer[:0]
+		w.afterCR = rest[idx] == '\r'
+		rest = rest[idx+1:]
+	}
+	return len(p), nil
+}
+
+// flush invokes the callback with any remaining text which did not end with a line terminator
+func (w *lineWriter) flush() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(w.buffer) > 0 {
+		w.fn(string(w.buffer))
+		w.buffer = w.buffer[:0]
+	}
+}
@@ -0,0 +1,338 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/conventionalcommits"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// OtherSectionType the section type used for commits which do not match any section
+	OtherSectionType = "other"
+
+	// OtherSectionTitle the title of the section for commits which do not match any section
+	OtherSectionTitle = "Other Changes"
+)
+
+var (
+	// DefaultSectionTypes the conventional commit types included in a changelog by default in the order they are rendered
+	DefaultSectionTypes = []SectionType{
+		{Type: "feat", Title: "Features"},
+		{Type: "fix", Title: "Bug Fixes"},
+		{Type: "perf", Title: "Performance Improvements"},
+		{Type: "revert", Title: "Reverts"},
+		{Type: "docs", Title: "Documentation"},
+		{Type: "refactor", Title: "Code Refactoring"},
+	}
+
+	// squashedPullRequestRegex matches the pull request number suffix of a squashed pull request commit
+	squashedPullRequestRegex = regexp.MustCompile(`\s*\(#(\d+)\)\s*$`)
+
+	// pullRequestRegexes the regular expressions used to find the pull request number of a commit
+	pullRequestRegexes = []*regexp.Regexp{
+		squashedPullRequestRegex,
+		regexp.MustCompile(`^Merge pull request #(\d+)`),
+		regexp.MustCompile(`(?m)^See merge request \S*!(\d+)\s*$`),
+	}
+)
+
+// SectionType the conventional commit type of a changelog section and its title
+type SectionType struct {
+	// Type the conventional commit type such as 'feat'
+	Type string
+	// Title the title of the section such as 'Features'
+	Title string
+}
+
+// Options the options for generating a changelog
+type Options struct {
+	// From the revision such as the previous release tag whose history is excluded. If empty the whole history is included
+	From string
+	// To the revision of the release which defaults to HEAD
+	To string
+	// Version the optional version of the release used in the title
+	Version string
+	// Date the optional date of the release
+	Date time.Time
+	// Repository the git repository used to create links to commits and pull requests
+	Repository *giturl.GitRepository
+	// GitKind the kind of git provider used to create links. Defaults to the kind of SaaS provider of the repository
+	GitKind string
+	// ScmClient the optional client used to find the pull requests of the commits
+	ScmClient *scm.Client
+	// SectionTypes the sections of the changelog which defaults to DefaultSectionTypes
+	SectionTypes []SectionType
+	// IncludeOther includes any commits which do not match a section including non conventional commits
+	IncludeOther bool
+}
+
+// Changelog the changes in a release grouped by conventional commit type
+type Changelog struct {
+	// Version the version of the release
+	Version string `json:"version,omitempty"`
+	// Date the date of the release
+	Date *time.Time `json:"date,omitempty"`
+	// From the revision of the previous release
+	From string `json:"from,omitempty"`
+	// To the revision of the release
+	To string `json:"to,omitempty"`
+	// CompareURL the URL to compare the previous release with this release
+	CompareURL string `json:"compareURL,omitempty"`
+	// Sections the sections of changes in order
+	Sections []*Section `json:"sections,omitempty"`
+	// BreakingChanges the entries which contain breaking changes
+	BreakingChanges []*Entry `json:"breakingChanges,omitempty"`
+}
+
+// Section the changes of a conventional commit type
+type Section struct {
+	// Type the conventional commit type of the section
+	Type string `json:"type"`
+	// Title the title of the section
+	Title string `json:"title"`
+	// Entries the entries of the section
+	Entries []*Entry `json:"entries"`
+}
+
+// Entry a commit in the changelog
+type Entry struct {
+	// SHA the git commit sha
+	SHA string `json:"sha"`
+	// URL the URL to view the commit
+	URL string `json:"url,omitempty"`
+	// Type the conventional commit type
+	Type string `json:"type,omitempty"`
+	// Scope the conventional commit scope
+	Scope string `json:"scope,omitempty"`
+	// Description the description of the change
+	Description string `json:"description"`
+	// BreakingChange the description of the breaking change if the commit is a breaking change
+	BreakingChange string `json:"breakingChange,omitempty"`
+	// Author the name of the author of the commit
+	Author string `json:"author,omitempty"`
+	// PullRequest the pull request of the commit if known
+	PullRequest *PullRequest `json:"pullRequest,omitempty"`
+}
+
+// PullRequest the pull request which contained a commit
+type PullRequest struct {
+	// Number the number of the pull request
+	Number int `json:"number"`
+	// Title the title of the pull request
+	Title string `json:"title,omitempty"`
+	// URL the URL to view the pull request
+	URL string `json:"url,omitempty"`
+	// Author the login of the author of the pull request
+	Author string `json:"author,omitempty"`
+}
+
+// Generate generates the changelog from the git history in the given directory. Merge commits are used to find the
+// pull requests of the commits they merge but are not included as entries
+func Generate(g gitclient.Interface, dir string, o *Options) (*Changelog, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	commits, err := gitlog.Log(g, dir, &gitlog.LogOptions{From: o.From, To: o.To})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the commits: %w", err)
+	}
+	return FromCommits(commits, o)
+}
+
+// FromCommits generates the changelog from the commits which are in newest first order. Merge commits are not
+// included as entries but commits merged by a pull request merge commit are linked to the pull request
+func FromCommits(commits []*gitlog.LogCommit, o *Options) (*Changelog, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	sectionTypes := o.SectionTypes
+	if len(sectionTypes) == 0 {
+		sectionTypes = DefaultSectionTypes
+	}
+	links := newLinker(o.Repository, o.GitKind)
+	answer := &Changelog{
+		Version:    o.Version,
+		From:       o.From,
+		To:         o.To,
+		CompareURL: links.compareURL(o.From, o.To),
+	}
+	if !o.Date.IsZero() {
+		answer.Date = &o.Date
+	}
+
+	sections := map[string]*Section{}
+	pullRequests := map[int]*PullRequest{}
+	mergedPullRequests := mergedPullRequestNumbers(commits)
+	for _, c := range commits {
+		if c.IsMerge() {
+			continue
+		}
+		e := &Entry{
+			SHA:         c.SHA,
+			URL:         links.commitURL(c.SHA),
+			Description: c.Subject,
+			Author:      c.Author.Name,
+		}
+		sectionType := OtherSectionType
+		cc, ok := conventionalcommits.Parse(c.Message())
+		if ok {
+			e.Type = cc.Type
+			e.Scope = cc.Scope
+			e.Description = cc.Description
+			e.BreakingChange = cc.BreakingChange
+			sectionType = cc.Type
+		}
+		title := sectionTitle(sectionTypes, sectionType)
+		if title == "" && o.IncludeOther {
+			sectionType = OtherSectionType
+			title = OtherSectionTitle
+		}
+		if title == "" && e.BreakingChange == "" {
+			continue
+		}
+
+		number := findPullRequestNumber(c)
+		if number == 0 {
+			number = mergedPullRequests[c.SHA]
+		}
+		if number > 0 {
+			pr := pullRequests[number]
+			if pr == nil {
+				pr = links.pullRequest(o.ScmClient, number)
+				pullRequests[number] = pr
+			}
+			e.PullRequest = pr
+			e.Description = squashedPullRequestRegex.ReplaceAllString(e.Description, "")
+			e.BreakingChange = squashedPullRequestRegex.ReplaceAllString(e.BreakingChange, "")
+		}
+
+		if e.BreakingChange != "" {
+			answer.BreakingChanges = append(answer.BreakingChanges, e)
+		}
+		if title == "" {
+			continue
+		}
+		s := sections[sectionType]
+		if s == nil {
+			s = &Section{Type: sectionType, Title: title}
+			sections[sectionType] = s
+		}
+		s.Entries = append(s.Entries, e)
+	}
+
+	for _, st := range sectionTypes {
+		if s := sections[st.Type]; s != nil {
+			answer.Sections = append(answer.Sections, s)
+		}
+	}
+	if s := sections[OtherSectionType]; s != nil {
+		answer.Sections = append(answer.Sections, s)
+	}
+	return answer, nil
+}
+
+// sectionTitle returns the title of the section type or an empty string if the type is not included
+func sectionTitle(sectionTypes []SectionType, sectionType string) string {
+	for _, st := range sectionTypes {
+		if st.Type == sectionType {
+			return st.Title
+		}
+	}
+	return ""
+}
+
+// findPullRequestNumber returns the pull request number from the commit message of a squashed or merged pull request
+func findPullRequestNumber(c *gitlog.LogCommit) int {
+	for _, text := range []string{c.Subject, c.Body} {
+		for _, r := range pullRequestRegexes {
+			groups := r.FindStringSubmatch(text)
+			if groups == nil {
+				continue
+			}
+			number, err := strconv.Atoi(groups[1])
+			if err == nil {
+				return number
+			}
+		}
+	}
+	return 0
+}
+
+// mergedPullRequestNumbers returns the pull request numbers of the commits merged by pull request merge commits
+// indexed by commit SHA. The merged commits are those reachable from the second parent of the merge commit but not
+// from its first parent
+func mergedPullRequestNumbers(commits []*gitlog.LogCommit) map[string]int {
+	answer := map[string]int{}
+	bySHA := map[string]*gitlog.LogCommit{}
+	for _, c := range commits {
+		bySHA[c.SHA] = c
+	}
+	// the commits are newest first so lets process the oldest merges first so that a commit is linked to the
+	// pull request which first merged it
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		if !c.IsMerge() {
+			continue
+		}
+		number := findPullRequestNumber(c)
+		if number == 0 {
+			continue
+		}
+		mainline := reachable(bySHA, c.Parents[0], nil)
+		for sha := range reachable(bySHA, c.Parents[1], mainline) {
+			if _, ok := answer[sha]; !ok {
+				answer[sha] = number
+			}
+		}
+	}
+	return answer
+}
+
+// reachable returns the SHAs of the commits reachable from the given SHA which are not excluded
+func reachable(bySHA map[string]*gitlog.LogCommit, sha string, exclude map[string]bool) map[string]bool {
+	answer := map[string]bool{}
+	stack := []string{sha}
+	for len(stack) > 0 {
+		sha = stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		c := bySHA[sha]
+		if c == nil || answer[sha] || exclude[sha] {
+			continue
+		}
+		answer[sha] = true
+		stack = append(stack, c.Parents...)
+	}
+	return answer
+}
+
+// pullRequest returns the pull request with the given number looking it up via the scm client if available
+func (l *linker) pullRequest(scmClient *scm.Client, number int) *PullRequest {
+	answer := &PullRequest{
+		Number: number,
+		URL:    l.pullRequestURL(number),
+	}
+	if scmClient == nil || l.repository == nil {
+		return answer
+	}
+	fullName := scm.Join(l.repository.Organisation, l.repository.Name)
+	pr, _, err := scmClient.PullRequests.Find(context.Background(), fullName, number)
+	if err != nil {
+		log.Logger().Warnf("failed to find pull request %d in repository %s: %s", number, fullName, err.Error())
+		return answer
+	}
+	answer.Title = pr.Title
+	answer.Author = pr.Author.Login
+	if pr.Link != "" {
+		answer.URL = pr.Link
+	}
+	return answer
+}
@@ -0,0 +1,168 @@
+//go:build unit
+// +build unit
+
+package changelog_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/go-scm/scm"
+	fakescm "github.com/jenkins-x/go-scm/scm/driver/fake"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/changelog"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	gitURL := "https://github.com/myorg/myrepo.git"
+	g := fakegit.NewFakeGit()
+	_, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create remote repository")
+
+	dir, err := gitclient.CloneToDir(g, gitURL, filepath.Join(t.TempDir(), "myrepo"))
+	require.NoError(t, err, "failed to clone")
+	_, err = g.Command(dir, "tag", "v1.0.0")
+	require.NoError(t, err, "failed to tag")
+
+	repo := g.Repository(dir)
+	fix, err := repo.CommitFiles("fix(api): handle nil client (#12)", map[string]string{"a.txt": "a\n"})
+	require.NoError(t, err)
+	_, err = repo.CommitFiles("chore: tidy up", map[string]string{"b.txt": "b\n"})
+	require.NoError(t, err)
+	_, err = repo.CommitFiles("update readme", map[string]string{"README.md": "hello world\n"})
+	require.NoError(t, err)
+	feat, err := repo.CommitFiles("feat!: new config format (#13)\n\nBREAKING CHANGE: the old config\nis no longer read", map[string]string{"c.txt": "c\n"})
+	require.NoError(t, err)
+
+	scmClient, fakeData := fakescm.NewDefault()
+	fakeData.PullRequests[12] = &scm.PullRequest{
+		Number: 12,
+		Title:  "fix the nil client",
+		Link:   "https://github.com/myorg/myrepo/pull/12",
+		Author: scm.User{Login: "jstrachan"},
+	}
+
+	gitRepository, err := giturl.ParseGitURL(gitURL)
+	require.NoError(t, err, "failed to parse git URL")
+
+	_, err = g.Command(dir, "tag", "v1.1.0")
+	require.NoError(t, err, "failed to tag")
+	cl, err := changelog.Generate(g, dir, &changelog.Options{
+		From:       "v1.0.0",
+		To:         "v1.1.0",
+		Version:    "v1.1.0",
+		Date:       time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		Repository: gitRepository,
+		ScmClient:  scmClient,
+	})
+	require.NoError(t, err, "failed to generate changelog")
+
+	require.Len(t, cl.Sections, 2, "sections")
+	assert.Equal(t, "Features", cl.Sections[0].Title, "first section")
+	assert.Equal(t, "Bug Fixes", cl.Sections[1].Title, "second section")
+	require.Len(t, cl.BreakingChanges, 1, "breaking changes")
+
+	fixEntry := cl.Sections[1].Entries[0]
+	assert.Equal(t, "https://github.com/myorg/myrepo/commit/"+fix.SHA, fixEntry.URL, "commit URL")
+	require.NotNil(t, fixEntry.PullRequest, "fix pull request")
+	assert.Equal(t, "jstrachan", fixEntry.PullRequest.Author, "pull request author from scm")
+	assert.Equal(t, "fix the nil client", fixEntry.PullRequest.Title, "pull request title from scm")
+
+	featEntry := cl.Sections[0].Entries[0]
+	require.NotNil(t, featEntry.PullRequest, "feat pull request")
+	assert.Equal(t, "https://github.com/myorg/myrepo/pull/13", featEntry.PullRequest.URL, "pull request URL when not found in scm")
+	assert.Empty(t, featEntry.PullRequest.Title, "pull request title when not found in scm")
+
+	short := func(sha string) string {
+		return sha[:7]
+	}
+	expected := "## [v1.1.0](https://github.com/myorg/myrepo/compare/v1.0.0...v1.1.0) (2021-03-04)\n" +
+		"\n### Features\n\n" +
+		"* new config format ([#13](https://github.com/myorg/myrepo/pull/13)) ([" + short(feat.SHA) + "](https://github.com/myorg/myrepo/commit/" + feat.SHA + "))\n" +
+		"\n### Bug Fixes\n\n" +
+		"* **api:** handle nil client ([#12](https://github.com/myorg/myrepo/pull/12)) ([" + short(fix.SHA) + "](https://github.com/myorg/myrepo/commit/" + fix.SHA + "))\n" +
+		"\n### BREAKING CHANGES\n\n" +
+		"* the old config\n  is no longer read ([#13](https://github.com/myorg/myrepo/pull/13)) ([" + short(feat.SHA) + "](https://github.com/myorg/myrepo/commit/" + feat.SHA + "))\n"
+	assert.Equal(t, expected, cl.Markdown(), "markdown")
+
+	buf := &bytes.Buffer{}
+	require.NoError(t, cl.Write(buf, "yaml"), "failed to write yaml")
+	assert.Contains(t, buf.String(), "version: v1.1.0", "yaml")
+	assert.Contains(t, buf.String(), "title: Bug Fixes", "yaml")
+
+	buf.Reset()
+	require.NoError(t, cl.Write(buf, "json"), "failed to write json")
+	assert.Contains(t, buf.String(), `"author":"jstrachan"`, "json")
+
+	require.Error(t, cl.Write(buf, "xml"), "should fail for an unknown format")
+}
+
+func TestFromCommitsOtherAndLinks(t *testing.T) {
+	commits := []*gitlog.LogCommit{
+		{SHA: "1111111aaaa", Subject: "docs: explain"},
+		{SHA: "2222222bbbb", Subject: "update readme", Body: "See merge request myorg/myrepo!7"},
+		{SHA: "3333333cccc", Subject: "chore(deps): bump"},
+	}
+	gitRepository, err := giturl.ParseGitURL("https://gitlab.com/myorg/myrepo.git")
+	require.NoError(t, err, "failed to parse git URL")
+
+	cl, err := changelog.FromCommits(commits, &changelog.Options{
+		Repository:   gitRepository,
+		IncludeOther: true,
+	})
+	require.NoError(t, err, "failed to generate changelog")
+	require.Len(t, cl.Sections, 2, "sections")
+	assert.Equal(t, "Documentation", cl.Sections[0].Title, "first section")
+	assert.Equal(t, changelog.OtherSectionTitle, cl.Sections[1].Title, "other section")
+	require.Len(t, cl.Sections[1].Entries, 2, "other entries")
+
+	e := cl.Sections[1].Entries[0]
+	assert.Equal(t, "https://gitlab.com/myorg/myrepo/-/commit/2222222bbbb", e.URL, "gitlab commit URL")
+	require.NotNil(t, e.PullRequest, "merge request")
+	assert.Equal(t, "https://gitlab.com/myorg/myrepo/-/merge_requests/7", e.PullRequest.URL, "gitlab merge request URL")
+	assert.True(t, strings.HasSuffix(cl.Markdown(), "\n* **deps:** bump ([3333333](https://gitlab.com/myorg/myrepo/-/commit/3333333cccc))\n"), "markdown ends with the chore")
+
+	cl, err = changelog.FromCommits(commits, nil)
+	require.NoError(t, err)
+	require.Len(t, cl.Sections, 1, "sections without other")
+	assert.Equal(t, "### Documentation\n\n* explain (1111111)\n", cl.Markdown(), "markdown without links")
+}
+
+func TestFromCommitsMergedPullRequests(t *testing.T) {
+	// newest first: a pull request merge, a commit on main and the two commits of the pull request branch
+	commits := []*gitlog.LogCommit{
+		{SHA: "4444444dddd", Parents: []string{"3333333cccc", "2222222bbbb"}, Subject: "Merge pull request #21 from myorg/feature"},
+		{SHA: "3333333cccc", Parents: []string{"0000000base"}, Subject: "fix: on main"},
+		{SHA: "2222222bbbb", Parents: []string{"1111111aaaa"}, Subject: "feat: second"},
+		{SHA: "1111111aaaa", Parents: []string{"0000000base"}, Subject: "feat: first"},
+	}
+	gitRepository, err := giturl.ParseGitURL("https://github.com/myorg/myrepo.git")
+	require.NoError(t, err, "failed to parse git URL")
+
+	cl, err := changelog.FromCommits(commits, &changelog.Options{
+		Repository:   gitRepository,
+		IncludeOther: true,
+	})
+	require.NoError(t, err, "failed to generate changelog")
+	require.Len(t, cl.Sections, 2, "sections should not include the merge commit")
+
+	features := cl.Sections[0]
+	require.Len(t, features.Entries, 2, "feature entries")
+	for _, e := range features.Entries {
+		require.NotNil(t, e.PullRequest, "pull request of %s", e.Description)
+		assert.Equal(t, 21, e.PullRequest.Number, "pull request number of %s", e.Description)
+	}
+	assert.Same(t, features.Entries[0].PullRequest, features.Entries[1].PullRequest, "shared pull request")
+
+	fixes := cl.Sections[1]
+	require.Len(t, fixes.Entries, 1, "fix entries")
+	assert.Nil(t, fixes.Entries[0].PullRequest, "commit on main should not be linked to the merged pull request")
+}
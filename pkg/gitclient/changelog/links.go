@@ -0,0 +1,83 @@
+package changelog
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+)
+
+// linker creates the URLs of commits and pull requests for the kind of git provider
+type linker struct {
+	repository *giturl.GitRepository
+	kind       string
+	baseURL    string
+}
+
+func newLinker(repository *giturl.GitRepository, kind string) *linker {
+	l := &linker{repository: repository, kind: kind}
+	if repository == nil {
+		return l
+	}
+	hostURL := repository.HostURLWithoutUser()
+	if l.kind == "" {
+		l.kind = giturl.SaasGitKind(hostURL)
+	}
+	if l.kind == giturl.KindBitBucketServer {
+		l.baseURL = stringhelpers.UrlJoin(hostURL, "projects", repository.Organisation, "repos", repository.Name)
+	} else {
+		l.baseURL = stringhelpers.UrlJoin(hostURL, repository.Organisation, repository.Name)
+	}
+	return l
+}
+
+// commitURL returns the URL to view the commit or an empty string if there is no repository
+func (l *linker) commitURL(sha string) string {
+	if l.baseURL == "" || sha == "" {
+		return ""
+	}
+	switch l.kind {
+	case giturl.KindGitlab:
+		return stringhelpers.UrlJoin(l.baseURL, "-", "commit", sha)
+	case giturl.KindBitBucketCloud, giturl.KindBitBucketServer:
+		return stringhelpers.UrlJoin(l.baseURL, "commits", sha)
+	default:
+		return stringhelpers.UrlJoin(l.baseURL, "commit", sha)
+	}
+}
+
+// pullRequestURL returns the URL to view the pull request or an empty string if there is no repository
+func (l *linker) pullRequestURL(number int) string {
+	if l.baseURL == "" {
+		return ""
+	}
+	n := strconv.Itoa(number)
+	switch l.kind {
+	case giturl.KindGitlab:
+		return stringhelpers.UrlJoin(l.baseURL, "-", "merge_requests", n)
+	case giturl.KindBitBucketCloud, giturl.KindBitBucketServer:
+		return stringhelpers.UrlJoin(l.baseURL, "pull-requests", n)
+	case giturl.KindGitea:
+		return stringhelpers.UrlJoin(l.baseURL, "pulls", n)
+	default:
+		return stringhelpers.UrlJoin(l.baseURL, "pull", n)
+	}
+}
+
+// compareURL returns the URL to compare the two revisions or an empty string if it is not supported
+func (l *linker) compareURL(from, to string) string {
+	if l.baseURL == "" || from == "" || to == "" {
+		return ""
+	}
+	switch l.kind {
+	case giturl.KindGitlab:
+		return stringhelpers.UrlJoin(l.baseURL, "-", "compare", fmt.Sprintf("%s...%s", from, to))
+	case giturl.KindBitBucketCloud:
+		return stringhelpers.UrlJoin(l.baseURL, "branches", "compare", fmt.Sprintf("%s%%0D%s", to, from))
+	case giturl.KindBitBucketServer:
+		return ""
+	default:
+		return stringhelpers.UrlJoin(l.baseURL, "compare", fmt.Sprintf("%s...%s", from, to))
+	}
+}
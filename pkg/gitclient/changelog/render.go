@@ -0,0 +1,90 @@
+package changelog
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/outputformat"
+)
+
+const (
+	// FormatMarkdown the markdown output format
+	FormatMarkdown = "markdown"
+
+	// breakingChangesTitle the title of the breaking changes section
+	breakingChangesTitle = "BREAKING CHANGES"
+)
+
+// Write writes the changelog to the output in the given format which is either markdown, yaml or json
+func (c *Changelog) Write(out io.Writer, format string) error {
+	if format == FormatMarkdown || format == "md" {
+		_, err := fmt.Fprint(out, c.Markdown())
+		return err
+	}
+	return outputformat.Marshal(c, out, format)
+}
+
+// Markdown renders the changelog as markdown
+func (c *Changelog) Markdown() string {
+	buf := strings.Builder{}
+	title := c.Version
+	if c.CompareURL != "" && title != "" {
+		title = fmt.Sprintf("[%s](%s)", title, c.CompareURL)
+	}
+	if c.Date != nil {
+		date := c.Date.Format("2006-01-02")
+		if title == "" {
+			title = date
+		} else {
+			title += " (" + date + ")"
+		}
+	}
+	if title != "" {
+		buf.WriteString("## " + title + "\n")
+	}
+	for _, s := range c.Sections {
+		buf.WriteString("\n### " + s.Title + "\n\n")
+		for _, e := range s.Entries {
+			buf.WriteString(e.markdown(e.Description) + "\n")
+		}
+	}
+	if len(c.BreakingChanges) > 0 {
+		buf.WriteString("\n### " + breakingChangesTitle + "\n\n")
+		for _, e := range c.BreakingChanges {
+			buf.WriteString(e.markdown(e.BreakingChange) + "\n")
+		}
+	}
+	return strings.TrimPrefix(buf.String(), "\n")
+}
+
+// markdown renders the entry as a markdown list item with the given text
+func (e *Entry) markdown(text string) string {
+	line := "* "
+	if e.Scope != "" {
+		line += "**" + e.Scope + ":** "
+	}
+	// indent any additional lines of a multi line breaking change
+	line += strings.ReplaceAll(text, "\n", "\n  ")
+	if e.PullRequest != nil {
+		line += " (" + markdownLink(fmt.Sprintf("#%d", e.PullRequest.Number), e.PullRequest.URL) + ")"
+	}
+	if e.SHA != "" {
+		line += " (" + markdownLink(shortSHA(e.SHA), e.URL) + ")"
+	}
+	return line
+}
+
+func markdownLink(text, url string) string {
+	if url == "" {
+		return text
+	}
+	return "[" + text + "](" + url + ")"
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
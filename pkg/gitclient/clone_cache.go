@@ -0,0 +1,54 @@
+package gitclient
+
+import (
+	"sync"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// CloneCache provides local reference repositories which are used to avoid downloading objects on clone
+type CloneCache interface {
+	// Reference returns the directory of an up to date reference repository for the git URL and a function
+	// which must be called to release it once the clone has completed
+	Reference(g Interface, gitURL string) (string, func(), error)
+}
+
+var (
+	cloneCacheLock    sync.RWMutex
+	cloneCacheFactory func() (CloneCache, error)
+)
+
+// RegisterCloneCache registers the factory of the clone cache used by CloneToDir and PartialCloneToDir. The factory
+// is invoked on each clone and returns nil if the cache is disabled.
+//
+// The cache lives in the gitcache package which depends on this package, so gitcache.Register registers a factory
+// which uses the mirror cache when the $JX_GIT_CACHE environment variable is true
+func RegisterCloneCache(factory func() (CloneCache, error)) {
+	cloneCacheLock.Lock()
+	defer cloneCacheLock.Unlock()
+	cloneCacheFactory = factory
+}
+
+// referenceArgs returns the clone arguments to use a reference repository from the registered clone cache and
+// the function to release it. If there is no cache or it fails no arguments are returned so a normal clone is used
+func referenceArgs(g Interface, gitURL string) ([]string, func()) {
+	cloneCacheLock.RLock()
+	factory := cloneCacheFactory
+	cloneCacheLock.RUnlock()
+	if factory == nil {
+		return nil, func() {}
+	}
+	cache, err := factory()
+	if err == nil && cache != nil {
+		var dir string
+		var release func()
+		dir, release, err = cache.Reference(g, gitURL)
+		if err == nil {
+			return []string{"--reference", dir, "--dissociate"}, release
+		}
+	}
+	if err != nil {
+		log.Logger().Warnf("failed to use the git clone cache so cloning %s without it: %s", gitURL, err.Error())
+	}
+	return nil, func() {}
+}
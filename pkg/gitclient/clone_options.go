@@ -0,0 +1,316 @@
+package gitclient
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// DefaultCredentialHelper the credential helper configured by SetCredentialHelper
+	DefaultCredentialHelper = "store"
+
+	// lfsPointerPrefix the first line of a Git LFS pointer file
+	lfsPointerPrefix = "version https://git-lfs.github.com/spec/v1"
+
+	// lfsPointerMaxSize the maximum size of a Git LFS pointer file
+	lfsPointerMaxSize = 1024
+)
+
+// CloneOptions the options for cloning or pulling repositories which use submodules or Git LFS. When options are
+// specified but submodules or Git LFS are disabled a warning is logged if the repository uses them
+type CloneOptions struct {
+	// Submodules initialises and updates any submodules recursively
+	Submodules bool
+	// LFS fetches and checks out any Git LFS files
+	LFS bool
+	// CredentialHelper the credential helper added to the repository configuration after cloning so that it is
+	// used when fetching submodules and Git LFS files such as DefaultCredentialHelper if SetCredentialHelper has
+	// been used. If empty the existing git configuration is used
+	CredentialHelper string
+}
+
+// LFSUnavailableError the error returned when a repository contains Git LFS pointer files
+// but the git-lfs extension is not installed
+type LFSUnavailableError struct {
+	// Dir the directory of the repository
+	Dir string
+	// Files the Git LFS pointer files which could not be fetched
+	Files []string
+	// Err the underlying error from git
+	Err error
+}
+
+// Error returns the error message
+func (e *LFSUnavailableError) Error() string {
+	return fmt.Sprintf("repository %s contains %d Git LFS pointer files such as %s but git-lfs is not available so please install it from https://git-lfs.com: %s",
+		e.Dir, len(e.Files), e.Files[0], e.Err.Error())
+}
+
+// Unwrap returns the underlying error
+func (e *LFSUnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// IsLFSUnavailable returns true if the error is caused by Git LFS not being available
+func IsLFSUnavailable(err error) bool {
+	var lfsErr *LFSUnavailableError
+	return errors.As(err, &lfsErr)
+}
+
+// setup updates any submodules and fetches any Git LFS files after a clone or pull. If they are not enabled
+// a warning is logged if the repository uses them. Nothing is done for nil options so that the existing clone
+// helpers behave and perform as they did before options were added
+func (o *CloneOptions) setup(g Interface, gitURL, dir string, shallow bool) error {
+	if o == nil {
+		return nil
+	}
+	err := configureCredentialHelper(g, dir, o.CredentialHelper)
+	if err != nil {
+		return err
+	}
+	hasSubmodules, err := files.FileExists(filepath.Join(dir, ".gitmodules"))
+	if err != nil {
+		return fmt.Errorf("failed to check for submodules in %s: %w", dir, err)
+	}
+	if hasSubmodules {
+		if o.Submodules {
+			err = updateSubmodules(g, dir, shallow)
+			if err != nil {
+				return err
+			}
+		} else {
+			log.Logger().Warnf("repository %s contains submodules which have not been cloned", gitURL)
+		}
+	}
+
+	if !o.LFS {
+		pointers, err := LFSPointerFiles(dir)
+		if err != nil {
+			return err
+		}
+		if len(pointers) > 0 {
+			log.Logger().Warnf("repository %s contains %d Git LFS pointer files such as %s which have not been fetched", gitURL, len(pointers), pointers[0])
+		}
+		return nil
+	}
+	return fetchLFS(g, dir)
+}
+
+// UpdateSubmodules initialises and updates the submodules of the repository recursively. If shallow is true only
+// the latest commit of each submodule is fetched. If the credential helper is not empty it is added to the
+// repository configuration first
+func UpdateSubmodules(g Interface, dir string, shallow bool, credentialHelper string) error {
+	err := configureCredentialHelper(g, dir, credentialHelper)
+	if err != nil {
+		return err
+	}
+	return updateSubmodules(g, dir, shallow)
+}
+
+// updateSubmodules initialises and updates the submodules of the repository recursively
+func updateSubmodules(g Interface, dir string, shallow bool) error {
+	_, err := g.Command(dir, "submodule", "sync", "--recursive")
+	if err != nil {
+		return fmt.Errorf("failed to sync submodules in %s: %w", dir, err)
+	}
+	args := []string{"submodule", "update", "--init", "--recursive"}
+	if shallow {
+		args = append(args, "--depth", "1")
+	}
+	_, err = g.Command(dir, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update submodules in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// FetchLFS fetches and checks out the Git LFS files of the repository. If git-lfs is not available
+// and the repository contains pointer files a LFSUnavailableError is returned. If the credential helper
+// is not empty it is added to the repository configuration first
+func FetchLFS(g Interface, dir, credentialHelper string) error {
+	err := configureCredentialHelper(g, dir, credentialHelper)
+	if err != nil {
+		return err
+	}
+	return fetchLFS(g, dir)
+}
+
+// fetchLFS fetches and checks out the Git LFS files of the repository
+func fetchLFS(g Interface, dir string) error {
+	_, err := g.Command(dir, "lfs", "version")
+	if err != nil {
+		pointers, pointerErr := LFSPointerFiles(dir)
+		if pointerErr != nil {
+			return pointerErr
+		}
+		if len(pointers) > 0 {
+			return &LFSUnavailableError{Dir: dir, Files: pointers, Err: err}
+		}
+		log.Logger().Debugf("git-lfs is not available but there are no Git LFS pointer files in %s", dir)
+		return nil
+	}
+	_, err = g.Command(dir, "lfs", "install", "--local")
+	if err != nil {
+		return fmt.Errorf("failed to install Git LFS hooks in %s: %w", dir, err)
+	}
+	_, err = g.Command(dir, "lfs", "pull")
+	if err != nil {
+		return fmt.Errorf("failed to pull Git LFS files in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// LFSPointerFiles returns the files in the directory which are Git LFS pointers rather than the real content.
+// Only files matching a filter=lfs pattern in a .gitattributes file are checked
+func LFSPointerFiles(dir string) ([]string, error) {
+	exists, err := files.DirExists(dir)
+	if err != nil || !exists {
+		return nil, err
+	}
+	var answer []string
+	found := map[string]bool{}
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Name() != ".gitattributes" {
+			return nil
+		}
+		patterns, err := lfsPatterns(path)
+		if err != nil || len(patterns) == 0 {
+			return err
+		}
+		attributesDir := filepath.Dir(path)
+		return filepath.Walk(attributesDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if info.Name() == ".git" {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.Size() > lfsPointerMaxSize || !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(attributesDir, path)
+			if err != nil {
+				return err
+			}
+			if !matchesAnyPattern(filepath.ToSlash(rel), patterns) {
+				return nil
+			}
+			pointer, err := isLFSPointer(path)
+			if err != nil {
+				return err
+			}
+			if pointer && !found[path] {
+				found[path] = true
+				rel, err = filepath.Rel(dir, path)
+				if err != nil {
+					return err
+				}
+				answer = append(answer, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find Git LFS pointer files in %s: %w", dir, err)
+	}
+	return answer, nil
+}
+
+// lfsPatterns returns the patterns in the .gitattributes file which use the lfs filter
+func lfsPatterns(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var answer []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for _, attr := range fields[1:] {
+			if attr == "filter=lfs" {
+				answer = append(answer, fields[0])
+				break
+			}
+		}
+	}
+	return answer, nil
+}
+
+// matchesAnyPattern returns true if the relative path matches one of the .gitattributes patterns
+func matchesAnyPattern(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		subject := rel
+		if !strings.Contains(strings.TrimPrefix(pattern, "/"), "/") {
+			// patterns without a slash match the file name at any depth
+			subject = filepath.Base(rel)
+		}
+		pattern = strings.TrimPrefix(pattern, "/")
+		if strings.HasSuffix(pattern, "/**") {
+			if strings.HasPrefix(rel, strings.TrimSuffix(pattern, "**")) {
+				return true
+			}
+			continue
+		}
+		if matched, err := filepath.Match(pattern, subject); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
+
+// isLFSPointer returns true if the file starts with the Git LFS pointer header
+func isLFSPointer(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	buf := make([]byte, len(lfsPointerPrefix))
+	_, err = io.ReadFull(f, buf)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return string(buf) == lfsPointerPrefix, nil
+}
+
+// configureCredentialHelper sets the credential helper in the configuration of the repository if it is not empty.
+// Using the repository configuration rather than a -c option works with any git client. Note that git does not
+// use the repository configuration when cloning submodules so they use the global credential helpers
+func configureCredentialHelper(g Interface, dir, credentialHelper string) error {
+	if credentialHelper == "" {
+		return nil
+	}
+	_, err := g.Command(dir, "config", "credential.helper", credentialHelper)
+	if err != nil {
+		return fmt.Errorf("failed to configure the credential helper %s in %s: %w", credentialHelper, dir, err)
+	}
+	return nil
+}
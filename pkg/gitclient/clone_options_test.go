@@ -0,0 +1,153 @@
+//go:build unit
+// +build unit
+
+package gitclient_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const lfsPointer = `version https://git-lfs.github.com/spec/v1
+oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393
+size 12345
+`
+
+func TestCloneWithSubmodules(t *testing.T) {
+	// allow submodules to be cloned from local directories
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	g, dir, otherDir := createClones(t)
+	subDir := filepath.Join(filepath.Dir(dir), "sub")
+	require.NoError(t, os.MkdirAll(subDir, 0o755))
+	_, err := g.Command(subDir, "init", "--initial-branch=master")
+	require.NoError(t, err)
+	writeAndCommit(t, g, subDir, "sub commit", map[string]string{"sub.txt": "sub\n"})
+
+	_, err = g.Command(dir, "submodule", "add", subDir, "charts")
+	require.NoError(t, err, "failed to add submodule")
+	_, err = g.Command(dir, "commit", "-m", "add submodule")
+	require.NoError(t, err)
+	require.NoError(t, gitclient.Push(g, dir, "origin", false, "master"))
+	remoteDir := filepath.Join(filepath.Dir(dir), "remote.git")
+
+	testCases := []struct {
+		name  string
+		clone func(cloneDir string, o *gitclient.CloneOptions) error
+	}{
+		{
+			name: "CloneToDir",
+			clone: func(cloneDir string, o *gitclient.CloneOptions) error {
+				_, err := gitclient.CloneToDirWithOptions(g, remoteDir, cloneDir, o)
+				return err
+			},
+		},
+		{
+			name: "ShallowCloneBranch",
+			clone: func(cloneDir string, o *gitclient.CloneOptions) error {
+				return gitclient.ShallowCloneBranchWithOptions(g, "file://"+remoteDir, "master", cloneDir, o)
+			},
+		},
+		{
+			name: "SparseCloneToDir",
+			clone: func(cloneDir string, o *gitclient.CloneOptions) error {
+				_, err := gitclient.SparseCloneToDirWithOptions(g, "file://"+remoteDir, cloneDir, true, o, "/*")
+				return err
+			},
+		},
+		{
+			name: "CloneOrPull",
+			clone: func(cloneDir string, o *gitclient.CloneOptions) error {
+				return gitclient.CloneOrPullWithOptions(g, remoteDir, cloneDir, o)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		cloneDir := filepath.Join(t.TempDir(), "without")
+		require.NoError(t, os.MkdirAll(cloneDir, 0o755))
+		require.NoError(t, tc.clone(cloneDir, nil), "%s failed without submodules", tc.name)
+		assert.NoFileExists(t, filepath.Join(cloneDir, "charts", "sub.txt"), "%s should not clone submodules by default", tc.name)
+
+		cloneDir = filepath.Join(t.TempDir(), "with")
+		require.NoError(t, os.MkdirAll(cloneDir, 0o755))
+		o := &gitclient.CloneOptions{Submodules: true, CredentialHelper: gitclient.DefaultCredentialHelper}
+		require.NoError(t, tc.clone(cloneDir, o), "%s failed with submodules", tc.name)
+		assertFileContent(t, filepath.Join(cloneDir, "charts", "sub.txt"), "sub\n")
+	}
+
+	// pulling an existing clone initialises the new submodule
+	require.NoError(t, gitclient.CloneOrPullWithOptions(g, remoteDir, otherDir, &gitclient.CloneOptions{Submodules: true}))
+	assertFileContent(t, filepath.Join(otherDir, "charts", "sub.txt"), "sub\n")
+}
+
+func TestCloneWithCredentialHelperWithFakeGit(t *testing.T) {
+	gitURL := "https://github.com/myorg/myrepo.git"
+	g := fakegit.NewFakeGit()
+	_, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create repository")
+
+	o := &gitclient.CloneOptions{Submodules: true, LFS: true, CredentialHelper: gitclient.DefaultCredentialHelper}
+	dir, err := gitclient.CloneToDirWithOptions(g, gitURL, filepath.Join(t.TempDir(), "myrepo"), o)
+	require.NoError(t, err, "failed to clone")
+	assertFileContent(t, filepath.Join(dir, "README.md"), "hello\n")
+
+	helper, err := g.Command(dir, "config", "--get", "credential.helper")
+	require.NoError(t, err, "failed to get the credential helper")
+	assert.Equal(t, gitclient.DefaultCredentialHelper, helper, "credential.helper")
+}
+
+func TestLFSPointerFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		".gitattributes":          "*.tgz filter=lfs diff=lfs merge=lfs -text\n",
+		"charts/app.tgz":          lfsPointer,
+		"charts/real.tgz":         "not a pointer",
+		"README.md":               lfsPointer,
+		"nested/.gitattributes":   "images/** filter=lfs diff=lfs merge=lfs -text\n",
+		"nested/images/logo.png":  lfsPointer,
+		"nested/other/logo.png":   lfsPointer,
+		"nested/images/small.png": "",
+	})
+
+	pointers, err := gitclient.LFSPointerFiles(dir)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"charts/app.tgz", "nested/images/logo.png"}, pointers, "pointer files")
+}
+
+func TestCloneWithLFSUnavailable(t *testing.T) {
+	g, dir, _ := createClones(t)
+	if _, err := g.Command(dir, "lfs", "version"); err == nil {
+		t.Skip("git-lfs is installed")
+	}
+	// commit the pointer file directly as git-lfs is not installed
+	writeAndCommit(t, g, dir, "add chart", map[string]string{
+		".gitattributes": "*.tgz filter=lfs diff=lfs merge=lfs -text\n",
+		"app.tgz":        lfsPointer,
+	})
+	require.NoError(t, gitclient.Push(g, dir, "origin", false, "master"))
+	remoteDir := filepath.Join(filepath.Dir(dir), "remote.git")
+
+	_, err := gitclient.CloneToDirWithOptions(g, remoteDir, filepath.Join(t.TempDir(), "without"), &gitclient.CloneOptions{})
+	require.NoError(t, err, "clone without LFS should only warn")
+
+	_, err = gitclient.CloneToDirWithOptions(g, remoteDir, filepath.Join(t.TempDir(), "with"), &gitclient.CloneOptions{LFS: true})
+	require.Error(t, err, "clone with LFS should fail")
+	assert.True(t, gitclient.IsLFSUnavailable(err), "should be LFS unavailable: %s", err.Error())
+	assert.Contains(t, err.Error(), "app.tgz")
+}
+
+func writeFiles(t *testing.T, dir string, fileContents map[string]string) {
+	for name, content := range fileContents {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+}
@@ -0,0 +1,135 @@
+package conventionalcommits
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	// BreakingChangeToken the footer token used to describe a breaking change
+	BreakingChangeToken = "BREAKING CHANGE"
+
+	// BreakingChangeTokenAlternative the alternative footer token used to describe a breaking change
+	BreakingChangeTokenAlternative = "BREAKING-CHANGE"
+)
+
+var (
+	headerRegex = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()\r\n]*)\))?(!)?: +(.+)$`)
+	footerRegex = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[A-Za-z][A-Za-z0-9-]*)(?:: | #)(.*)$`)
+)
+
+// Commit a commit message parsed using the Conventional Commits specification
+// https://www.conventionalcommits.org/
+type Commit struct {
+	// SHA the git commit sha of the commit if known
+	SHA string
+	// Type the type of the commit such as 'feat' or 'fix' in lower case
+	Type string
+	// Scope the optional scope of the commit
+	Scope string
+	// Breaking true if the commit contains a breaking change
+	Breaking bool
+	// BreakingChange the description of the breaking change from the footer or the description if '!' is used
+	BreakingChange string
+	// Description the description after the type and scope
+	Description string
+	// Body the body of the commit message excluding the footers
+	Body string
+	// Footers the footers of the commit message
+	Footers []Footer
+}
+
+// Footer a footer of a conventional commit message such as 'Reviewed-by: Z' or 'Refs #123'
+type Footer struct {
+	// Token the token of the footer such as 'Reviewed-by' or 'BREAKING CHANGE'
+	Token string
+	// Value the value of the footer
+	Value string
+}
+
+// Parse parses the commit message returning false if the message is not a conventional commit
+func Parse(message string) (*Commit, bool) {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	header, rest, _ := strings.Cut(message, "\n")
+	groups := headerRegex.FindStringSubmatch(strings.TrimSpace(header))
+	if groups == nil {
+		return nil, false
+	}
+	c := &Commit{
+		Type:        strings.ToLower(groups[1]),
+		Scope:       strings.TrimSpace(groups[2]),
+		Breaking:    groups[3] == "!",
+		Description: strings.TrimSpace(groups[4]),
+	}
+	if c.Breaking {
+		c.BreakingChange = c.Description
+	}
+
+	paragraphs := splitParagraphs(rest)
+	footerStart := len(paragraphs)
+	for i := len(paragraphs) - 1; i >= 0; i-- {
+		if !footerRegex.MatchString(paragraphs[i][0]) {
+			break
+		}
+		footerStart = i
+	}
+	var body []string
+	for _, p := range paragraphs[:footerStart] {
+		body = append(body, strings.Join(p, "\n"))
+	}
+	c.Body = strings.Join(body, "\n\n")
+
+	for _, p := range paragraphs[footerStart:] {
+		for _, line := range p {
+			groups := footerRegex.FindStringSubmatch(line)
+			if groups == nil && len(c.Footers) > 0 {
+				// continuation of the previous footer
+				last := &c.Footers[len(c.Footers)-1]
+				last.Value = last.Value + "\n" + line
+				continue
+			}
+			if groups != nil {
+				c.Footers = append(c.Footers, Footer{Token: groups[1], Value: strings.TrimSpace(groups[2])})
+			}
+		}
+	}
+	for _, f := range c.Footers {
+		if f.Token == BreakingChangeToken || f.Token == BreakingChangeTokenAlternative {
+			c.Breaking = true
+			c.BreakingChange = f.Value
+		}
+	}
+	return c, true
+}
+
+// splitParagraphs splits the text into paragraphs of lines separated by blank lines
+func splitParagraphs(text string) [][]string {
+	var answer [][]string
+	var current []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if len(current) > 0 {
+				answer = append(answer, current)
+				current = nil
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	if len(current) > 0 {
+		answer = append(answer, current)
+	}
+	return answer
+}
+
+// FooterValues returns the values of the footers with the given token ignoring case
+func (c *Commit) FooterValues(token string) []string {
+	var answer []string
+	for _, f := range c.Footers {
+		if strings.EqualFold(f.Token, token) {
+			answer = append(answer, f.Value)
+		}
+	}
+	return answer
+}
@@ -0,0 +1,98 @@
+//go:build unit
+// +build unit
+
+package conventionalcommits_test
+
+import (
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/conventionalcommits"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		message  string
+		expected *conventionalcommits.Commit
+	}{
+		{
+			message: "feat: add cheese",
+			expected: &conventionalcommits.Commit{
+				Type:        "feat",
+				Description: "add cheese",
+			},
+		},
+		{
+			message: "Fix(api): handle nil pointer\n\nthe client could be nil\nwhen offline\n\nRefs #123\nReviewed-by: Ann",
+			expected: &conventionalcommits.Commit{
+				Type:        "fix",
+				Scope:       "api",
+				Description: "handle nil pointer",
+				Body:        "the client could be nil\nwhen offline",
+				Footers: []conventionalcommits.Footer{
+					{Token: "Refs", Value: "123"},
+					{Token: "Reviewed-by", Value: "Ann"},
+				},
+			},
+		},
+		{
+			message: "refactor(core)!: remove the old API",
+			expected: &conventionalcommits.Commit{
+				Type:           "refactor",
+				Scope:          "core",
+				Breaking:       true,
+				BreakingChange: "remove the old API",
+				Description:    "remove the old API",
+			},
+		},
+		{
+			message: "chore: drop support for Node 6\n\nBREAKING CHANGE: use JavaScript features\nnot available in Node 6.",
+			expected: &conventionalcommits.Commit{
+				Type:           "chore",
+				Breaking:       true,
+				BreakingChange: "use JavaScript features\nnot available in Node 6.",
+				Description:    "drop support for Node 6",
+				Footers: []conventionalcommits.Footer{
+					{Token: "BREAKING CHANGE", Value: "use JavaScript features\nnot available in Node 6."},
+				},
+			},
+		},
+		{
+			message: "docs: explain things\n\nsome text\n\nBREAKING-CHANGE: renamed flags",
+			expected: &conventionalcommits.Commit{
+				Type:           "docs",
+				Breaking:       true,
+				BreakingChange: "renamed flags",
+				Description:    "explain things",
+				Body:           "some text",
+				Footers: []conventionalcommits.Footer{
+					{Token: "BREAKING-CHANGE", Value: "renamed flags"},
+				},
+			},
+		},
+		{
+			message: "Merge pull request #12 from foo/bar",
+		},
+		{
+			message: "feat:missing space",
+		},
+		{
+			message: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		c, ok := conventionalcommits.Parse(tc.message)
+		if tc.expected == nil {
+			assert.False(t, ok, "should not parse %q", tc.message)
+			continue
+		}
+		require.True(t, ok, "should parse %q", tc.message)
+		assert.Equal(t, tc.expected, c, "for message %q", tc.message)
+	}
+
+	c, ok := conventionalcommits.Parse("fix: x\n\nRefs #1\nRefs #2")
+	require.True(t, ok)
+	assert.Equal(t, []string{"1", "2"}, c.FooterValues("refs"), "FooterValues")
+}
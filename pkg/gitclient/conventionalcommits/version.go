@@ -0,0 +1,238 @@
+package conventionalcommits
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/stringhelpers"
+)
+
+// Bump the kind of semantic version increment required by commits
+type Bump int
+
+const (
+	// BumpNone no release is required
+	BumpNone Bump = iota
+	// BumpPatch the patch version should be incremented
+	BumpPatch
+	// BumpMinor the minor version should be incremented
+	BumpMinor
+	// BumpMajor the major version should be incremented
+	BumpMajor
+)
+
+// String returns the name of the bump
+func (b Bump) String() string {
+	switch b {
+	case BumpPatch:
+		return "patch"
+	case BumpMinor:
+		return "minor"
+	case BumpMajor:
+		return "major"
+	default:
+		return "none"
+	}
+}
+
+var (
+	// DefaultMinorTypes the commit types which increment the minor version by default
+	DefaultMinorTypes = []string{"feat"}
+
+	// DefaultPatchTypes the commit types which increment the patch version by default
+	DefaultPatchTypes = []string{"fix", "perf"}
+)
+
+// Options the options for calculating the next version
+type Options struct {
+	// TagPrefix the prefix of the version tags which defaults to 'v'. Use '-' for no prefix
+	TagPrefix string
+	// PreRelease the pre-release identifier such as 'rc' or 'beta'. If empty a normal release is created
+	// and any current pre-release is promoted
+	PreRelease string
+	// IncludePreReleases includes pre-release tags when finding the latest version. They are always included
+	// if a PreRelease identifier is configured so that the pre-release number can be incremented
+	IncludePreReleases bool
+	// BumpMinorPreMajor increments the minor rather than the major version for breaking changes while the major version is 0
+	BumpMinorPreMajor bool
+	// MinorTypes the commit types which increment the minor version which defaults to DefaultMinorTypes
+	MinorTypes []string
+	// PatchTypes the commit types which increment the patch version which defaults to DefaultPatchTypes
+	PatchTypes []string
+}
+
+// Release the next release calculated from the commits since the latest version tag
+type Release struct {
+	// PreviousTag the latest version tag or empty if there is none
+	PreviousTag string
+	// PreviousVersion the version of the latest version tag which is 0.0.0 if there is none
+	PreviousVersion semver.Version
+	// Version the next version which is the same as the previous version if no release is required
+	Version semver.Version
+	// Tag the tag name of the next version
+	Tag string
+	// Bump the kind of version increment required by the commits
+	Bump Bump
+	// Commits the conventional commits since the latest version tag
+	Commits []*Commit
+}
+
+// tagPrefix returns the tag prefix defaulting to 'v'
+func (o *Options) tagPrefix() string {
+	switch o.TagPrefix {
+	case "":
+		return "v"
+	case "-":
+		return ""
+	default:
+		return o.TagPrefix
+	}
+}
+
+// Bump returns the version increment required by the commit
+func (o *Options) Bump(c *Commit) Bump {
+	minorTypes := o.MinorTypes
+	if len(minorTypes) == 0 {
+		minorTypes = DefaultMinorTypes
+	}
+	patchTypes := o.PatchTypes
+	if len(patchTypes) == 0 {
+		patchTypes = DefaultPatchTypes
+	}
+	switch {
+	case c.Breaking:
+		return BumpMajor
+	case stringhelpers.StringArrayIndex(minorTypes, c.Type) >= 0:
+		return BumpMinor
+	case stringhelpers.StringArrayIndex(patchTypes, c.Type) >= 0:
+		return BumpPatch
+	default:
+		return BumpNone
+	}
+}
+
+// MaxBump returns the largest version increment required by any of the commits
+func (o *Options) MaxBump(commits []*Commit) Bump {
+	answer := BumpNone
+	for _, c := range commits {
+		if b := o.Bump(c); b > answer {
+			answer = b
+		}
+	}
+	return answer
+}
+
+// NextVersion returns the next version after the current version for the bump.
+//
+// If the current version is a pre-release then the bump is applied to the version it is a pre-release of, only
+// incrementing that version if it is not already at least the required bump. A pre-release is promoted to a normal
+// release if no pre-release identifier is configured; otherwise the pre-release number is incremented.
+func (o *Options) NextVersion(current semver.Version, bump Bump) semver.Version {
+	if bump == BumpNone {
+		return current
+	}
+	if bump == BumpMajor && o.BumpMinorPreMajor && current.Major == 0 {
+		bump = BumpMinor
+	}
+	next := semver.Version{Major: current.Major, Minor: current.Minor, Patch: current.Patch}
+	if len(current.Pre) > 0 {
+		// the version being pre-released may already contain the bump
+		switch {
+		case bump == BumpMajor && (current.Minor != 0 || current.Patch != 0):
+			next = semver.Version{Major: current.Major + 1}
+		case bump == BumpMinor && current.Patch != 0:
+			next = semver.Version{Major: current.Major, Minor: current.Minor + 1}
+		default:
+			if o.PreRelease != "" {
+				next.Pre = nextPreRelease(current.Pre, o.PreRelease)
+			}
+			return next
+		}
+	} else {
+		switch bump {
+		case BumpMajor:
+			next = semver.Version{Major: current.Major + 1}
+		case BumpMinor:
+			next = semver.Version{Major: current.Major, Minor: current.Minor + 1}
+		default:
+			next.Patch++
+		}
+	}
+	if o.PreRelease != "" {
+		next.Pre = nextPreRelease(nil, o.PreRelease)
+	}
+	return next
+}
+
+// nextPreRelease returns the next pre-release such as 'rc.2' after 'rc.1' or 'rc.1' if the identifier changes
+func nextPreRelease(current []semver.PRVersion, identifier string) []semver.PRVersion {
+	if len(current) == 2 && current[0].VersionStr == identifier && current[1].IsNum {
+		return []semver.PRVersion{current[0], {VersionNum: current[1].VersionNum + 1, IsNum: true}}
+	}
+	return []semver.PRVersion{{VersionStr: identifier}, {VersionNum: 1, IsNum: true}}
+}
+
+// LatestVersionTag returns the tag with the highest semantic version matching the tag prefix or an empty
+// string if there are no version tags
+func LatestVersionTag(g gitclient.Interface, dir string, o *Options) (string, semver.Version, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	tag, err := gitclient.LatestVersionTag(g, dir, &gitclient.VersionTagOptions{
+		Prefix:             o.tagPrefix(),
+		IncludePreReleases: o.IncludePreReleases || o.PreRelease != "",
+	})
+	if err != nil || tag == nil {
+		return "", semver.Version{}, err
+	}
+	return tag.Name, tag.Version, nil
+}
+
+// NextRelease calculates the next release from the conventional commits since the latest version tag
+func NextRelease(g gitclient.Interface, dir string, o *Options) (*Release, error) {
+	if o == nil {
+		o = &Options{}
+	}
+	tag, version, err := LatestVersionTag(g, dir, o)
+	if err != nil {
+		return nil, err
+	}
+	logCommits, err := gitlog.Log(g, dir, &gitlog.LogOptions{From: tag, NoMerges: true})
+	if err != nil {
+		return nil, err
+	}
+	release := &Release{
+		PreviousTag:     tag,
+		PreviousVersion: version,
+	}
+	for _, lc := range logCommits {
+		c, ok := Parse(lc.Message())
+		if !ok {
+			continue
+		}
+		c.SHA = lc.SHA
+		release.Commits = append(release.Commits, c)
+	}
+	release.Bump = o.MaxBump(release.Commits)
+	release.Version = o.NextVersion(version, release.Bump)
+	release.Tag = o.tagPrefix() + release.Version.String()
+	return release, nil
+}
+
+// IsRelease returns true if the commits require a new version to be released
+func (r *Release) IsRelease() bool {
+	return r.Bump != BumpNone
+}
+
+// ParseBump parses the bump name such as 'minor'
+func ParseBump(text string) (Bump, error) {
+	for _, b := range []Bump{BumpNone, BumpPatch, BumpMinor, BumpMajor} {
+		if strings.EqualFold(text, b.String()) {
+			return b, nil
+		}
+	}
+	return BumpNone, fmt.Errorf("invalid version bump '%s' should be one of none, patch, minor or major", text)
+}
@@ -0,0 +1,130 @@
+//go:build unit
+// +build unit
+
+package conventionalcommits_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/conventionalcommits"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextVersion(t *testing.T) {
+	testCases := []struct {
+		current  string
+		bump     conventionalcommits.Bump
+		options  conventionalcommits.Options
+		expected string
+	}{
+		{current: "1.2.3", bump: conventionalcommits.BumpNone, expected: "1.2.3"},
+		{current: "1.2.3", bump: conventionalcommits.BumpPatch, expected: "1.2.4"},
+		{current: "1.2.3", bump: conventionalcommits.BumpMinor, expected: "1.3.0"},
+		{current: "1.2.3", bump: conventionalcommits.BumpMajor, expected: "2.0.0"},
+		{current: "0.2.3", bump: conventionalcommits.BumpMajor, expected: "1.0.0"},
+		{current: "0.2.3", bump: conventionalcommits.BumpMajor, options: conventionalcommits.Options{BumpMinorPreMajor: true}, expected: "0.3.0"},
+		{current: "1.2.3", bump: conventionalcommits.BumpMinor, options: conventionalcommits.Options{PreRelease: "rc"}, expected: "1.3.0-rc.1"},
+		{current: "1.3.0-rc.1", bump: conventionalcommits.BumpPatch, options: conventionalcommits.Options{PreRelease: "rc"}, expected: "1.3.0-rc.2"},
+		{current: "1.3.0-rc.2", bump: conventionalcommits.BumpMinor, options: conventionalcommits.Options{PreRelease: "rc"}, expected: "1.3.0-rc.3"},
+		{current: "1.3.0-rc.2", bump: conventionalcommits.BumpMajor, options: conventionalcommits.Options{PreRelease: "rc"}, expected: "2.0.0-rc.1"},
+		{current: "1.3.0-beta.2", bump: conventionalcommits.BumpPatch, options: conventionalcommits.Options{PreRelease: "rc"}, expected: "1.3.0-rc.1"},
+		{current: "1.3.0-rc.2", bump: conventionalcommits.BumpPatch, expected: "1.3.0"},
+		{current: "1.3.1-rc.1", bump: conventionalcommits.BumpMinor, expected: "1.4.0"},
+	}
+
+	for _, tc := range testCases {
+		current := semver.MustParse(tc.current)
+		o := tc.options
+		got := o.NextVersion(current, tc.bump)
+		assert.Equal(t, tc.expected, got.String(), "for %s bump of %s with options %#v", tc.bump.String(), tc.current, tc.options)
+	}
+}
+
+func TestBump(t *testing.T) {
+	o := &conventionalcommits.Options{}
+	testCases := map[string]conventionalcommits.Bump{
+		"docs: fix typo":                    conventionalcommits.BumpNone,
+		"fix: handle nil":                   conventionalcommits.BumpPatch,
+		"perf: faster":                      conventionalcommits.BumpPatch,
+		"feat(cli): add flag":               conventionalcommits.BumpMinor,
+		"chore!: drop go 1.15":              conventionalcommits.BumpMajor,
+		"fix: x\n\nBREAKING CHANGE: remove": conventionalcommits.BumpMajor,
+	}
+	for message, expected := range testCases {
+		c, ok := conventionalcommits.Parse(message)
+		require.True(t, ok, "should parse %q", message)
+		assert.Equal(t, expected, o.Bump(c), "bump for %q", message)
+	}
+
+	b, err := conventionalcommits.ParseBump("Minor")
+	require.NoError(t, err)
+	assert.Equal(t, conventionalcommits.BumpMinor, b)
+	_, err = conventionalcommits.ParseBump("huge")
+	assert.Error(t, err, "should fail to parse an invalid bump")
+}
+
+func TestNextRelease(t *testing.T) {
+	g := fakegit.NewFakeGit()
+	gitURL := "https://github.com/myorg/myrepo.git"
+	_, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create remote repository")
+
+	dir, err := gitclient.CloneToDir(g, gitURL, filepath.Join(t.TempDir(), "myrepo"))
+	require.NoError(t, err, "failed to clone")
+	repo := g.Repository(dir)
+	require.NotNil(t, repo, "no repository for dir %s", dir)
+
+	release, err := conventionalcommits.NextRelease(g, dir, nil)
+	require.NoError(t, err, "failed to calculate the release without tags")
+	assert.Equal(t, "", release.PreviousTag, "PreviousTag")
+	assert.False(t, release.IsRelease(), "should not release without conventional commits")
+	assert.Equal(t, "v0.0.0", release.Tag, "Tag")
+
+	_, err = g.Command(dir, "tag", "v1.2.3")
+	require.NoError(t, err, "failed to tag")
+	_, err = g.Command(dir, "tag", "other-tag")
+	require.NoError(t, err, "failed to tag")
+
+	_, err = repo.CommitFiles("fix: handle nil", map[string]string{"a.txt": "a\n"})
+	require.NoError(t, err)
+	_, err = repo.CommitFiles("update docs", map[string]string{"b.txt": "b\n"})
+	require.NoError(t, err)
+	_, err = repo.CommitFiles("feat(cli): add flag\n\nsome details\n\nRefs #12", map[string]string{"c.txt": "c\n"})
+	require.NoError(t, err)
+
+	release, err = conventionalcommits.NextRelease(g, dir, nil)
+	require.NoError(t, err, "failed to calculate the release")
+	assert.Equal(t, "v1.2.3", release.PreviousTag, "PreviousTag")
+	assert.Equal(t, conventionalcommits.BumpMinor, release.Bump, "Bump")
+	assert.True(t, release.IsRelease(), "IsRelease")
+	assert.Equal(t, "v1.3.0", release.Tag, "Tag")
+	require.Len(t, release.Commits, 2, "conventional commits")
+	assert.Equal(t, "feat", release.Commits[0].Type, "newest commit type")
+	assert.Equal(t, []string{"12"}, release.Commits[0].FooterValues("Refs"), "footers")
+	assert.NotEmpty(t, release.Commits[0].SHA, "SHA")
+
+	o := &conventionalcommits.Options{PreRelease: "rc"}
+	release, err = conventionalcommits.NextRelease(g, dir, o)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.3.0-rc.1", release.Tag, "pre-release Tag")
+
+	_, err = g.Command(dir, "tag", release.Tag)
+	require.NoError(t, err, "failed to tag")
+	_, err = repo.CommitFiles("fix: another fix", map[string]string{"a.txt": "a2\n"})
+	require.NoError(t, err)
+
+	release, err = conventionalcommits.NextRelease(g, dir, o)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.3.0-rc.1", release.PreviousTag, "PreviousTag")
+	assert.Equal(t, "v1.3.0-rc.2", release.Tag, "next pre-release Tag")
+
+	release, err = conventionalcommits.NextRelease(g, dir, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.2.3", release.PreviousTag, "PreviousTag ignores pre-releases")
+	assert.Equal(t, "v1.3.0", release.Tag, "promoted Tag")
+}
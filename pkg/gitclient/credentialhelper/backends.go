@@ -0,0 +1,160 @@
+package credentialhelper
+
+import (
+	"strings"
+	"sync"
+)
+
+// Backend a source of git credentials used by the GitCredentialsHelper. Backends are queried on every operation
+// rather than caching credentials so that tokens which are rotated while the helper is in use are picked up
+type Backend interface {
+	// Get returns the credentials which may match the query. The GitCredentialsHelper picks the best match
+	Get(query GitCredential) ([]GitCredential, error)
+
+	// Store stores the credential replacing any existing credential with the same URL and username
+	Store(credential GitCredential) error
+
+	// Erase removes the credentials matching the non empty fields of the credential
+	Erase(credential GitCredential) error
+}
+
+// Writable marks the backend as a target of the store and erase operations of the GitCredentialsHelper. Backends
+// which are not marked are only read so that credentials, such as those in a cluster Secret, are never modified
+// by accident whenever git uses them
+func Writable(backend Backend) Backend {
+	return &writableBackend{Backend: backend}
+}
+
+// writableBackend a backend which is a target of the store and erase operations
+type writableBackend struct {
+	Backend
+}
+
+// isWritable returns true if the backend was marked by Writable
+func isWritable(backend Backend) bool {
+	_, ok := backend.(*writableBackend)
+	return ok
+}
+
+// MemoryBackend a Backend which keeps the credentials in memory
+type MemoryBackend struct {
+	lock        sync.Mutex
+	credentials []GitCredential
+}
+
+// NewMemoryBackend creates a Backend for the given credentials
+func NewMemoryBackend(credentials []GitCredential) *MemoryBackend {
+	return &MemoryBackend{credentials: append([]GitCredential{}, credentials...)}
+}
+
+// Get returns the credentials
+func (b *MemoryBackend) Get(_ GitCredential) ([]GitCredential, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return append([]GitCredential{}, b.credentials...), nil
+}
+
+// Store stores the credential in memory
+func (b *MemoryBackend) Store(credential GitCredential) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.credentials, _ = storeCredential(b.credentials, credential)
+	return nil
+}
+
+// Erase removes the matching credentials from memory
+func (b *MemoryBackend) Erase(credential GitCredential) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.credentials, _ = eraseCredentials(b.credentials, credential)
+	return nil
+}
+
+// bestMatch returns the index of the credential which matches the query with the longest path prefix or -1 if
+// none match. If several credentials have the same path the first one wins
+func bestMatch(credentials []GitCredential, query GitCredential) int {
+	answer := -1
+	longest := -1
+	for i := range credentials {
+		length, ok := matchLength(credentials[i], query)
+		if ok && length > longest {
+			answer = i
+			longest = length
+		}
+	}
+	return answer
+}
+
+// matchLength returns true and the length of the path of the credential if it can be used for the query. The
+// protocol and host must be equal, the path of the credential must be a prefix of the path of the query and if
+// both specify a username it must be equal
+func matchLength(credential GitCredential, query GitCredential) (int, bool) {
+	if credential.Protocol != query.Protocol || credential.Host != query.Host {
+		return 0, false
+	}
+	if query.Username != "" && credential.Username != "" && query.Username != credential.Username {
+		return 0, false
+	}
+	prefix := normalizePath(credential.Path)
+	if prefix == "" {
+		return 0, true
+	}
+	path := normalizePath(query.Path)
+	if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+		return 0, false
+	}
+	return len(prefix), true
+}
+
+// sameURL returns true if the credentials are for the same URL and username so that storing one replaces the other
+func sameURL(a GitCredential, b GitCredential) bool {
+	return a.Protocol == b.Protocol && a.Host == b.Host && normalizePath(a.Path) == normalizePath(b.Path) &&
+		a.Username == b.Username
+}
+
+// erases returns true if the credential should be erased as it matches the non empty fields of want like git
+func erases(want GitCredential, credential GitCredential) bool {
+	return matchesField(want.Protocol, credential.Protocol) &&
+		matchesField(want.Host, credential.Host) &&
+		matchesField(normalizePath(want.Path), normalizePath(credential.Path)) &&
+		matchesField(want.Username, credential.Username) &&
+		matchesField(want.Password, credential.Password)
+}
+
+func matchesField(want string, actual string) bool {
+	return want == "" || want == actual
+}
+
+// storeCredential returns the credentials with the credential added at the front replacing any with the same URL
+// and whether they were modified
+func storeCredential(credentials []GitCredential, credential GitCredential) ([]GitCredential, bool) {
+	if len(credentials) > 0 && credentials[0] == credential {
+		return credentials, false
+	}
+	answer := []GitCredential{credential}
+	for _, c := range credentials {
+		if !sameURL(c, credential) {
+			answer = append(answer, c)
+		}
+	}
+	return answer, true
+}
+
+// eraseCredentials returns the credentials without those matching want and whether any were removed
+func eraseCredentials(credentials []GitCredential, want GitCredential) ([]GitCredential, bool) {
+	var answer []GitCredential
+	removed := false
+	for _, c := range credentials {
+		if erases(want, c) {
+			removed = true
+			continue
+		}
+		answer = append(answer, c)
+	}
+	return answer, removed
+}
+
+// normalizePath removes any leading and trailing slashes as git sends paths without a leading slash
+func normalizePath(path string) string {
+	return strings.Trim(path, "/")
+}
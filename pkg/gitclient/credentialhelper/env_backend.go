@@ -0,0 +1,70 @@
+package credentialhelper
+
+import (
+	"fmt"
+	"os"
+)
+
+const (
+	// GIT_SERVER the environment variable for the URL of the git server, optionally with a path prefix, which
+	// the environment credentials are for. Defaults to https://github.com
+	GIT_SERVER = "GIT_SERVER"
+	// GIT_USERNAME the environment variable for the git username
+	GIT_USERNAME = "GIT_USERNAME"
+	// GIT_TOKEN the environment variable for the git token or password
+	GIT_TOKEN = "GIT_TOKEN"
+)
+
+// EnvironmentBackend a read only Backend which uses a credential from environment variables
+type EnvironmentBackend struct {
+	// ServerEnv the environment variable for the git server URL which defaults to $GIT_SERVER
+	ServerEnv string
+	// UsernameEnv the environment variable for the username which defaults to $GIT_USERNAME
+	UsernameEnv string
+	// TokenEnv the environment variable for the token or password which defaults to $GIT_TOKEN
+	TokenEnv string
+}
+
+// NewEnvironmentBackend creates a Backend using the $GIT_SERVER, $GIT_USERNAME and $GIT_TOKEN environment variables
+func NewEnvironmentBackend() *EnvironmentBackend {
+	return &EnvironmentBackend{
+		ServerEnv:   GIT_SERVER,
+		UsernameEnv: GIT_USERNAME,
+		TokenEnv:    GIT_TOKEN,
+	}
+}
+
+// Get returns the credential from the environment variables if the token is set
+func (b *EnvironmentBackend) Get(_ GitCredential) ([]GitCredential, error) {
+	token := os.Getenv(defaultEnv(b.TokenEnv, GIT_TOKEN))
+	if token == "" {
+		return nil, nil
+	}
+	serverEnv := defaultEnv(b.ServerEnv, GIT_SERVER)
+	server, err := parseGitSecretServerUrl(os.Getenv(serverEnv))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse environment variable %s: %w", serverEnv, err)
+	}
+	credential, err := CreateGitCredentialFromURL(server.String(), os.Getenv(defaultEnv(b.UsernameEnv, GIT_USERNAME)), token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid git credential in environment variable %s: %w", serverEnv, err)
+	}
+	return []GitCredential{credential}, nil
+}
+
+// Store does nothing as environment variables cannot be persisted
+func (b *EnvironmentBackend) Store(_ GitCredential) error {
+	return nil
+}
+
+// Erase does nothing as environment variables cannot be persisted
+func (b *EnvironmentBackend) Erase(_ GitCredential) error {
+	return nil
+}
+
+func defaultEnv(name string, defaultName string) string {
+	if name == "" {
+		return defaultName
+	}
+	return name
+}
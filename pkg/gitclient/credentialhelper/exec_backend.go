@@ -0,0 +1,124 @@
+package credentialhelper
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+)
+
+// ExecBackend a Backend which runs an external git credential helper program such as git-credential-store
+// or a program which fetches tokens from a vault. The operation is passed as the last argument and the credential
+// is written to stdin using the git credential protocol, see https://git-scm.com/docs/gitcredentials
+type ExecBackend struct {
+	// Command the program to run
+	Command string
+	// Args the arguments passed before the operation
+	Args []string
+	// Dir the optional directory to run the program in
+	Dir string
+	// CommandRunner the runner of the program which defaults to cmdrunner.QuietCommandRunner
+	CommandRunner cmdrunner.CommandRunner
+}
+
+// NewExecBackend creates a Backend which runs the program with the arguments
+func NewExecBackend(command string, args ...string) *ExecBackend {
+	return &ExecBackend{
+		Command: command,
+		Args:    args,
+	}
+}
+
+// Get runs the get operation of the program returning its credential if it returned a password
+func (b *ExecBackend) Get(query GitCredential) ([]GitCredential, error) {
+	out, err := b.run("get", query)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	credential, err := CreateGitCredential(lines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the output of %s: %w", b.Command, err)
+	}
+	if credential.Password == "" {
+		return nil, nil
+	}
+
+	// the program only needs to return the fields it knows about
+	if credential.Protocol == "" {
+		credential.Protocol = query.Protocol
+	}
+	if credential.Host == "" {
+		credential.Host = query.Host
+	}
+	if credential.Path == "" {
+		credential.Path = query.Path
+	}
+	return []GitCredential{credential}, nil
+}
+
+// Store runs the store operation of the program
+func (b *ExecBackend) Store(credential GitCredential) error {
+	_, err := b.run("store", credential)
+	return err
+}
+
+// Erase runs the erase operation of the program
+func (b *ExecBackend) Erase(credential GitCredential) error {
+	_, err := b.run("erase", credential)
+	return err
+}
+
+// run runs the operation of the program returning its standard output
+func (b *ExecBackend) run(op string, credential GitCredential) (string, error) {
+	runner := b.CommandRunner
+	if runner == nil {
+		runner = cmdrunner.QuietCommandRunner
+	}
+	var out bytes.Buffer
+	c := &cmdrunner.Command{
+		Dir:  b.Dir,
+		Name: b.Command,
+		Args: append(append([]string{}, b.Args...), op),
+		In:   strings.NewReader(requestString(credential)),
+		Out:  &out,
+	}
+	c.AddSecrets(credential.Password)
+	_, err := runner(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to run git credential helper %s %s: %w", b.Command, op, err)
+	}
+	return out.String(), nil
+}
+
+// requestString returns the credential in the git credential protocol format omitting empty fields
+// as an empty password would otherwise be treated as a known password
+func requestString(credential GitCredential) string {
+	var buf strings.Builder
+	fields := []struct {
+		name  string
+		value string
+	}{
+		{"protocol", credential.Protocol},
+		{"host", credential.Host},
+		{"path", credential.Path},
+		{"username", credential.Username},
+		{"password", credential.Password},
+	}
+	for _, f := range fields {
+		if f.value != "" {
+			buf.WriteString(f.name + "=" + f.value + "\n")
+		}
+	}
+	buf.WriteString("\n")
+	return buf.String()
+}
@@ -0,0 +1,195 @@
+package credentialhelper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/homedir"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// GitCredentialsFile returns the location of the git credentials file in `$XDG_CONFIG_HOME/git/credentials`,
+// `~/.git-credentials` or one of the other well known locations. If no file exists the default location is returned
+func GitCredentialsFile() (string, error) {
+	homeDir := homedir.HomeDir()
+	cfgHome := os.Getenv(XDG_CONFIG_HOME)
+	if cfgHome == "" {
+		cfgHome = homeDir
+	}
+	if cfgHome == "" {
+		cfgHome = "."
+	}
+	paths := []string{
+		filepath.Join(cfgHome, "git", "credentials"),
+		filepath.Join(cfgHome, ".git-credentials"),
+	}
+	if homeDir != "" && homeDir != cfgHome {
+		paths = append(paths,
+			filepath.Join(homeDir, "git", "credentials"),
+			filepath.Join(homeDir, ".git-credentials"),
+		)
+	}
+	paths = append(paths,
+		filepath.Join("git", "credentials"),
+		filepath.Join(".git-credentials"),
+	)
+	for _, path := range paths {
+		exists, err := files.FileExists(path)
+		if err != nil {
+			return path, fmt.Errorf("failed to check if git credentials file exists %s: %w", path, err)
+		}
+		if exists {
+			return path, nil
+		}
+	}
+
+	// lets return the default name we think should be used....
+	return filepath.Join(cfgHome, "git", "credentials"), nil
+}
+
+// FileBackend a Backend which uses a git credentials file in the format of git-credential-store
+// see https://git-scm.com/docs/git-credential-store
+type FileBackend struct {
+	// Path the path of the credentials file
+	Path string
+
+	lock sync.Mutex
+}
+
+// NewFileBackend creates a Backend for the credentials file. If the path is empty the file returned by
+// GitCredentialsFile is used so that the credentials are shared with the loadcreds package
+func NewFileBackend(path string) (*FileBackend, error) {
+	if path == "" {
+		var err error
+		path, err = GitCredentialsFile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find git credentials file: %w", err)
+		}
+	}
+	return &FileBackend{Path: path}, nil
+}
+
+// Get returns the credentials in the file which is read on every call
+func (b *FileBackend) Get(_ GitCredential) ([]GitCredential, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.load()
+}
+
+// Store stores the credential at the start of the file replacing any existing credential with the same URL
+func (b *FileBackend) Store(credential GitCredential) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	credentials, err := b.load()
+	if err != nil {
+		return err
+	}
+	credentials, modified := storeCredential(credentials, credential)
+	if !modified {
+		return nil
+	}
+	return b.save(credentials)
+}
+
+// Erase removes the matching credentials from the file
+func (b *FileBackend) Erase(credential GitCredential) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	credentials, err := b.load()
+	if err != nil {
+		return err
+	}
+	credentials, removed := eraseCredentials(credentials, credential)
+	if !removed {
+		return nil
+	}
+	return b.save(credentials)
+}
+
+func (b *FileBackend) load() ([]GitCredential, error) {
+	data, err := os.ReadFile(b.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load git credentials file %s: %w", b.Path, err)
+	}
+	return parseCredentials(data, b.Path), nil
+}
+
+// save writes the credentials to a temporary file which is renamed so that readers never see a partial file
+func (b *FileBackend) save(credentials []GitCredential) error {
+	dir := filepath.Dir(b.Path)
+	err := os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	tmpFile, err := os.CreateTemp(dir, filepath.Base(b.Path)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file in %s: %w", dir, err)
+	}
+	defer os.Remove(tmpFile.Name()) //nolint:errcheck
+
+	_, err = tmpFile.Write(formatCredentials(credentials))
+	if err != nil {
+		tmpFile.Close() //nolint:errcheck
+		return fmt.Errorf("failed to write git credentials to %s: %w", tmpFile.Name(), err)
+	}
+	err = tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close %s: %w", tmpFile.Name(), err)
+	}
+	err = os.Rename(tmpFile.Name(), b.Path)
+	if err != nil {
+		return fmt.Errorf("failed to save git credentials file %s: %w", b.Path, err)
+	}
+	return nil
+}
+
+// parseCredentials parses the lines of a git credentials file ignoring any invalid lines
+func parseCredentials(data []byte, source string) []GitCredential {
+	var answer []GitCredential
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.User == nil {
+			log.Logger().Warnf("ignoring invalid line in git credentials %s", source)
+			continue
+		}
+		password, _ := u.User.Password()
+		answer = append(answer, GitCredential{
+			Protocol: u.Scheme,
+			Host:     u.Host,
+			Path:     normalizePath(u.Path),
+			Username: u.User.Username(),
+			Password: password,
+		})
+	}
+	return answer
+}
+
+// formatCredentials formats the credentials as the lines of a git credentials file
+func formatCredentials(credentials []GitCredential) []byte {
+	var buf strings.Builder
+	for _, c := range credentials {
+		u := url.URL{
+			Scheme: c.Protocol,
+			User:   url.UserPassword(c.Username, c.Password),
+			Host:   c.Host,
+		}
+		if path := normalizePath(c.Path); path != "" {
+			u.Path = "/" + path
+		}
+		buf.WriteString(u.String())
+		buf.WriteString("\n")
+	}
+	return []byte(buf.String())
+}
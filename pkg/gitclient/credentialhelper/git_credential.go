@@ -45,9 +45,14 @@ func CreateGitCredential(lines []string) (GitCredential, error) {
 		return credential, errors.New("no data lines provided")
 	}
 
-	fieldMap, err := stringhelpers.ExtractKeyValuePairs(lines, "=")
-	if err != nil {
-		return credential, fmt.Errorf("unable to extract git credential parameters: %w", err)
+	// lets only split on the first '=' as tokens and passwords may contain it
+	fieldMap := map[string]string{}
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return credential, fmt.Errorf("unable to extract git credential parameters: expected key value pair '%s'", line)
+		}
+		fieldMap[key] = value
 	}
 
 	data, err := json.Marshal(fieldMap)
@@ -5,19 +5,32 @@ import (
 	"errors"
 	"fmt"
 	"io"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 )
 
 // GitCredentialsHelper is used to implement the git credential helper algorithm.
 // See also https://git-scm.com/docs/git-credential.
 type GitCredentialsHelper struct {
-	in               io.Reader
-	out              io.Writer
-	knownCredentials []GitCredential
+	in       io.Reader
+	out      io.Writer
+	backends []Backend
 }
 
 // CreateGitCredentialsHelper creates an instance of a git credential helper. It needs to get passed the handles to read
 // the git credential data as well as write the response to. It also gets the list og known credentials.
 func CreateGitCredentialsHelper(in io.Reader, out io.Writer, credentials []GitCredential) (*GitCredentialsHelper, error) {
+	if credentials == nil {
+		return nil, errors.New("credentials parameter cannot be nil")
+	}
+	return CreateGitCredentialsHelperWithBackends(in, out, Writable(NewMemoryBackend(credentials)))
+}
+
+// CreateGitCredentialsHelperWithBackends creates an instance of a git credential helper which uses the given backends.
+// When several credentials match a request the one with the longest path prefix wins and if they have the same
+// path the credential from the earliest backend wins. The store and erase operations are only applied to the backends
+// marked with Writable so that other backends are only read
+func CreateGitCredentialsHelperWithBackends(in io.Reader, out io.Writer, backends ...Backend) (*GitCredentialsHelper, error) {
 	if in == nil {
 		return nil, errors.New("in parameter cannot be nil")
 	}
@@ -26,19 +39,18 @@ func CreateGitCredentialsHelper(in io.Reader, out io.Writer, credentials []GitCr
 		return nil, errors.New("out parameter cannot be nil")
 	}
 
-	if credentials == nil {
-		return nil, errors.New("credentials parameter cannot be nil")
+	if len(backends) == 0 {
+		return nil, errors.New("backends parameter cannot be empty")
 	}
 
 	return &GitCredentialsHelper{
-		in:               in,
-		out:              out,
-		knownCredentials: credentials,
+		in:       in,
+		out:      out,
+		backends: backends,
 	}, nil
 }
 
 // Run executes the specified git credential helper operation which must be one of get, store or erase.
-// NOTE: Currently only get is implemented.
 func (h *GitCredentialsHelper) Run(op string) error {
 	var err error
 
@@ -46,11 +58,9 @@ func (h *GitCredentialsHelper) Run(op string) error {
 	case "get":
 		err = h.Get()
 	case "store":
-		// not yet implemented (HF)
-		fmt.Println("")
+		err = h.Store()
 	case "erase":
-		// not yet implemented (HF)
-		fmt.Println("")
+		err = h.Erase()
 	default:
 		err = fmt.Errorf("invalid git credential operation '%s'", op)
 	}
@@ -61,50 +71,118 @@ func (h *GitCredentialsHelper) Run(op string) error {
 // Get implements the get operation of the git credential helper protocol. It reads the authentication query from
 // the reader of this instance and writes the response to the writer (usually this will be stdout and stdin).
 func (h *GitCredentialsHelper) Get() error {
-	var data []string
-	scanner := bufio.NewScanner(h.in)
-	for scanner.Scan() {
-		data = append(data, scanner.Text())
+	gitCredential, err := h.readCredential()
+	if err != nil {
+		return err
 	}
 
-	if scanner.Err() != nil {
-		return fmt.Errorf("unable to read input from stdin: %w", scanner.Err())
+	answer := h.Fill(gitCredential)
+
+	_, err = fmt.Fprint(h.out, answer.String())
+	if err != nil {
+		return fmt.Errorf("unable to write response to stdin: %w", err)
 	}
 
-	gitCredential, err := CreateGitCredential(data)
+	return nil
+}
+
+// Store implements the store operation of the git credential helper protocol. It reads the credential which git
+// successfully used from the reader of this instance and stores it in the writable backends
+func (h *GitCredentialsHelper) Store() error {
+	gitCredential, err := h.readCredential()
 	if err != nil {
-		return fmt.Errorf("unable to create GitCredential struct: %w", scanner.Err())
+		return err
+	}
+	if gitCredential.Protocol == "" || gitCredential.Host == "" || gitCredential.Password == "" {
+		// git only stores complete credentials
+		return nil
 	}
 
-	answer := h.Fill(gitCredential)
+	var errs []error
+	for _, backend := range h.backends {
+		if !isWritable(backend) {
+			continue
+		}
+		err = backend.Store(gitCredential)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to store git credential for %s: %w", gitCredential.Host, errors.Join(errs...))
+	}
+	return nil
+}
 
-	_, err = fmt.Fprint(h.out, answer.String())
+// Erase implements the erase operation of the git credential helper protocol. It reads the credential which git
+// failed to use from the reader of this instance and removes the matching credentials from the writable backends
+func (h *GitCredentialsHelper) Erase() error {
+	gitCredential, err := h.readCredential()
 	if err != nil {
-		return fmt.Errorf("unable to write response to stdin: %w", err)
+		return err
+	}
+	if gitCredential.Protocol == "" || gitCredential.Host == "" {
+		// lets not erase every credential
+		return nil
 	}
 
+	var errs []error
+	for _, backend := range h.backends {
+		if !isWritable(backend) {
+			continue
+		}
+		err = backend.Erase(gitCredential)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to erase git credential for %s: %w", gitCredential.Host, errors.Join(errs...))
+	}
 	return nil
 }
 
 // Fill creates a GitCredential instance based on a git credential helper request which represented by the passed queryCredential instance.
 // If there is no auth information available an empty credential instance is returned
 func (h *GitCredentialsHelper) Fill(queryCredential GitCredential) GitCredential {
-	for _, authCredential := range h.knownCredentials {
-		if queryCredential.Protocol != authCredential.Protocol {
+	var candidates []GitCredential
+	for _, backend := range h.backends {
+		credentials, err := backend.Get(queryCredential)
+		if err != nil {
+			// lets try the other backends
+			log.Logger().Warnf("failed to get git credentials: %s", err.Error())
 			continue
 		}
+		candidates = append(candidates, credentials...)
+	}
 
-		if queryCredential.Host != authCredential.Host {
-			continue
-		}
+	idx := bestMatch(candidates, queryCredential)
+	if idx < 0 {
+		return GitCredential{}
+	}
+	return candidates[idx].Clone()
+}
 
-		if queryCredential.Path != authCredential.Path {
-			continue
+// readCredential reads the credential of the request from the reader of this instance
+func (h *GitCredentialsHelper) readCredential() (GitCredential, error) {
+	var data []string
+	scanner := bufio.NewScanner(h.in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// a blank line terminates the request
+			break
 		}
+		data = append(data, line)
+	}
 
-		answer := authCredential.Clone()
-		return answer
+	if scanner.Err() != nil {
+		return GitCredential{}, fmt.Errorf("unable to read input from stdin: %w", scanner.Err())
 	}
 
-	return GitCredential{}
+	gitCredential, err := CreateGitCredential(data)
+	if err != nil {
+		return GitCredential{}, fmt.Errorf("unable to create GitCredential struct: %w", err)
+	}
+	return gitCredential, nil
 }
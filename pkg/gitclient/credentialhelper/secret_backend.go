@@ -0,0 +1,152 @@
+package credentialhelper
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// SecretCredentialsKey the key of the Secret data containing credentials in the git credentials file format
+	SecretCredentialsKey = "credentials"
+)
+
+// SecretBackend a Backend which uses a Kubernetes Secret. Credentials are stored in the SecretCredentialsKey entry
+// using the format of a git credentials file. The `url`, `username` and `password` entries used by the jx-boot
+// Secret are also read but are never modified
+type SecretBackend struct {
+	// KubeClient the client used to read and write the Secret
+	KubeClient kubernetes.Interface
+	// Namespace the namespace of the Secret
+	Namespace string
+	// Name the name of the Secret
+	Name string
+	// CreateIfMissing creates the Secret when storing a credential if it does not exist rather than failing
+	CreateIfMissing bool
+}
+
+// NewSecretBackend creates a Backend for the Secret
+func NewSecretBackend(kubeClient kubernetes.Interface, namespace, name string) *SecretBackend {
+	return &SecretBackend{
+		KubeClient: kubeClient,
+		Namespace:  namespace,
+		Name:       name,
+	}
+}
+
+// Get returns the credentials in the Secret which is read on every call
+func (b *SecretBackend) Get(_ GitCredential) ([]GitCredential, error) {
+	secret, err := b.KubeClient.CoreV1().Secrets(b.Namespace).Get(context.TODO(), b.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find Secret %s in namespace %s: %w", b.Name, b.Namespace, err)
+	}
+	answer := parseCredentials(secret.Data[SecretCredentialsKey], b.source())
+	credential, ok, err := b.urlCredential(secret)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		answer = append(answer, credential)
+	}
+	return answer, nil
+}
+
+// Store stores the credential in the SecretCredentialsKey entry of the Secret. The Secret is only updated if the
+// credential has changed and is only created if CreateIfMissing is true
+func (b *SecretBackend) Store(credential GitCredential) error {
+	return b.update(b.CreateIfMissing, func(secret *corev1.Secret) bool {
+		credentials, modified := storeCredential(parseCredentials(secret.Data[SecretCredentialsKey], b.source()), credential)
+		if modified {
+			secret.Data[SecretCredentialsKey] = formatCredentials(credentials)
+		}
+		return modified
+	})
+}
+
+// Erase removes the matching credentials from the SecretCredentialsKey entry of the Secret
+func (b *SecretBackend) Erase(credential GitCredential) error {
+	err := b.update(false, func(secret *corev1.Secret) bool {
+		credentials, removed := eraseCredentials(parseCredentials(secret.Data[SecretCredentialsKey], b.source()), credential)
+		if removed {
+			secret.Data[SecretCredentialsKey] = formatCredentials(credentials)
+		}
+		return removed
+	})
+	if apierrors.IsNotFound(err) {
+		// there is nothing to erase
+		return nil
+	}
+	return err
+}
+
+// update modifies the latest version of the Secret retrying on conflicts as other processes may be rotating
+// the credentials at the same time. If the Secret does not exist it is created if create is true
+func (b *SecretBackend) update(create bool, fn func(secret *corev1.Secret) bool) error {
+	secretInterface := b.KubeClient.CoreV1().Secrets(b.Namespace)
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secretInterface.Get(context.TODO(), b.Name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) || !create {
+				return err
+			}
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      b.Name,
+					Namespace: b.Namespace,
+				},
+				Data: map[string][]byte{},
+			}
+			fn(secret)
+			_, err = secretInterface.Create(context.TODO(), secret, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// lets retry with the secret created by another process
+				return apierrors.NewConflict(corev1.Resource("secrets"), b.Name, err)
+			}
+			return err
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		if !fn(secret) {
+			return nil
+		}
+		_, err = secretInterface.Update(context.TODO(), secret, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update Secret %s in namespace %s: %w", b.Name, b.Namespace, err)
+	}
+	return nil
+}
+
+// urlCredential returns the credential from the `url`, `username` and `password` entries of the Secret if present
+func (b *SecretBackend) urlCredential(secret *corev1.Secret) (GitCredential, bool, error) {
+	gitURL := string(secret.Data["url"])
+	password := string(secret.Data["password"])
+	if gitURL == "" || password == "" {
+		return GitCredential{}, false, nil
+	}
+	// lets convert the git URL into a provider URL
+	gitInfo, err := giturl.ParseGitURL(gitURL)
+	if err != nil {
+		return GitCredential{}, false, fmt.Errorf("failed to parse git URL %s in %s: %w", gitURL, b.source(), err)
+	}
+	credential, err := CreateGitCredentialFromURL(gitInfo.HostURL(), string(secret.Data["username"]), password)
+	if err != nil {
+		return GitCredential{}, false, fmt.Errorf("invalid git auth information in %s: %w", b.source(), err)
+	}
+	return credential, true, nil
+}
+
+func (b *SecretBackend) source() string {
+	return fmt.Sprintf("Secret %s in namespace %s", b.Name, b.Namespace)
+}
@@ -0,0 +1,895 @@
+package fakegit
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func (f *FakeGit) init(dir string, a *args) (string, error) {
+	target := dir
+	if len(a.positional) > 0 {
+		target = a.positional[0]
+	}
+	key := normalizeKey(dir, target)
+	if r := f.Repositories[key]; r != nil {
+		return fmt.Sprintf("Reinitialized existing Git repository in %s", filepath.Join(key, ".git")), nil
+	}
+	r := f.newRepository(key, a.has("--bare"))
+	branch := a.value("-b", "--initial-branch")
+	if branch == "" {
+		branch = f.configValue(nil, "init.defaultBranch")
+	}
+	if branch != "" {
+		r.Head = branch
+	}
+	return fmt.Sprintf("Initialized empty Git repository in %s", filepath.Join(key, ".git")), nil
+}
+
+func (f *FakeGit) clone(dir string, a *args) (string, error) {
+	if len(a.positional) == 0 {
+		return "", fmt.Errorf("fatal: You must specify a repository to clone.")
+	}
+	gitURL := a.positional[0]
+	src := f.Repositories[normalizeKey(dir, gitURL)]
+	if src == nil {
+		return "", fmt.Errorf("fatal: repository '%s' does not exist", gitURL)
+	}
+	target := strings.TrimSuffix(path.Base(strings.TrimSuffix(gitURL, "/")), ".git")
+	if len(a.positional) > 1 {
+		target = a.positional[1]
+	}
+	key := normalizeKey(dir, target)
+	if existing := f.Repositories[key]; existing != nil {
+		return "", fmt.Errorf("fatal: destination path '%s' already exists and is not an empty directory", target)
+	}
+	r := f.newRepository(key, a.has("--bare"))
+	remoteName := a.value("-o", "--origin")
+	if remoteName == "" {
+		remoteName = "origin"
+	}
+	r.Remotes[remoteName] = gitURL
+
+	branch := a.value("-b", "--branch")
+	if branch == "" {
+		branch = src.Head
+	}
+	singleBranch := a.has("--single-branch") || a.has("--depth")
+	for name, sha := range src.Branches {
+		if singleBranch && name != branch {
+			continue
+		}
+		src.copyObjects(r, sha)
+		r.RemoteBranches[remoteName+"/"+name] = sha
+	}
+	for name, t := range src.Tags {
+		src.copyObjects(r, t.SHA)
+		tag := *t
+		r.Tags[name] = &tag
+	}
+
+	output := fmt.Sprintf("Cloning into '%s'...", target)
+	sha := src.Branches[branch]
+	switch {
+	case sha != "":
+		r.Head = branch
+		r.Branches[branch] = sha
+		r.Upstreams[branch] = remoteName + "/" + branch
+	case src.Tags[branch] != nil:
+		r.Head = ""
+		r.DetachedHead = src.Tags[branch].SHA
+	case len(src.Branches) == 0:
+		r.Head = branch
+		return output + "\nwarning: You appear to have cloned an empty repository.", nil
+	default:
+		delete(f.Repositories, key)
+		return "", fmt.Errorf("fatal: Remote branch %s not found in upstream %s", branch, remoteName)
+	}
+	if r.Bare {
+		return output, nil
+	}
+	tree := r.Commits[r.headSHA()].Tree
+	r.Index = copyFiles(tree)
+	if a.has("-n", "--no-checkout") {
+		r.noCheckout = true
+		return output, nil
+	}
+	return output, r.updateWorkTree(r.sparseFiles(tree))
+}
+
+func (f *FakeGit) config(dir string, a *args) (string, error) {
+	r := f.findRepository(dir)
+	cfg := f.GlobalConfig
+	if !a.has("--global", "--system") {
+		if r == nil {
+			return "", fmt.Errorf("fatal: not in a git directory")
+		}
+		cfg = r.Config
+	}
+	switch {
+	case a.has("-l", "--list"):
+		var lines []string
+		for _, k := range sortedConfigKeys(cfg) {
+			for _, v := range cfg[k] {
+				lines = append(lines, k+"="+v)
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	case len(a.positional) == 0:
+		return "", fmt.Errorf("error: no key specified")
+	}
+	key := a.positional[0]
+	switch {
+	case a.has("--unset", "--unset-all"):
+		if _, ok := cfg[key]; !ok {
+			return "", fmt.Errorf("exit status 5")
+		}
+		delete(cfg, key)
+		return "", nil
+	case a.has("--add"):
+		if len(a.positional) < 2 {
+			return "", fmt.Errorf("error: wrong number of arguments, should be 2")
+		}
+		cfg[key] = append(cfg[key], a.positional[1])
+		return "", nil
+	case a.has("--get-all"):
+		if len(cfg[key]) == 0 {
+			return "", fmt.Errorf("exit status 1")
+		}
+		return strings.Join(cfg[key], "\n"), nil
+	case len(a.positional) > 1 && !a.has("--get"):
+		cfg[key] = []string{a.positional[1]}
+		return "", nil
+	default:
+		values := cfg[key]
+		if len(values) == 0 {
+			return "", fmt.Errorf("exit status 1")
+		}
+		return values[len(values)-1], nil
+	}
+}
+
+func sortedConfigKeys(m map[string][]string) []string {
+	answer := make([]string, 0, len(m))
+	for k := range m {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+func (r *Repository) remote(a *args) (string, error) {
+	if len(a.positional) == 0 {
+		var lines []string
+		for _, name := range sortedKeys(r.Remotes) {
+			if a.has("-v", "--verbose") {
+				lines = append(lines, fmt.Sprintf("%s\t%s (fetch)", name, r.Remotes[name]), fmt.Sprintf("%s\t%s (push)", name, r.Remotes[name]))
+				continue
+			}
+			lines = append(lines, name)
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	action := a.positional[0]
+	params := a.positional[1:]
+	switch action {
+	case "add":
+		if len(params) != 2 {
+			return "", fmt.Errorf("usage: git remote add <name> <url>")
+		}
+		if _, ok := r.Remotes[params[0]]; ok {
+			return "", fmt.Errorf("error: remote %s already exists.", params[0])
+		}
+		r.Remotes[params[0]] = params[1]
+		return "", nil
+	case "set-url":
+		if len(params) != 2 {
+			return "", fmt.Errorf("usage: git remote set-url <name> <url>")
+		}
+		if _, ok := r.Remotes[params[0]]; !ok {
+			return "", fmt.Errorf("error: No such remote '%s'", params[0])
+		}
+		r.Remotes[params[0]] = params[1]
+		return "", nil
+	case "get-url":
+		if len(params) != 1 {
+			return "", fmt.Errorf("usage: git remote get-url <name>")
+		}
+		u, ok := r.Remotes[params[0]]
+		if !ok {
+			return "", fmt.Errorf("error: No such remote '%s'", params[0])
+		}
+		return u, nil
+	case "remove", "rm":
+		if len(params) != 1 {
+			return "", fmt.Errorf("usage: git remote remove <name>")
+		}
+		if _, ok := r.Remotes[params[0]]; !ok {
+			return "", fmt.Errorf("error: No such remote: '%s'", params[0])
+		}
+		delete(r.Remotes, params[0])
+		for name := range r.RemoteBranches {
+			if strings.HasPrefix(name, params[0]+"/") {
+				delete(r.RemoteBranches, name)
+			}
+		}
+		return "", nil
+	default:
+		return "", fmt.Errorf("error: unknown subcommand: %s", action)
+	}
+}
+
+// remoteRepository returns the name, URL and repository of the remote name or URL
+func (r *Repository) remoteRepository(remote string) (string, string, *Repository, error) {
+	if remote == "" {
+		remote = "origin"
+		if upstream := r.Upstreams[r.Head]; upstream != "" {
+			remote, _, _ = strings.Cut(upstream, "/")
+		}
+	}
+	name := remote
+	gitURL, ok := r.Remotes[remote]
+	if !ok {
+		if !isURL(remote) && !strings.Contains(remote, "/") && !strings.HasPrefix(remote, ".") {
+			return "", "", nil, fmt.Errorf("fatal: '%s' does not appear to be a git repository", remote)
+		}
+		name = ""
+		gitURL = remote
+	}
+	src := r.fake.Repositories[normalizeKey(r.Dir, gitURL)]
+	if src == nil {
+		return "", "", nil, fmt.Errorf("fatal: repository '%s' not found", gitURL)
+	}
+	return name, gitURL, src, nil
+}
+
+func (r *Repository) fetch(a *args) (string, error) {
+	remote := ""
+	if len(a.positional) > 0 {
+		remote = a.positional[0]
+	}
+	name, _, src, err := r.remoteRepository(remote)
+	if err != nil {
+		return "", err
+	}
+	var refspecs []string
+	if len(a.positional) > 1 {
+		refspecs = a.positional[1:]
+	}
+	if len(refspecs) == 0 {
+		if name == "" {
+			refspecs = []string{"HEAD"}
+		} else {
+			refspecs = sortedKeys(src.Branches)
+		}
+		if a.has("--prune", "-p") && name != "" {
+			for rb := range r.RemoteBranches {
+				if b, ok := strings.CutPrefix(rb, name+"/"); ok && src.Branches[b] == "" {
+					delete(r.RemoteBranches, rb)
+				}
+			}
+		}
+	}
+	r.FetchHead = ""
+	for _, refspec := range refspecs {
+		refspec = strings.TrimPrefix(refspec, "+")
+		srcRef, dstRef, _ := strings.Cut(refspec, ":")
+		sha, err := src.resolve(srcRef)
+		if err != nil {
+			return "", fmt.Errorf("fatal: couldn't find remote ref %s", srcRef)
+		}
+		src.copyObjects(r, sha)
+		if r.FetchHead == "" {
+			r.FetchHead = sha
+		}
+		r.updateRef(dstRef, sha)
+		if dstRef == "" && name != "" {
+			if b := strings.TrimPrefix(srcRef, "refs/heads/"); src.Branches[b] != "" {
+				r.RemoteBranches[name+"/"+b] = sha
+			}
+		}
+	}
+	for tagName, t := range src.Tags {
+		// git automatically follows tags pointing at fetched commits
+		if !a.has("--tags", "-t") && r.Commits[t.SHA] == nil {
+			continue
+		}
+		src.copyObjects(r, t.SHA)
+		if r.Tags[tagName] == nil || a.has("-f", "--force") {
+			tag := *t
+			r.Tags[tagName] = &tag
+		}
+	}
+	return "", nil
+}
+
+// updateRef updates the local branch, remote tracking branch or tag reference to the SHA
+func (r *Repository) updateRef(ref, sha string) {
+	switch {
+	case ref == "":
+		return
+	case strings.HasPrefix(ref, "refs/remotes/"):
+		r.RemoteBranches[strings.TrimPrefix(ref, "refs/remotes/")] = sha
+	case strings.HasPrefix(ref, "refs/tags/"):
+		name := strings.TrimPrefix(ref, "refs/tags/")
+		r.Tags[name] = &Tag{Name: name, SHA: sha}
+	default:
+		r.Branches[strings.TrimPrefix(ref, "refs/heads/")] = sha
+	}
+}
+
+func (r *Repository) pull(a *args) (string, error) {
+	remote := ""
+	branch := ""
+	if len(a.positional) > 0 {
+		remote = a.positional[0]
+	}
+	if len(a.positional) > 1 {
+		branch = a.positional[1]
+	}
+	if branch == "" {
+		upstream := r.Upstreams[r.Head]
+		if upstream == "" || (remote != "" && !strings.HasPrefix(upstream, remote+"/")) {
+			return "", fmt.Errorf("There is no tracking information for the current branch.")
+		}
+		remote, branch, _ = strings.Cut(upstream, "/")
+	}
+	_, err := r.fetch(&args{flags: map[string][]string{}, positional: []string{remote, branch}})
+	if err != nil {
+		return "", err
+	}
+	if r.headSHA() == "" {
+		r.setHead(r.FetchHead)
+		tree := r.Commits[r.FetchHead].Tree
+		r.Index = copyFiles(tree)
+		return "", r.updateWorkTree(r.sparseFiles(tree))
+	}
+	mergeArgs := &args{flags: map[string][]string{}, positional: []string{r.FetchHead}}
+	if a.has("--ff-only") {
+		mergeArgs.flags["--ff-only"] = nil
+	}
+	if a.has("--rebase", "-r") {
+		return "", fmt.Errorf("git pull --rebase is not supported by the fake git client")
+	}
+	return r.merge(mergeArgs)
+}
+
+func (r *Repository) push(a *args) (string, error) {
+	remote := ""
+	if len(a.positional) > 0 {
+		remote = a.positional[0]
+	}
+	name, gitURL, dst, err := r.remoteRepository(remote)
+	if err != nil {
+		return "", err
+	}
+	force := a.has("-f", "--force", "--force-with-lease")
+	var refspecs []string
+	if len(a.positional) > 1 {
+		refspecs = a.positional[1:]
+	}
+	if len(refspecs) == 0 && !a.has("--tags") {
+		refspecs = []string{"HEAD"}
+	}
+
+	var rejected []string
+	for _, refspec := range refspecs {
+		refForce := force || strings.HasPrefix(refspec, "+")
+		refspec = strings.TrimPrefix(refspec, "+")
+		srcRef, dstRef, hasDst := strings.Cut(refspec, ":")
+		if a.has("-d", "--delete") {
+			srcRef, dstRef, hasDst = "", srcRef, true
+		}
+		if srcRef == "" {
+			dstName := strings.TrimPrefix(strings.TrimPrefix(dstRef, "refs/heads/"), "refs/tags/")
+			if dst.Branches[dstName] == "" && dst.Tags[dstName] == nil {
+				return "", fmt.Errorf("error: unable to delete '%s': remote ref does not exist", dstRef)
+			}
+			delete(dst.Branches, dstName)
+			delete(dst.Tags, dstName)
+			if name != "" {
+				delete(r.RemoteBranches, name+"/"+dstName)
+			}
+			continue
+		}
+
+		localBranch := ""
+		if srcRef == "HEAD" {
+			if r.Head == "" {
+				return "", fmt.Errorf("fatal: You are not currently on a branch.")
+			}
+			localBranch = r.Head
+			if !hasDst {
+				dstRef = r.Head
+				if upstream := r.Upstreams[r.Head]; name != "" && strings.HasPrefix(upstream, name+"/") {
+					dstRef = strings.TrimPrefix(upstream, name+"/")
+				}
+			}
+		} else if b := strings.TrimPrefix(srcRef, "refs/heads/"); r.Branches[b] != "" {
+			localBranch = b
+		}
+		if !hasDst && dstRef == "" {
+			dstRef = srcRef
+		}
+		sha, err := r.resolve(srcRef)
+		if err != nil {
+			return "", fmt.Errorf("error: src refspec %s does not match any", srcRef)
+		}
+		r.copyObjects(dst, sha)
+
+		isTag := strings.HasPrefix(dstRef, "refs/tags/") || (localBranch == "" && r.Tags[srcRef] != nil)
+		dstName := strings.TrimPrefix(strings.TrimPrefix(dstRef, "refs/heads/"), "refs/tags/")
+		if isTag {
+			if t := dst.Tags[dstName]; t != nil && t.SHA != sha && !refForce {
+				rejected = append(rejected, fmt.Sprintf(" ! [rejected]        %s -> %s (already exists)", srcRef, dstName))
+				continue
+			}
+			tag := Tag{Name: dstName, SHA: sha}
+			if t := r.Tags[srcRef]; t != nil {
+				tag = *t
+				tag.Name = dstName
+			}
+			dst.Tags[dstName] = &tag
+			continue
+		}
+		if old := dst.Branches[dstName]; old != "" && old != sha && !refForce && !dst.isAncestor(old, sha) {
+			rejected = append(rejected, fmt.Sprintf(" ! [rejected]        %s -> %s (non-fast-forward)", srcRef, dstName))
+			continue
+		}
+		dst.Branches[dstName] = sha
+		if dst.Branches[dst.Head] == "" {
+			dst.Head = dstName
+		}
+		if name != "" {
+			r.RemoteBranches[name+"/"+dstName] = sha
+			if localBranch != "" && a.has("-u", "--set-upstream") {
+				r.Upstreams[localBranch] = name + "/" + dstName
+			}
+		}
+	}
+
+	if a.has("--tags") {
+		for tagName, t := range r.Tags {
+			if existing := dst.Tags[tagName]; existing != nil && existing.SHA != t.SHA && !force {
+				rejected = append(rejected, fmt.Sprintf(" ! [rejected]        %s -> %s (already exists)", tagName, tagName))
+				continue
+			}
+			r.copyObjects(dst, t.SHA)
+			tag := *t
+			dst.Tags[tagName] = &tag
+		}
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		return "", fmt.Errorf("To %s\n%s\nerror: failed to push some refs to '%s'", gitURL, strings.Join(rejected, "\n"), gitURL)
+	}
+	return "", nil
+}
+
+func (r *Repository) checkout(dir string, a *args) (string, error) {
+	if len(a.paths) > 0 {
+		paths, err := r.matchingPaths(dir, a.paths, r.Index)
+		if err != nil {
+			return "", err
+		}
+		files := copyFiles(r.Files)
+		for _, p := range paths {
+			files[p] = r.Index[p]
+		}
+		return "", r.updateWorkTree(files)
+	}
+
+	branch := a.value("-b", "-B")
+	startPoint := ""
+	if len(a.positional) > 0 {
+		startPoint = a.positional[0]
+	}
+	if branch == "" && a.has("-t", "--track") {
+		_, branch, _ = strings.Cut(startPoint, "/")
+	}
+	force := a.has("-f", "--force")
+
+	if branch != "" {
+		if r.Branches[branch] != "" && !a.has("-B") {
+			return "", fmt.Errorf("fatal: a branch named '%s' already exists", branch)
+		}
+		if startPoint == "" {
+			startPoint = "HEAD"
+		}
+		sha, err := r.resolve(startPoint)
+		if err != nil {
+			if startPoint == "HEAD" && r.headSHA() == "" {
+				// switching to a new branch of an empty repository
+				r.Head = branch
+				return fmt.Sprintf("Switched to a new branch '%s'", branch), nil
+			}
+			return "", fmt.Errorf("fatal: '%s' is not a commit and a branch '%s' cannot be created from it", startPoint, branch)
+		}
+		err = r.switchTo(sha, force)
+		if err != nil {
+			return "", err
+		}
+		r.Branches[branch] = sha
+		r.Head = branch
+		if r.RemoteBranches[startPoint] != "" {
+			r.Upstreams[branch] = startPoint
+		}
+		return fmt.Sprintf("Switched to a new branch '%s'", branch), nil
+	}
+
+	if startPoint == "" {
+		if r.noCheckout {
+			r.noCheckout = false
+			tree := r.headCommit().Tree
+			r.Index = copyFiles(tree)
+			return "", r.updateWorkTree(r.sparseFiles(tree))
+		}
+		return "", nil
+	}
+	if sha := r.Branches[startPoint]; sha != "" || (startPoint == r.Head && sha == "") {
+		err := r.switchTo(sha, force)
+		if err != nil {
+			return "", err
+		}
+		r.Head = startPoint
+		return fmt.Sprintf("Switched to branch '%s'", startPoint), nil
+	}
+	var remoteBranches []string
+	for rb := range r.RemoteBranches {
+		if _, b, _ := strings.Cut(rb, "/"); b == startPoint {
+			remoteBranches = append(remoteBranches, rb)
+		}
+	}
+	if len(remoteBranches) == 1 {
+		sha := r.RemoteBranches[remoteBranches[0]]
+		err := r.switchTo(sha, force)
+		if err != nil {
+			return "", err
+		}
+		r.Branches[startPoint] = sha
+		r.Head = startPoint
+		r.Upstreams[startPoint] = remoteBranches[0]
+		return fmt.Sprintf("branch '%s' set up to track '%s'.\nSwitched to a new branch '%s'", startPoint, remoteBranches[0], startPoint), nil
+	}
+	sha, err := r.resolve(startPoint)
+	if err != nil {
+		return "", fmt.Errorf("error: pathspec '%s' did not match any file(s) known to git", startPoint)
+	}
+	err = r.switchTo(sha, force)
+	if err != nil {
+		return "", err
+	}
+	r.Head = ""
+	r.DetachedHead = sha
+	return fmt.Sprintf("HEAD is now at %s %s", sha[:7], r.Commits[sha].Subject()), nil
+}
+
+// switchTo updates the index and working tree from the HEAD commit to the commit keeping any local changes
+// to files which are the same in both commits
+func (r *Repository) switchTo(sha string, force bool) error {
+	var oldTree, newTree map[string]string
+	if c := r.headCommit(); c != nil {
+		oldTree = c.Tree
+	}
+	if c := r.Commits[sha]; c != nil {
+		newTree = c.Tree
+	}
+	index := copyFiles(r.Index)
+	files := copyFiles(r.Files)
+	var conflicts []string
+	for _, p := range unionKeys(oldTree, newTree) {
+		oldContent, inOld := oldTree[p]
+		newContent, inNew := newTree[p]
+		if inOld == inNew && oldContent == newContent {
+			continue
+		}
+		if !force && (!sameEntry(r.Index, oldTree, p) || (!sameEntry(r.Files, oldTree, p) && r.sparseIncluded(p))) {
+			conflicts = append(conflicts, p)
+			continue
+		}
+		if inNew {
+			index[p] = newContent
+			if r.sparseIncluded(p) {
+				files[p] = newContent
+			}
+		} else {
+			delete(index, p)
+			delete(files, p)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("error: Your local changes to the following files would be overwritten by checkout:\n\t%s\nPlease commit your changes or stash them before you switch branches.\nAborting",
+			strings.Join(conflicts, "\n\t"))
+	}
+	if force {
+		index = copyFiles(newTree)
+		files = r.sparseFiles(newTree)
+	}
+	r.Index = index
+	r.mergeHead = ""
+	r.conflicts = nil
+	return r.updateWorkTree(files)
+}
+
+// sameEntry returns true if the path has the same content or is missing in both maps
+func sameEntry(a, b map[string]string, p string) bool {
+	av, aok := a[p]
+	bv, bok := b[p]
+	return aok == bok && av == bv
+}
+
+func unionKeys(maps ...map[string]string) []string {
+	all := map[string]string{}
+	for _, m := range maps {
+		for k := range m {
+			all[k] = ""
+		}
+	}
+	return sortedKeys(all)
+}
+
+func (r *Repository) branch(a *args) (string, error) {
+	switch {
+	case a.has("--show-current"):
+		return r.Head, nil
+	case a.has("--set-upstream-to", "-u"):
+		upstream := a.value("--set-upstream-to", "-u")
+		branch := r.Head
+		if len(a.positional) > 0 {
+			branch = a.positional[0]
+		}
+		if r.Branches[branch] == "" {
+			return "", fmt.Errorf("fatal: branch '%s' does not exist", branch)
+		}
+		if r.RemoteBranches[upstream] == "" && r.Branches[upstream] == "" {
+			return "", fmt.Errorf("fatal: the requested upstream branch '%s' does not exist", upstream)
+		}
+		r.Upstreams[branch] = upstream
+		return fmt.Sprintf("branch '%s' set up to track '%s'.", branch, upstream), nil
+	case a.has("-d", "-D", "--delete"):
+		for _, b := range a.positional {
+			sha := r.Branches[b]
+			if sha == "" {
+				return "", fmt.Errorf("error: branch '%s' not found.", b)
+			}
+			if b == r.Head {
+				return "", fmt.Errorf("error: Cannot delete branch '%s' checked out at '%s'", b, r.Dir)
+			}
+			if !a.has("-D") && !r.isAncestor(sha, r.headSHA()) {
+				return "", fmt.Errorf("error: The branch '%s' is not fully merged.", b)
+			}
+			delete(r.Branches, b)
+			delete(r.Upstreams, b)
+		}
+		return "", nil
+	case a.has("-m", "-M"):
+		names := append(a.values("-m"), a.positional...)
+		oldName, newName := r.Head, ""
+		switch len(names) {
+		case 1:
+			newName = names[0]
+		case 2:
+			oldName, newName = names[0], names[1]
+		default:
+			return "", fmt.Errorf("fatal: branch name required")
+		}
+		if r.Branches[newName] != "" && !a.has("-M") {
+			return "", fmt.Errorf("fatal: a branch named '%s' already exists", newName)
+		}
+		r.Branches[newName] = r.Branches[oldName]
+		delete(r.Branches, oldName)
+		if upstream := r.Upstreams[oldName]; upstream != "" {
+			r.Upstreams[newName] = upstream
+			delete(r.Upstreams, oldName)
+		}
+		if r.Head == oldName {
+			r.Head = newName
+		}
+		return "", nil
+	case a.has("-r", "--remotes"):
+		var lines []string
+		for _, rb := range sortedKeys(r.RemoteBranches) {
+			lines = append(lines, "  "+rb)
+		}
+		return strings.Join(lines, "\n"), nil
+	case len(a.positional) > 0:
+		name := a.positional[0]
+		if r.Branches[name] != "" && !a.has("-f", "--force") {
+			return "", fmt.Errorf("fatal: a branch named '%s' already exists", name)
+		}
+		startPoint := "HEAD"
+		if len(a.positional) > 1 {
+			startPoint = a.positional[1]
+		}
+		sha, err := r.resolve(startPoint)
+		if err != nil || sha == "" {
+			return "", fmt.Errorf("fatal: not a valid object name: '%s'", startPoint)
+		}
+		r.Branches[name] = sha
+		if r.RemoteBranches[startPoint] != "" {
+			r.Upstreams[name] = startPoint
+		}
+		return "", nil
+	default:
+		var lines []string
+		if r.Head == "" && r.DetachedHead != "" {
+			lines = append(lines, fmt.Sprintf("* (HEAD detached at %s)", r.DetachedHead[:7]))
+		}
+		for _, b := range sortedKeys(r.Branches) {
+			prefix := "  "
+			if b == r.Head {
+				prefix = "* "
+			}
+			lines = append(lines, prefix+b)
+		}
+		if a.has("-a", "--all") {
+			for _, rb := range sortedKeys(r.RemoteBranches) {
+				lines = append(lines, "  remotes/"+rb)
+			}
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+}
+
+func (r *Repository) reset(a *args) (string, error) {
+	rev := "HEAD"
+	if len(a.positional) > 0 {
+		rev = a.positional[0]
+	}
+	sha, err := r.resolve(rev)
+	if err != nil {
+		return "", err
+	}
+	tree := map[string]string{}
+	if c := r.Commits[sha]; c != nil {
+		tree = c.Tree
+	}
+	if len(a.paths) > 0 {
+		paths, err := r.matchingPaths(r.Dir, a.paths, unionMaps(r.Index, tree))
+		if err != nil {
+			return "", err
+		}
+		for _, p := range paths {
+			if content, ok := tree[p]; ok {
+				r.Index[p] = content
+			} else {
+				delete(r.Index, p)
+			}
+		}
+		return "", nil
+	}
+	r.setHead(sha)
+	r.mergeHead = ""
+	r.conflicts = nil
+	if a.has("--soft") {
+		return "", nil
+	}
+	r.Index = copyFiles(tree)
+	if a.has("--hard") {
+		err = r.updateWorkTree(r.sparseFiles(tree))
+		if err != nil {
+			return "", err
+		}
+		if c := r.Commits[sha]; c != nil {
+			return fmt.Sprintf("HEAD is now at %s %s", sha[:7], c.Subject()), nil
+		}
+	}
+	return "", nil
+}
+
+func unionMaps(maps ...map[string]string) map[string]string {
+	answer := map[string]string{}
+	for _, m := range maps {
+		for k, v := range m {
+			answer[k] = v
+		}
+	}
+	return answer
+}
+
+func (r *Repository) merge(a *args) (string, error) {
+	if a.has("--abort") {
+		if r.mergeHead == "" {
+			return "", fmt.Errorf("fatal: There is no merge to abort (MERGE_HEAD missing).")
+		}
+		return r.reset(&args{flags: map[string][]string{"--hard": nil}})
+	}
+	if len(a.positional) == 0 {
+		return "", fmt.Errorf("fatal: No remote for the current branch.")
+	}
+	rev := a.positional[0]
+	theirs, err := r.resolve(rev)
+	if err != nil {
+		return "", fmt.Errorf("merge: %s - not something we can merge", rev)
+	}
+	ours := r.headSHA()
+	if ours == "" || (r.isAncestor(ours, theirs) && !a.has("--no-ff")) {
+		err = r.switchTo(theirs, false)
+		if err != nil {
+			return "", err
+		}
+		r.setHead(theirs)
+		return fmt.Sprintf("Updating %s..%s\nFast-forward", shortSHA(ours), theirs[:7]), nil
+	}
+	if r.isAncestor(theirs, ours) {
+		return "Already up to date.", nil
+	}
+	if a.has("--ff-only") {
+		return "", fmt.Errorf("fatal: Not possible to fast-forward, aborting.")
+	}
+
+	var baseTree map[string]string
+	if base := r.Commits[r.mergeBase(ours, theirs)]; base != nil {
+		baseTree = base.Tree
+	}
+	ourTree := r.Commits[ours].Tree
+	theirTree := r.Commits[theirs].Tree
+	merged := map[string]string{}
+	var conflicts []string
+	for _, p := range unionKeys(baseTree, ourTree, theirTree) {
+		switch {
+		case sameEntry(ourTree, theirTree, p) || sameEntry(baseTree, theirTree, p):
+			if content, ok := ourTree[p]; ok {
+				merged[p] = content
+			}
+		case sameEntry(baseTree, ourTree, p):
+			if content, ok := theirTree[p]; ok {
+				merged[p] = content
+			}
+		default:
+			conflicts = append(conflicts, p)
+			merged[p] = fmt.Sprintf("<<<<<<< HEAD\n%s=======\n%s>>>>>>> %s\n", ourTree[p], theirTree[p], rev)
+		}
+	}
+
+	// lets make sure we don't overwrite any local changes
+	for _, p := range unionKeys(ourTree, merged) {
+		if sameEntry(ourTree, merged, p) {
+			continue
+		}
+		if !sameEntry(r.Index, ourTree, p) || !sameEntry(r.Files, ourTree, p) {
+			return "", fmt.Errorf("error: Your local changes to the following files would be overwritten by merge:\n\t%s\nPlease commit your changes or stash them before you merge.\nAborting", p)
+		}
+	}
+	files := copyFiles(r.Files)
+	for _, p := range unionKeys(ourTree, merged) {
+		if content, ok := merged[p]; ok {
+			files[p] = content
+		} else {
+			delete(files, p)
+		}
+	}
+	err = r.updateWorkTree(files)
+	if err != nil {
+		return "", err
+	}
+
+	r.Index = copyFiles(merged)
+	if len(conflicts) > 0 {
+		r.mergeHead = theirs
+		r.conflicts = map[string]bool{}
+		var lines []string
+		for _, p := range conflicts {
+			r.conflicts[p] = true
+			r.Index[p] = ourTree[p]
+			lines = append(lines, fmt.Sprintf("CONFLICT (content): Merge conflict in %s", p))
+		}
+		return "", fmt.Errorf("%s\nAutomatic merge failed; fix conflicts and then commit the result.", strings.Join(lines, "\n"))
+	}
+	message := strings.Join(a.values("-m"), "\n\n")
+	if message == "" {
+		message = fmt.Sprintf("Merge branch '%s'", rev)
+	}
+	c := r.createCommit(message, copyFiles(merged), []string{ours, theirs}, r.fake.userName(r), r.fake.userEmail(r))
+	r.setHead(c.SHA)
+	return "Merge made by the 'ort' strategy.", nil
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,825 @@
+package fakegit
+
+import (
+	"crypto/sha1" //nolint:gosec
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+)
+
+const (
+	// DefaultBranch the default initial branch of new repositories
+	DefaultBranch = "master"
+
+	// DefaultUserName the user name used for commits if none is configured
+	DefaultUserName = "fake-git"
+
+	// DefaultUserEmail the user email used for commits if none is configured
+	DefaultUserEmail = "fake-git@example.com"
+)
+
+// startTime the deterministic time of the first commit
+var startTime = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// FakeGit a fake gitclient.Interface which models repositories, branches, tags, commits, remotes and
+// working trees in memory so that code using the gitclient helpers can be tested without a git binary.
+//
+// Repositories are keyed by their directory or git URL. If the directory of a repository exists on the
+// local file system its working tree is mirrored to disk so that code under test can use regular file
+// operations; otherwise the working tree only lives in memory.
+type FakeGit struct {
+	// DefaultBranch the initial branch of new repositories which defaults to master
+	DefaultBranch string
+
+	// Repositories the repositories keyed by their directory or normalized git URL
+	Repositories map[string]*Repository
+
+	// GlobalConfig the global git configuration
+	GlobalConfig map[string][]string
+
+	// Commands the git command lines that have been invoked in order
+	Commands []string
+
+	// Now returns the time used for commits and tags which defaults to a deterministic clock
+	Now func() time.Time
+
+	lock  sync.Mutex
+	ticks int
+}
+
+// Repository an in-memory git repository
+type Repository struct {
+	// Key the directory or normalized git URL of the repository
+	Key string
+	// Dir the directory of the working tree if the repository is not bare
+	Dir string
+	// Bare true if the repository has no working tree such as a remote repository
+	Bare bool
+	// Commits the commits indexed by SHA
+	Commits map[string]*Commit
+	// Branches the SHA of the local branches indexed by name
+	Branches map[string]string
+	// RemoteBranches the SHA of the remote tracking branches indexed by name such as 'origin/master'
+	RemoteBranches map[string]string
+	// Tags the tags indexed by name
+	Tags map[string]*Tag
+	// Head the name of the current branch which is empty if the HEAD is detached
+	Head string
+	// DetachedHead the SHA of the HEAD if it is detached
+	DetachedHead string
+	// Remotes the URLs of the remotes indexed by name
+	Remotes map[string]string
+	// Upstreams the upstream remote tracking branch of local branches such as 'origin/master'
+	Upstreams map[string]string
+	// Config the git configuration of the repository
+	Config map[string][]string
+	// Index the staged file contents indexed by path
+	Index map[string]string
+	// Files the working tree file contents indexed by path
+	Files map[string]string
+	// Sparse true if sparse checkout is enabled
+	Sparse bool
+	// SparseCheckout the sparse checkout patterns
+	SparseCheckout []string
+	// FetchHead the SHA of the last fetched commit
+	FetchHead string
+
+	fake       *FakeGit
+	noCheckout bool
+	mergeHead  string
+	conflicts  map[string]bool
+}
+
+// Commit an in-memory git commit
+type Commit struct {
+	// SHA the SHA of the commit
+	SHA string
+	// Parents the SHAs of the parent commits
+	Parents []string
+	// Tree the file contents of the commit indexed by path
+	Tree map[string]string
+	// AuthorName the author name
+	AuthorName string
+	// AuthorEmail the author email
+	AuthorEmail string
+	// CommitterName the committer name
+	CommitterName string
+	// CommitterEmail the committer email
+	CommitterEmail string
+	// Date the date of the commit
+	Date time.Time
+	// Message the commit message
+	Message string
+}
+
+// Tag an in-memory git tag
+type Tag struct {
+	// Name the name of the tag
+	Name string
+	// SHA the SHA of the tagged commit
+	SHA string
+	// Annotated true if the tag is an annotated tag
+	Annotated bool
+	// Message the message of an annotated tag
+	Message string
+	// Date the creation date of an annotated tag
+	Date time.Time
+}
+
+// NewFakeGit creates a new empty fake git client
+func NewFakeGit() *FakeGit {
+	return &FakeGit{
+		DefaultBranch: DefaultBranch,
+		Repositories:  map[string]*Repository{},
+		GlobalConfig:  map[string][]string{},
+	}
+}
+
+// Command runs the git sub command such as 'commit' or 'clone' in the given directory with the arguments
+func (f *FakeGit) Command(dir string, args ...string) (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.Repositories == nil {
+		f.Repositories = map[string]*Repository{}
+	}
+	if f.GlobalConfig == nil {
+		f.GlobalConfig = map[string][]string{}
+	}
+	f.Commands = append(f.Commands, strings.TrimSpace("git "+strings.Join(args, " ")))
+	if len(args) == 0 {
+		return "", fmt.Errorf("no git sub command specified")
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve directory %s: %w", dir, err)
+	}
+
+	subCommand := args[0]
+	a := parseArgs(args[1:])
+	switch subCommand {
+	case "init":
+		return f.init(dir, a)
+	case "clone":
+		return f.clone(dir, a)
+	case "config":
+		return f.config(dir, a)
+	}
+
+	r := f.findRepository(dir)
+	if r == nil {
+		return "", fmt.Errorf("fatal: not a git repository (or any of the parent directories): %s", dir)
+	}
+	err = r.loadWorkTree()
+	if err != nil {
+		return "", err
+	}
+	switch subCommand {
+	case "remote":
+		return r.remote(a)
+	case "fetch":
+		return r.fetch(a)
+	case "pull":
+		return r.pull(a)
+	case "push":
+		return r.push(a)
+	case "checkout":
+		return r.checkout(dir, a)
+	case "branch":
+		return r.branch(a)
+	case "add":
+		return r.add(dir, a)
+	case "rm":
+		return r.rm(dir, a)
+	case "commit":
+		return r.commit(a)
+	case "status":
+		return r.status(dir, a)
+	case "rev-parse":
+		return r.revParse(a)
+	case "rev-list":
+		return r.revList(a)
+	case "log":
+		return r.log(dir, a)
+	case "tag":
+		return r.tag(a)
+	case "reset":
+		return r.reset(a)
+	case "merge":
+		return r.merge(a)
+	case "for-each-ref":
+		return r.forEachRef(a)
+	case "describe":
+		return r.describe(a)
+	case "sparse-checkout":
+		return r.sparseCheckout(a)
+	case "count-objects":
+		return r.countObjects(a)
+	default:
+		return "", fmt.Errorf("git: '%s' is not supported by the fake git client", subCommand)
+	}
+}
+
+// Repository returns the repository for the given directory or git URL or nil if it does not exist
+func (f *FakeGit) Repository(key string) *Repository {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if r := f.Repositories[normalizeKey("", key)]; r != nil {
+		return r
+	}
+	return f.findRepository(normalizeKey("", key))
+}
+
+// CreateRepository creates a bare repository for the given git URL or directory which can be cloned,
+// fetched from and pushed to. If any files are supplied they are committed on the default branch
+func (f *FakeGit) CreateRepository(key string, files map[string]string) (*Repository, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	r := f.newRepository(normalizeKey("", key), true)
+	if len(files) > 0 {
+		_, err := r.commitFiles("initial commit", files)
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+func (f *FakeGit) newRepository(key string, bare bool) *Repository {
+	branch := f.DefaultBranch
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	r := &Repository{
+		Key:            key,
+		Bare:           bare,
+		Commits:        map[string]*Commit{},
+		Branches:       map[string]string{},
+		RemoteBranches: map[string]string{},
+		Tags:           map[string]*Tag{},
+		Head:           branch,
+		Remotes:        map[string]string{},
+		Upstreams:      map[string]string{},
+		Config:         map[string][]string{},
+		Index:          map[string]string{},
+		Files:          map[string]string{},
+		fake:           f,
+	}
+	if !bare {
+		r.Dir = key
+	}
+	f.Repositories[key] = r
+	return r
+}
+
+// findRepository finds the repository for the directory or any of its parent directories
+func (f *FakeGit) findRepository(dir string) *Repository {
+	for d := dir; ; d = filepath.Dir(d) {
+		if r := f.Repositories[d]; r != nil {
+			return r
+		}
+		if d == filepath.Dir(d) {
+			return nil
+		}
+	}
+}
+
+// now returns the current time which increments by a minute on each call by default
+func (f *FakeGit) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	f.ticks++
+	return startTime.Add(time.Duration(f.ticks) * time.Minute)
+}
+
+// configValue returns the last value of the key in the repository or global configuration
+func (f *FakeGit) configValue(r *Repository, key string) string {
+	if r != nil {
+		if values := r.Config[key]; len(values) > 0 {
+			return values[len(values)-1]
+		}
+	}
+	if values := f.GlobalConfig[key]; len(values) > 0 {
+		return values[len(values)-1]
+	}
+	return ""
+}
+
+// normalizeKey returns the key of a repository from a directory or git URL resolving relative paths against the dir
+func normalizeKey(dir, key string) string {
+	if isURL(key) {
+		return strings.TrimSuffix(strings.TrimSuffix(key, "/"), ".git")
+	}
+	key = strings.TrimPrefix(key, "file://")
+	if !filepath.IsAbs(key) {
+		if dir == "" {
+			abs, err := filepath.Abs(key)
+			if err == nil {
+				return abs
+			}
+		}
+		key = filepath.Join(dir, key)
+	}
+	return filepath.Clean(key)
+}
+
+// isURL returns true if the text is a remote git URL rather than a local path
+func isURL(text string) bool {
+	return (strings.Contains(text, "://") && !strings.HasPrefix(text, "file://")) || strings.HasPrefix(text, "git@")
+}
+
+// CommitFiles commits the given files on the current branch replacing any existing files with the same path
+func (r *Repository) CommitFiles(message string, files map[string]string) (*Commit, error) {
+	r.fake.lock.Lock()
+	defer r.fake.lock.Unlock()
+	return r.commitFiles(message, files)
+}
+
+func (r *Repository) commitFiles(message string, files map[string]string) (*Commit, error) {
+	if !r.Bare {
+		err := r.loadWorkTree()
+		if err != nil {
+			return nil, err
+		}
+	}
+	tree := map[string]string{}
+	if head := r.headCommit(); head != nil {
+		tree = copyFiles(head.Tree)
+	}
+	for k, v := range files {
+		tree[k] = v
+	}
+	c := r.createCommit(message, tree, r.headParents(), r.fake.userName(r), r.fake.userEmail(r))
+	r.setHead(c.SHA)
+	r.Index = copyFiles(tree)
+	if r.Bare {
+		return c, nil
+	}
+	newFiles := copyFiles(r.Files)
+	for k, v := range files {
+		newFiles[k] = v
+	}
+	return c, r.updateWorkTree(newFiles)
+}
+
+// userName returns the configured user name or the default user name
+func (f *FakeGit) userName(r *Repository) string {
+	if name := os.Getenv("GIT_AUTHOR_NAME"); name != "" {
+		return name
+	}
+	if name := f.configValue(r, "user.name"); name != "" {
+		return name
+	}
+	return DefaultUserName
+}
+
+// userEmail returns the configured user email or the default user email
+func (f *FakeGit) userEmail(r *Repository) string {
+	if email := os.Getenv("GIT_AUTHOR_EMAIL"); email != "" {
+		return email
+	}
+	if email := f.configValue(r, "user.email"); email != "" {
+		return email
+	}
+	return DefaultUserEmail
+}
+
+// HeadCommit returns the commit of the HEAD or nil if there are no commits
+func (r *Repository) HeadCommit() *Commit {
+	r.fake.lock.Lock()
+	defer r.fake.lock.Unlock()
+	return r.headCommit()
+}
+
+// BranchCommit returns the commit of the local branch or nil if it does not exist
+func (r *Repository) BranchCommit(branch string) *Commit {
+	r.fake.lock.Lock()
+	defer r.fake.lock.Unlock()
+	return r.Commits[r.Branches[branch]]
+}
+
+// Log returns the commits reachable from the revision in reverse chronological order
+func (r *Repository) Log(rev string) ([]*Commit, error) {
+	r.fake.lock.Lock()
+	defer r.fake.lock.Unlock()
+
+	sha, err := r.resolve(rev)
+	if err != nil {
+		return nil, err
+	}
+	return r.history(sha, nil), nil
+}
+
+// WriteFile writes the file into the working tree of the repository
+func (r *Repository) WriteFile(path, content string) error {
+	r.fake.lock.Lock()
+	defer r.fake.lock.Unlock()
+
+	err := r.loadWorkTree()
+	if err != nil {
+		return err
+	}
+	files := copyFiles(r.Files)
+	files[path] = content
+	return r.updateWorkTree(files)
+}
+
+func (r *Repository) headSHA() string {
+	if r.Head == "" {
+		return r.DetachedHead
+	}
+	return r.Branches[r.Head]
+}
+
+func (r *Repository) headCommit() *Commit {
+	return r.Commits[r.headSHA()]
+}
+
+func (r *Repository) headParents() []string {
+	if sha := r.headSHA(); sha != "" {
+		return []string{sha}
+	}
+	return nil
+}
+
+// setHead moves the current branch or detached HEAD to the SHA
+func (r *Repository) setHead(sha string) {
+	if r.Head == "" {
+		r.DetachedHead = sha
+		return
+	}
+	r.Branches[r.Head] = sha
+}
+
+func (r *Repository) createCommit(message string, tree map[string]string, parents []string, name, email string) *Commit {
+	c := &Commit{
+		Parents:        parents,
+		Tree:           tree,
+		AuthorName:     name,
+		AuthorEmail:    email,
+		CommitterName:  name,
+		CommitterEmail: email,
+		Date:           r.fake.now(),
+		Message:        message,
+	}
+	c.SHA = c.hash()
+	r.Commits[c.SHA] = c
+	return c
+}
+
+// hash returns the SHA of the commit from its contents
+func (c *Commit) hash() string {
+	h := sha1.New() //nolint:gosec
+	paths := sortedKeys(c.Tree)
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s\x00%s\x00", p, c.Tree[p])
+	}
+	fmt.Fprintf(h, "%s\n%s <%s>\n%s <%s>\n%d\n%s", strings.Join(c.Parents, " "), c.AuthorName, c.AuthorEmail,
+		c.CommitterName, c.CommitterEmail, c.Date.Unix(), c.Message)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// blobSHA returns the git blob SHA of the file in the tree or zeros if the tree does not contain the file
+func blobSHA(tree map[string]string, path string) string {
+	content, ok := tree[path]
+	if !ok {
+		return strings.Repeat("0", 40)
+	}
+	h := sha1.New() //nolint:gosec
+	fmt.Fprintf(h, "blob %d\x00%s", len(content), content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Subject returns the first line of the commit message
+func (c *Commit) Subject() string {
+	subject, _, _ := strings.Cut(strings.TrimSpace(c.Message), "\n")
+	return strings.TrimSpace(subject)
+}
+
+// resolve resolves the revision such as a branch, tag, SHA, HEAD~1 or origin/master into a commit SHA
+func (r *Repository) resolve(rev string) (string, error) {
+	base := rev
+	var suffix string
+	if i := strings.IndexAny(rev, "~^"); i > 0 {
+		base = rev[:i]
+		suffix = rev[i:]
+	}
+	sha := r.resolveRef(base)
+	if sha == "" {
+		return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree", rev)
+	}
+	for len(suffix) > 0 {
+		op := suffix[0]
+		suffix = suffix[1:]
+		n := 1
+		digits := 0
+		for digits < len(suffix) && suffix[digits] >= '0' && suffix[digits] <= '9' {
+			digits++
+		}
+		if digits > 0 {
+			n, _ = strconv.Atoi(suffix[:digits])
+			suffix = suffix[digits:]
+		}
+		c := r.Commits[sha]
+		if op == '^' {
+			if n == 0 {
+				continue
+			}
+			if c == nil || len(c.Parents) < n {
+				return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree", rev)
+			}
+			sha = c.Parents[n-1]
+			continue
+		}
+		for i := 0; i < n; i++ {
+			c = r.Commits[sha]
+			if c == nil || len(c.Parents) == 0 {
+				return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree", rev)
+			}
+			sha = c.Parents[0]
+		}
+	}
+	return sha, nil
+}
+
+func (r *Repository) resolveRef(name string) string {
+	switch {
+	case name == "HEAD" || name == "@":
+		return r.headSHA()
+	case name == "FETCH_HEAD":
+		return r.FetchHead
+	case strings.HasPrefix(name, "refs/heads/"):
+		return r.Branches[strings.TrimPrefix(name, "refs/heads/")]
+	case strings.HasPrefix(name, "refs/remotes/"):
+		return r.RemoteBranches[strings.TrimPrefix(name, "refs/remotes/")]
+	case strings.HasPrefix(name, "refs/tags/"):
+		if t := r.Tags[strings.TrimPrefix(name, "refs/tags/")]; t != nil {
+			return t.SHA
+		}
+		return ""
+	}
+	if sha := r.Branches[name]; sha != "" {
+		return sha
+	}
+	if t := r.Tags[name]; t != nil {
+		return t.SHA
+	}
+	if sha := r.RemoteBranches[name]; sha != "" {
+		return sha
+	}
+	if len(name) >= 4 {
+		var found string
+		for sha := range r.Commits {
+			if strings.HasPrefix(sha, name) {
+				if found != "" {
+					return ""
+				}
+				found = sha
+			}
+		}
+		return found
+	}
+	return ""
+}
+
+// history returns the commits reachable from the SHA excluding those reachable from any of the excluded SHAs
+// in reverse chronological order
+func (r *Repository) history(sha string, exclude []string) []*Commit {
+	excluded := map[string]bool{}
+	for _, e := range exclude {
+		for s := range r.ancestors(e) {
+			excluded[s] = true
+		}
+	}
+	var answer []*Commit
+	for s := range r.ancestors(sha) {
+		if !excluded[s] && r.Commits[s] != nil {
+			answer = append(answer, r.Commits[s])
+		}
+	}
+	sort.SliceStable(answer, func(i, j int) bool {
+		if answer[i].Date.Equal(answer[j].Date) {
+			return r.isAncestor(answer[j].SHA, answer[i].SHA)
+		}
+		return answer[i].Date.After(answer[j].Date)
+	})
+	return answer
+}
+
+// ancestors returns the set of SHAs reachable from the SHA including itself
+func (r *Repository) ancestors(sha string) map[string]bool {
+	answer := map[string]bool{}
+	stack := []string{sha}
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if s == "" || answer[s] {
+			continue
+		}
+		answer[s] = true
+		if c := r.Commits[s]; c != nil {
+			stack = append(stack, c.Parents...)
+		}
+	}
+	return answer
+}
+
+// isAncestor returns true if the ancestor SHA is reachable from the SHA
+func (r *Repository) isAncestor(ancestor, sha string) bool {
+	return ancestor != "" && r.ancestors(sha)[ancestor]
+}
+
+// mergeBase returns the best common ancestor of the two SHAs
+func (r *Repository) mergeBase(a, b string) string {
+	bAncestors := r.ancestors(b)
+	for _, c := range r.history(a, nil) {
+		if bAncestors[c.SHA] {
+			return c.SHA
+		}
+	}
+	return ""
+}
+
+// copyObjects copies the commits and tags reachable from the SHA into the other repository
+func (r *Repository) copyObjects(to *Repository, sha string) {
+	for s := range r.ancestors(sha) {
+		if c := r.Commits[s]; c != nil {
+			to.Commits[s] = c
+		}
+	}
+}
+
+// loadWorkTree loads the working tree from disk if the directory of the repository exists
+func (r *Repository) loadWorkTree() error {
+	if !r.mirrored() {
+		return nil
+	}
+	answer := map[string]string{}
+	err := filepath.WalkDir(r.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(r.Dir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		answer[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load the working tree from %s: %w", r.Dir, err)
+	}
+	r.Files = answer
+	return nil
+}
+
+// updateWorkTree replaces the working tree writing any changes to disk if the directory of the repository exists
+func (r *Repository) updateWorkTree(newFiles map[string]string) error {
+	if r.mirrored() {
+		for p := range r.Files {
+			if _, ok := newFiles[p]; !ok {
+				err := os.Remove(filepath.Join(r.Dir, filepath.FromSlash(p)))
+				if err != nil && !os.IsNotExist(err) {
+					return fmt.Errorf("failed to remove %s: %w", p, err)
+				}
+			}
+		}
+		for p, content := range newFiles {
+			if old, ok := r.Files[p]; ok && old == content {
+				continue
+			}
+			path := filepath.Join(r.Dir, filepath.FromSlash(p))
+			err := os.MkdirAll(filepath.Dir(path), files.DefaultDirWritePermissions)
+			if err != nil {
+				return fmt.Errorf("failed to create the directory for %s: %w", p, err)
+			}
+			err = os.WriteFile(path, []byte(content), files.DefaultFileWritePermissions)
+			if err != nil {
+				return fmt.Errorf("failed to write %s: %w", p, err)
+			}
+		}
+	}
+	r.Files = newFiles
+	return nil
+}
+
+// mirrored returns true if the working tree is mirrored to a directory on disk
+func (r *Repository) mirrored() bool {
+	if r.Bare || r.Dir == "" {
+		return false
+	}
+	info, err := os.Stat(r.Dir)
+	return err == nil && info.IsDir()
+}
+
+func copyFiles(m map[string]string) map[string]string {
+	answer := make(map[string]string, len(m))
+	for k, v := range m {
+		answer[k] = v
+	}
+	return answer
+}
+
+func sortedKeys(m map[string]string) []string {
+	answer := make([]string, 0, len(m))
+	for k := range m {
+		answer = append(answer, k)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+// args the parsed flags and positional arguments of a git sub command
+type args struct {
+	flags      map[string][]string
+	positional []string
+	paths      []string
+}
+
+// valueFlags the flags of git sub commands which take a separate value argument
+var valueFlags = map[string]bool{
+	"-b": true, "-B": true, "--branch": true, "--depth": true, "-m": true, "--message": true, "-n": true,
+	"--max-count": true, "-o": true, "--origin": true, "--set-upstream-to": true,
+	"--author": true, "--pretty": true, "--format": true, "--initial-branch": true,
+}
+
+// messageFlags the flags whose separate value argument may start with '-'
+var messageFlags = map[string]bool{"-m": true, "--message": true}
+
+// parseArgs parses the arguments of a git sub command treating unknown flags as boolean flags
+func parseArgs(arguments []string) *args {
+	a := &args{flags: map[string][]string{}}
+	for i := 0; i < len(arguments); i++ {
+		arg := arguments[i]
+		switch {
+		case arg == "--":
+			a.paths = append(a.paths, arguments[i+1:]...)
+			return a
+		case len(arg) > 1 && arg[0] == '-' && arg[1] >= '0' && arg[1] <= '9':
+			a.flags["-n"] = append(a.flags["-n"], arg[1:])
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			name, value, hasValue := strings.Cut(arg, "=")
+			// flags such as 'status --branch' are boolean so only consume a following flag as a message
+			if !hasValue && valueFlags[name] && i+1 < len(arguments) && (!strings.HasPrefix(arguments[i+1], "-") || messageFlags[name]) {
+				i++
+				value = arguments[i]
+			}
+			a.flags[name] = append(a.flags[name], value)
+		default:
+			a.positional = append(a.positional, arg)
+		}
+	}
+	return a
+}
+
+// has returns true if any of the flag names are present
+func (a *args) has(names ...string) bool {
+	for _, n := range names {
+		if _, ok := a.flags[n]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the last value of any of the flag names
+func (a *args) value(names ...string) string {
+	answer := ""
+	for _, n := range names {
+		if values := a.flags[n]; len(values) > 0 {
+			answer = values[len(values)-1]
+		}
+	}
+	return answer
+}
+
+// values returns all of the values of the flag names
+func (a *args) values(names ...string) []string {
+	var answer []string
+	for _, n := range names {
+		answer = append(answer, a.flags[n]...)
+	}
+	return answer
+}
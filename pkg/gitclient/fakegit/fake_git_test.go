@@ -0,0 +1,225 @@
+//go:build unit
+// +build unit
+
+package fakegit_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const gitURL = "https://github.com/myorg/myrepo.git"
+
+func TestFakeGitCloneCommitAndPush(t *testing.T) {
+	g := fakegit.NewFakeGit()
+	remote, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create remote repository")
+
+	dir, err := gitclient.CloneToDir(g, gitURL, filepath.Join(t.TempDir(), "myrepo"))
+	require.NoError(t, err, "failed to clone")
+
+	data, err := os.ReadFile(filepath.Join(dir, "README.md"))
+	require.NoError(t, err, "failed to read cloned file")
+	assert.Equal(t, "hello\n", string(data), "cloned README.md")
+
+	branch, err := gitclient.Branch(g, dir)
+	require.NoError(t, err, "failed to get branch")
+	assert.Equal(t, fakegit.DefaultBranch, branch, "branch")
+
+	changes, err := gitclient.HasChanges(g, dir)
+	require.NoError(t, err, "failed to check changes")
+	assert.False(t, changes, "should not have changes after clone")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello world\n"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "charts"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "charts", "values.yaml"), []byte("replicas: 1\n"), 0o600))
+
+	out, err := g.Command(dir, "status", "-s")
+	require.NoError(t, err, "failed to get status")
+	assert.Equal(t, " M README.md\n?? charts/values.yaml", out, "status")
+
+	changed, err := gitclient.AddAndCommitFiles(g, dir, "chore: add values")
+	require.NoError(t, err, "failed to commit")
+	assert.True(t, changed, "should have committed changes")
+
+	err = gitclient.Push(g, dir, "origin", false, "HEAD")
+	require.NoError(t, err, "failed to push")
+
+	pushed := remote.BranchCommit(fakegit.DefaultBranch)
+	require.NotNil(t, pushed, "should have pushed the branch")
+	assert.Equal(t, "chore: add values", pushed.Message, "pushed commit message")
+	assert.Equal(t, map[string]string{
+		"README.md":          "hello world\n",
+		"charts/values.yaml": "replicas: 1\n",
+	}, pushed.Tree, "pushed files")
+
+	sha, err := gitclient.GetLatestCommitSha(g, dir)
+	require.NoError(t, err, "failed to get latest sha")
+	assert.Equal(t, pushed.SHA, sha, "latest sha")
+
+	out, err = g.Command(dir, "log")
+	require.NoError(t, err, "failed to get log")
+	commits := gitlog.ParseGitLog(out)
+	require.Len(t, commits, 2, "commits")
+	assert.Equal(t, "chore: add values\n", commits[0].Comment, "latest comment")
+	assert.Equal(t, "fake-git <fake-git@example.com>", commits[0].Author, "author")
+
+	logCommits, err := gitlog.Log(g, dir, &gitlog.LogOptions{MaxCount: 1})
+	require.NoError(t, err, "failed to get structured log")
+	require.Len(t, logCommits, 1, "structured log commits")
+	assert.Equal(t, "chore: add values", logCommits[0].Subject, "structured log subject")
+	assert.Equal(t, []gitlog.ChangedFile{
+		{Status: gitlog.FileModified, Path: "README.md"},
+		{Status: gitlog.FileAdded, Path: "charts/values.yaml"},
+	}, logCommits[0].Files, "structured log files")
+
+	err = gitclient.Remove(g, dir, "charts")
+	require.NoError(t, err, "failed to remove charts")
+	assert.NoFileExists(t, filepath.Join(dir, "charts", "values.yaml"), "removed file")
+}
+
+func TestFakeGitInMemory(t *testing.T) {
+	g := fakegit.NewFakeGit()
+	g.DefaultBranch = "main"
+	dir := "/fake/workspace"
+
+	err := gitclient.Init(g, dir)
+	require.NoError(t, err, "failed to init")
+
+	r := g.Repository(dir)
+	require.NotNil(t, r, "should have created the repository")
+	require.NoError(t, r.WriteFile("README.md", "hello\n"))
+
+	_, err = gitclient.AddAndCommitFiles(g, dir, "initial commit")
+	require.NoError(t, err, "failed to commit")
+
+	_, err = g.Command(dir, "tag", "v1.0.0")
+	require.NoError(t, err, "failed to tag")
+	firstSHA, err := gitclient.GetLatestCommitSha(g, dir)
+	require.NoError(t, err, "failed to get latest sha")
+
+	require.NoError(t, r.WriteFile("README.md", "hello world\n"))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "fix: update readme")
+	require.NoError(t, err, "failed to commit")
+
+	_, err = g.Command(dir, "tag", "-a", "v1.1.0", "-m", "release 1.1.0")
+	require.NoError(t, err, "failed to tag")
+
+	tags, err := gitclient.FilterTags(g, dir, "v1.*")
+	require.NoError(t, err, "failed to filter tags")
+	assert.Equal(t, []string{"v1.0.0", "v1.1.0"}, tags, "tags")
+
+	_, tagName, err := gitclient.NthTag(g, dir, 2)
+	require.NoError(t, err, "failed to get tag")
+	assert.Equal(t, "v1.0.0", tagName, "second latest tag")
+
+	commitSHA, tagName, err := gitclient.GetCommitPointedToByLatestTag(g, dir)
+	require.NoError(t, err, "failed to get latest tag")
+	assert.Equal(t, "v1.1.0", tagName, "latest tag")
+	assert.Equal(t, r.HeadCommit().SHA, commitSHA, "latest tag commit")
+
+	out, _, err := gitclient.Describe(g, dir, false, firstSHA, "", true)
+	require.NoError(t, err, "failed to describe")
+	assert.Equal(t, firstSHA, out, "should fall back to the commit as v1.0.0 is a lightweight tag")
+
+	out, err = g.Command(dir, "log", "--pretty=format:%s", "v1.0.0..HEAD")
+	require.NoError(t, err, "failed to get log")
+	assert.Equal(t, "fix: update readme", out, "log range")
+
+	branch, err := gitclient.CreateBranch(g, dir)
+	require.NoError(t, err, "failed to create branch")
+	current, err := gitclient.Branch(g, dir)
+	require.NoError(t, err, "failed to get branch")
+	assert.Equal(t, branch, current, "current branch")
+
+	require.NoError(t, r.WriteFile("README.md", "changed on branch\n"))
+	err = gitclient.CommitIfChanges(g, dir, "should not commit unstaged changes")
+	require.Error(t, err, "should fail to commit without staging")
+
+	_, err = g.Command(dir, "commit", "-a", "-m", "feat: branch change")
+	require.NoError(t, err, "failed to commit")
+
+	err = gitclient.Checkout(g, dir, "main")
+	require.NoError(t, err, "failed to checkout main")
+	assert.Equal(t, "hello world\n", r.Files["README.md"], "README.md on main")
+
+	err = gitclient.Merge(g, dir, branch)
+	require.NoError(t, err, "failed to merge")
+	assert.Equal(t, "changed on branch\n", r.Files["README.md"], "README.md after fast forward merge")
+}
+
+func TestFakeGitShallowCloneAndRejectedPush(t *testing.T) {
+	g := fakegit.NewFakeGit()
+	remote, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create remote repository")
+
+	dir1 := "/fake/first"
+	err = gitclient.ShallowCloneBranch(g, gitURL, fakegit.DefaultBranch, dir1)
+	require.NoError(t, err, "failed to shallow clone")
+
+	dir2 := "/fake/second"
+	_, err = g.Command("/fake", "clone", gitURL, dir2)
+	require.NoError(t, err, "failed to clone")
+
+	require.NoError(t, g.Repository(dir1).WriteFile("first.txt", "first\n"))
+	_, err = gitclient.AddAndCommitFiles(g, dir1, "first change")
+	require.NoError(t, err, "failed to commit")
+	err = gitclient.Push(g, dir1, "origin", false)
+	require.NoError(t, err, "failed to push")
+
+	require.NoError(t, g.Repository(dir2).WriteFile("second.txt", "second\n"))
+	_, err = gitclient.AddAndCommitFiles(g, dir2, "second change")
+	require.NoError(t, err, "failed to commit")
+	err = gitclient.Push(g, dir2, "origin", false)
+	require.Error(t, err, "should reject non fast forward push")
+	assert.Contains(t, err.Error(), "non-fast-forward", "rejected push error")
+
+	err = gitclient.Pull(g, dir2)
+	require.NoError(t, err, "failed to pull")
+	err = gitclient.Push(g, dir2, "origin", false)
+	require.NoError(t, err, "failed to push after pull")
+
+	head := remote.BranchCommit(fakegit.DefaultBranch)
+	require.NotNil(t, head, "remote branch")
+	assert.Len(t, head.Parents, 2, "should have pushed a merge commit")
+	assert.Equal(t, map[string]string{
+		"README.md":  "hello\n",
+		"first.txt":  "first\n",
+		"second.txt": "second\n",
+	}, head.Tree, "remote files")
+
+	err = gitclient.ForcePushBranch(g, dir1, "HEAD", "feature")
+	require.NoError(t, err, "failed to force push")
+	assert.NotNil(t, remote.BranchCommit("feature"), "should have pushed the feature branch")
+
+	_, err = g.Command(dir1, "rebase", "origin/master")
+	require.Error(t, err, "rebase is not supported")
+}
+
+func TestFakeGitSparseClone(t *testing.T) {
+	g := fakegit.NewFakeGit()
+	_, err := g.CreateRepository(gitURL, map[string]string{
+		"README.md":             "hello\n",
+		"charts/app/Chart.yaml": "name: app\n",
+		"docs/index.md":         "docs\n",
+	})
+	require.NoError(t, err, "failed to create remote repository")
+
+	dir, err := gitclient.SparseCloneToDir(g, gitURL, filepath.Join(t.TempDir(), "sparse"), true, "charts/")
+	require.NoError(t, err, "failed to sparse clone")
+
+	assert.FileExists(t, filepath.Join(dir, "charts", "app", "Chart.yaml"), "included file")
+	assert.NoFileExists(t, filepath.Join(dir, "README.md"), "excluded file")
+	assert.NoFileExists(t, filepath.Join(dir, "docs", "index.md"), "excluded file")
+
+	changes, err := gitclient.HasChanges(g, dir)
+	require.NoError(t, err, "failed to check changes")
+	assert.False(t, changes, "excluded files should not be reported as deleted")
+}
@@ -0,0 +1,595 @@
+package fakegit
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+)
+
+// revisionRange resolves the revisions and ranges such as 'a..b' or '^a' into the SHA to walk and the SHAs to exclude
+func (r *Repository) revisionRange(revisions []string) (string, []string, error) {
+	include := ""
+	var exclude []string
+	for _, rev := range revisions {
+		if from, to, ok := strings.Cut(rev, ".."); ok {
+			if from == "" {
+				from = "HEAD"
+			}
+			if to == "" {
+				to = "HEAD"
+			}
+			fromSHA, err := r.resolve(from)
+			if err != nil {
+				return "", nil, err
+			}
+			exclude = append(exclude, fromSHA)
+			rev = to
+		} else if strings.HasPrefix(rev, "^") {
+			sha, err := r.resolve(strings.TrimPrefix(rev, "^"))
+			if err != nil {
+				return "", nil, err
+			}
+			exclude = append(exclude, sha)
+			continue
+		}
+		sha, err := r.resolve(rev)
+		if err != nil {
+			return "", nil, err
+		}
+		include = sha
+	}
+	if include == "" {
+		include = r.headSHA()
+		if include == "" {
+			return "", nil, fmt.Errorf("fatal: your current branch '%s' does not have any commits yet", r.Head)
+		}
+	}
+	return include, exclude, nil
+}
+
+// commits returns the commits for the log or rev-list arguments
+func (r *Repository) commits(dir string, a *args) ([]*Commit, error) {
+	sha, exclude, err := r.revisionRange(a.positional)
+	if err != nil {
+		return nil, err
+	}
+	limit := -1
+	if v := a.value("-n", "--max-count"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("fatal: '%s': not an integer", v)
+		}
+	}
+	var answer []*Commit
+	for _, c := range r.history(sha, exclude) {
+		if limit >= 0 && len(answer) >= limit {
+			break
+		}
+		if a.has("--no-merges") && len(c.Parents) > 1 {
+			continue
+		}
+		if a.has("--merges") && len(c.Parents) < 2 {
+			continue
+		}
+		if len(a.paths) > 0 && !r.changesPaths(dir, c, a.paths) {
+			continue
+		}
+		answer = append(answer, c)
+	}
+	if a.has("--reverse") {
+		for i, j := 0, len(answer)-1; i < j; i, j = i+1, j-1 {
+			answer[i], answer[j] = answer[j], answer[i]
+		}
+	}
+	return answer, nil
+}
+
+// changesPaths returns true if the commit changes any of the paths compared to its first parent
+func (r *Repository) changesPaths(dir string, c *Commit, specs []string) bool {
+	var parentTree map[string]string
+	if len(c.Parents) > 0 && r.Commits[c.Parents[0]] != nil {
+		parentTree = r.Commits[c.Parents[0]].Tree
+	}
+	for _, p := range unionKeys(parentTree, c.Tree) {
+		if !sameEntry(parentTree, c.Tree, p) && matchesAnyPathSpec(dir, r.Dir, specs, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Repository) log(dir string, a *args) (string, error) {
+	commits, err := r.commits(dir, a)
+	if err != nil {
+		return "", err
+	}
+	format := a.value("--pretty", "--format")
+	if a.has("--oneline") {
+		format = "tformat:%h %s"
+	}
+	terminator := "\n"
+	if a.has("-z") {
+		terminator = "\x00"
+	}
+	buf := strings.Builder{}
+	for i, c := range commits {
+		text, terminated, err := gitlog.FormatCommit(c.prettyCommit(), format)
+		if err != nil {
+			return "", fmt.Errorf("fatal: %w", err)
+		}
+		if i > 0 && (!terminated || a.has("--name-status")) {
+			buf.WriteString(terminator)
+		}
+		buf.WriteString(text)
+		if terminated {
+			buf.WriteString(terminator)
+		}
+		if a.has("--name-status") {
+			buf.WriteString(r.nameStatus(c, a.has("-z"), a.has("-M", "--find-renames")))
+		}
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// nameStatus returns the files changed by the commit compared to its first parent in the --name-status format
+func (r *Repository) nameStatus(c *Commit, nulTerminated, findRenames bool) string {
+	if len(c.Parents) > 1 {
+		return ""
+	}
+	var parentTree map[string]string
+	if len(c.Parents) > 0 && r.Commits[c.Parents[0]] != nil {
+		parentTree = r.Commits[c.Parents[0]].Tree
+	}
+	var added, deleted, lines [][]string
+	for _, p := range unionKeys(parentTree, c.Tree) {
+		_, inParent := parentTree[p]
+		_, inCommit := c.Tree[p]
+		switch {
+		case !inParent:
+			added = append(added, []string{"A", p})
+		case !inCommit:
+			deleted = append(deleted, []string{"D", p})
+		case parentTree[p] != c.Tree[p]:
+			lines = append(lines, []string{"M", p})
+		}
+	}
+	for _, d := range deleted {
+		renamed := false
+		for i, a := range added {
+			if findRenames && a[0] == "A" && parentTree[d[1]] == c.Tree[a[1]] {
+				added[i] = []string{"R100", d[1], a[1]}
+				renamed = true
+				break
+			}
+		}
+		if !renamed {
+			lines = append(lines, d)
+		}
+	}
+	lines = append(lines, added...)
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i][len(lines[i])-1] < lines[j][len(lines[j])-1]
+	})
+	if len(lines) == 0 {
+		return ""
+	}
+	buf := strings.Builder{}
+	if nulTerminated {
+		buf.WriteString("\n")
+		for _, line := range lines {
+			buf.WriteString(strings.Join(line, "\x00") + "\x00")
+		}
+		return buf.String()
+	}
+	for _, line := range lines {
+		buf.WriteString("\n" + strings.Join(line, "\t"))
+	}
+	return buf.String() + "\n"
+}
+
+// prettyCommit returns the commit to format with the git log pretty formats
+func (c *Commit) prettyCommit() *gitlog.PrettyCommit {
+	return &gitlog.PrettyCommit{
+		SHA:       c.SHA,
+		Parents:   c.Parents,
+		Author:    gitlog.Signature{Name: c.AuthorName, Email: c.AuthorEmail, Date: c.Date},
+		Committer: gitlog.Signature{Name: c.CommitterName, Email: c.CommitterEmail, Date: c.Date},
+		Message:   c.Message,
+	}
+}
+
+func (r *Repository) revList(a *args) (string, error) {
+	commits, err := r.commits(r.Dir, a)
+	if err != nil {
+		return "", err
+	}
+	if a.has("--count") {
+		return strconv.Itoa(len(commits)), nil
+	}
+	var lines []string
+	for _, c := range commits {
+		lines = append(lines, c.SHA)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func (r *Repository) revParse(a *args) (string, error) {
+	var lines []string
+	if a.has("--show-toplevel") {
+		if r.Bare {
+			return "", fmt.Errorf("fatal: this operation must be run in a work tree")
+		}
+		lines = append(lines, r.Dir)
+	}
+	if a.has("--git-dir") {
+		lines = append(lines, r.Key+"/.git")
+	}
+	if a.has("--is-inside-work-tree") {
+		lines = append(lines, strconv.FormatBool(!r.Bare))
+	}
+	if a.has("--is-bare-repository") {
+		lines = append(lines, strconv.FormatBool(r.Bare))
+	}
+	for _, rev := range a.positional {
+		if a.has("--abbrev-ref") {
+			name, err := r.abbrevRef(rev)
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, name)
+			continue
+		}
+		if a.has("--symbolic-full-name") {
+			if rev == "HEAD" && r.Head != "" {
+				lines = append(lines, "refs/heads/"+r.Head)
+				continue
+			}
+		}
+		sha, err := r.resolve(rev)
+		if err != nil || sha == "" {
+			if a.has("-q", "--quiet") {
+				return "", fmt.Errorf("exit status 1")
+			}
+			return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision or path not in the working tree", rev)
+		}
+		if a.has("--short") {
+			n := 7
+			if v := a.value("--short"); v != "" {
+				n, _ = strconv.Atoi(v)
+			}
+			if n > 0 && n < len(sha) {
+				sha = sha[:n]
+			}
+		}
+		lines = append(lines, sha)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// abbrevRef returns the short name of the revision such as the current branch for HEAD or the upstream for @{u}
+func (r *Repository) abbrevRef(rev string) (string, error) {
+	switch {
+	case rev == "HEAD":
+		if r.Head == "" {
+			return "HEAD", nil
+		}
+		if r.headSHA() == "" {
+			return "", fmt.Errorf("fatal: ambiguous argument 'HEAD': unknown revision or path not in the working tree")
+		}
+		return r.Head, nil
+	case strings.HasSuffix(rev, "@{u}") || strings.HasSuffix(rev, "@{upstream}"):
+		branch := strings.TrimSuffix(strings.TrimSuffix(rev, "@{u}"), "@{upstream}")
+		if branch == "" {
+			branch = r.Head
+		}
+		upstream := r.Upstreams[branch]
+		if upstream == "" {
+			return "", fmt.Errorf("fatal: no upstream configured for branch '%s'", branch)
+		}
+		return upstream, nil
+	case r.Branches[strings.TrimPrefix(rev, "refs/heads/")] != "":
+		return strings.TrimPrefix(rev, "refs/heads/"), nil
+	case r.Tags[strings.TrimPrefix(rev, "refs/tags/")] != nil:
+		return strings.TrimPrefix(rev, "refs/tags/"), nil
+	case r.RemoteBranches[strings.TrimPrefix(rev, "refs/remotes/")] != "":
+		return strings.TrimPrefix(rev, "refs/remotes/"), nil
+	}
+	sha, err := r.resolve(rev)
+	if err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+func (r *Repository) tag(a *args) (string, error) {
+	if a.has("-d", "--delete") {
+		var lines []string
+		for _, name := range a.positional {
+			t := r.Tags[name]
+			if t == nil {
+				return "", fmt.Errorf("error: tag '%s' not found.", name)
+			}
+			delete(r.Tags, name)
+			lines = append(lines, fmt.Sprintf("Deleted tag '%s' (was %s)", name, shortSHA(t.SHA)))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+	if a.has("-l", "--list") || len(a.positional) == 0 {
+		var names []string
+		for name := range r.Tags {
+			if len(a.positional) == 0 {
+				names = append(names, name)
+				continue
+			}
+			for _, pattern := range a.positional {
+				if matched, _ := path.Match(pattern, name); matched {
+					names = append(names, name)
+					break
+				}
+			}
+		}
+		sort.Strings(names)
+		return strings.Join(names, "\n"), nil
+	}
+
+	name := a.positional[0]
+	rev := "HEAD"
+	if len(a.positional) > 1 {
+		rev = a.positional[1]
+	}
+	if r.Tags[name] != nil && !a.has("-f", "--force") {
+		return "", fmt.Errorf("fatal: tag '%s' already exists", name)
+	}
+	sha, err := r.resolve(rev)
+	if err != nil || sha == "" {
+		return "", fmt.Errorf("fatal: Failed to resolve '%s' as a valid ref.", rev)
+	}
+	t := &Tag{Name: name, SHA: sha}
+	message := strings.Join(a.values("-m", "--message"), "\n\n")
+	if a.has("-a", "--annotate") || message != "" {
+		if message == "" {
+			return "", fmt.Errorf("fatal: no tag message specified for annotated tag %s", name)
+		}
+		t.Annotated = true
+		t.Message = message
+		t.Date = r.fake.now()
+	}
+	r.Tags[name] = t
+	return "", nil
+}
+
+// ref a reference used by for-each-ref
+type ref struct {
+	name string
+	sha  string
+	date time.Time
+	tag  *Tag
+}
+
+func (r *Repository) refs() []ref {
+	var answer []ref
+	for name, sha := range r.Branches {
+		answer = append(answer, ref{name: "refs/heads/" + name, sha: sha})
+	}
+	for name, sha := range r.RemoteBranches {
+		answer = append(answer, ref{name: "refs/remotes/" + name, sha: sha})
+	}
+	for name, t := range r.Tags {
+		answer = append(answer, ref{name: "refs/tags/" + name, sha: t.SHA, tag: t})
+	}
+	for i := range answer {
+		if answer[i].tag != nil && answer[i].tag.Annotated {
+			answer[i].date = answer[i].tag.Date
+		} else if c := r.Commits[answer[i].sha]; c != nil {
+			answer[i].date = c.Date
+		}
+	}
+	return answer
+}
+
+func (r *Repository) forEachRef(a *args) (string, error) {
+	var refs []ref
+	for _, rf := range r.refs() {
+		if len(a.positional) == 0 {
+			refs = append(refs, rf)
+			continue
+		}
+		for _, pattern := range a.positional {
+			if matchesPathSpec(pattern, rf.name) {
+				refs = append(refs, rf)
+				break
+			}
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].name < refs[j].name
+	})
+	sortKeys := a.values("--sort")
+	for i := len(sortKeys) - 1; i >= 0; i-- {
+		key := sortKeys[i]
+		descending := strings.HasPrefix(key, "-")
+		key = strings.TrimPrefix(key, "-")
+		sort.SliceStable(refs, func(i, j int) bool {
+			var less, greater bool
+			switch key {
+			case "creatordate", "committerdate", "authordate", "taggerdate":
+				less, greater = refs[i].date.Before(refs[j].date), refs[i].date.After(refs[j].date)
+			default:
+				less, greater = refs[i].name < refs[j].name, refs[i].name > refs[j].name
+			}
+			if descending {
+				return greater
+			}
+			return less
+		})
+	}
+	if v := a.value("--count"); v != "" {
+		count, err := strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("fatal: invalid --count argument: '%s'", v)
+		}
+		if count < len(refs) {
+			refs = refs[:count]
+		}
+	}
+
+	format := a.value("--format")
+	if format == "" {
+		format = "%(objectname) %(objecttype)\t%(refname)"
+	}
+	var lines []string
+	for _, rf := range refs {
+		lines = append(lines, r.formatRef(rf, format))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// formatRef expands the for-each-ref format atoms such as %(refname:short) for the reference
+func (r *Repository) formatRef(rf ref, format string) string {
+	short := strings.TrimPrefix(strings.TrimPrefix(strings.TrimPrefix(rf.name, "refs/heads/"), "refs/tags/"), "refs/remotes/")
+	subject := ""
+	if c := r.Commits[rf.sha]; c != nil {
+		subject = c.Subject()
+	}
+	objectType := "commit"
+	if rf.tag != nil && rf.tag.Annotated {
+		objectType = "tag"
+		subject, _, _ = strings.Cut(rf.tag.Message, "\n")
+	}
+	atoms := map[string]string{
+		"objectname":       rf.sha,
+		"objectname:short": shortSHA(rf.sha),
+		"objecttype":       objectType,
+		"refname":          rf.name,
+		"refname:short":    short,
+		"creatordate":      rf.date.Format(gitlog.MediumDateFormat),
+		"creatordate:iso":  rf.date.Format("2006-01-02 15:04:05 -0700"),
+		"creatordate:unix": strconv.FormatInt(rf.date.Unix(), 10),
+		"subject":          subject,
+	}
+	buf := strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 >= len(format) {
+			buf.WriteByte(format[i])
+			continue
+		}
+		rest := format[i+1:]
+		switch {
+		case rest[0] == '(':
+			end := strings.Index(rest, ")")
+			if end > 0 {
+				buf.WriteString(atoms[rest[1:end]])
+				i += end + 1
+				continue
+			}
+		case rest[0] == '%':
+			buf.WriteByte('%')
+			i++
+			continue
+		case len(rest) >= 2:
+			b, err := strconv.ParseUint(rest[:2], 16, 8)
+			if err == nil {
+				buf.WriteByte(byte(b))
+				i += 2
+				continue
+			}
+		}
+		buf.WriteByte('%')
+	}
+	return buf.String()
+}
+
+func (r *Repository) describe(a *args) (string, error) {
+	rev := "HEAD"
+	if len(a.positional) > 0 {
+		rev = a.positional[0]
+	}
+	sha, err := r.resolve(rev)
+	if err != nil || sha == "" {
+		return "", fmt.Errorf("fatal: Not a valid object name %s", rev)
+	}
+	abbrev := 7
+	if v := a.value("--abbrev"); v != "" {
+		abbrev, err = strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("fatal: invalid --abbrev argument: '%s'", v)
+		}
+	}
+	contains := a.has("--contains")
+	allTags := a.has("--tags") || contains
+
+	best := ""
+	bestDistance := -1
+	for _, name := range sortedTagNames(r.Tags) {
+		t := r.Tags[name]
+		if !allTags && !t.Annotated {
+			continue
+		}
+		var distance int
+		if contains {
+			if !r.isAncestor(sha, t.SHA) {
+				continue
+			}
+			distance = len(r.history(t.SHA, []string{sha}))
+		} else {
+			if !r.isAncestor(t.SHA, sha) {
+				continue
+			}
+			distance = len(r.history(sha, []string{t.SHA}))
+		}
+		if bestDistance < 0 || distance < bestDistance {
+			best = name
+			bestDistance = distance
+		}
+	}
+	switch {
+	case best == "" && a.has("--always"):
+		return shortSHA(sha), nil
+	case best == "":
+		return "", fmt.Errorf("fatal: cannot describe '%s'", sha)
+	case bestDistance == 0:
+		return best, nil
+	case contains:
+		return fmt.Sprintf("%s~%d", best, bestDistance), nil
+	case abbrev == 0:
+		return best, nil
+	default:
+		if abbrev > len(sha) {
+			abbrev = len(sha)
+		}
+		return fmt.Sprintf("%s-%d-g%s", best, bestDistance, sha[:abbrev]), nil
+	}
+}
+
+func sortedTagNames(tags map[string]*Tag) []string {
+	answer := make([]string, 0, len(tags))
+	for name := range tags {
+		answer = append(answer, name)
+	}
+	sort.Strings(answer)
+	return answer
+}
+
+func (r *Repository) countObjects(a *args) (string, error) {
+	blobs := map[string]bool{}
+	size := 0
+	for _, c := range r.Commits {
+		for _, content := range c.Tree {
+			if !blobs[content] {
+				blobs[content] = true
+				size += len(content)
+			}
+		}
+	}
+	count := len(r.Commits) + len(blobs)
+	sizePack := (size + 1023) / 1024
+	if !a.has("-v", "--verbose") {
+		return "0 objects, 0 kilobytes", nil
+	}
+	return fmt.Sprintf("count: 0\nsize: 0\nin-pack: %d\npacks: 1\nsize-pack: %d\nprune-packable: 0\ngarbage: 0\nsize-garbage: 0", count, sizePack), nil
+}
@@ -0,0 +1,518 @@
+package fakegit
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+func (r *Repository) add(dir string, a *args) (string, error) {
+	if r.Bare {
+		return "", fmt.Errorf("fatal: this operation must be run in a work tree")
+	}
+	specs := append(a.positional, a.paths...)
+	if len(specs) == 0 {
+		if !a.has("-A", "--all", "-u", "--update") {
+			return "", fmt.Errorf("Nothing specified, nothing added.")
+		}
+		specs = []string{r.Dir}
+		dir = r.Dir
+	}
+	paths, err := r.matchingPaths(dir, specs, unionMaps(r.Files, r.Index))
+	if err != nil {
+		return "", err
+	}
+	for _, p := range paths {
+		if a.has("-u", "--update") {
+			if _, tracked := r.Index[p]; !tracked {
+				continue
+			}
+		}
+		if content, ok := r.Files[p]; ok {
+			r.Index[p] = content
+		} else if r.sparseIncluded(p) {
+			delete(r.Index, p)
+		}
+		delete(r.conflicts, p)
+	}
+	return "", nil
+}
+
+func (r *Repository) rm(dir string, a *args) (string, error) {
+	specs := append(a.positional, a.paths...)
+	if len(specs) == 0 {
+		return "", fmt.Errorf("usage: git rm [<options>] [--] <file>...")
+	}
+	paths, err := r.matchingPaths(dir, specs, r.Index)
+	if err != nil {
+		return "", err
+	}
+	files := copyFiles(r.Files)
+	var lines []string
+	for _, p := range paths {
+		if strings.Contains(p, "/") && !a.has("-r") && !containsPath(specs, dir, r.Dir, p) {
+			return "", fmt.Errorf("fatal: not removing '%s' recursively without -r", specs[0])
+		}
+		delete(r.Index, p)
+		if !a.has("--cached") {
+			delete(files, p)
+		}
+		lines = append(lines, fmt.Sprintf("rm '%s'", p))
+	}
+	if a.has("-q", "--quiet") {
+		lines = nil
+	}
+	return strings.Join(lines, "\n"), r.updateWorkTree(files)
+}
+
+// containsPath returns true if the path is explicitly listed in the path specs
+func containsPath(specs []string, dir, root, p string) bool {
+	for _, s := range specs {
+		if relativePath(dir, root, s) == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Repository) commit(a *args) (string, error) {
+	if r.Bare {
+		return "", fmt.Errorf("fatal: this operation must be run in a work tree")
+	}
+	if len(r.conflicts) > 0 {
+		return "", fmt.Errorf("error: Committing is not possible because you have unmerged files.")
+	}
+	if a.has("-a", "--all") {
+		for p := range r.Index {
+			if content, ok := r.Files[p]; ok {
+				r.Index[p] = content
+			} else if r.sparseIncluded(p) {
+				delete(r.Index, p)
+			}
+		}
+	}
+	head := r.headCommit()
+	amend := a.has("--amend")
+	if amend && head == nil {
+		return "", fmt.Errorf("fatal: You have nothing to amend.")
+	}
+	message := strings.Join(a.values("-m", "--message"), "\n\n")
+	if message == "" {
+		if !amend {
+			return "", fmt.Errorf("Aborting commit due to empty commit message.")
+		}
+		message = head.Message
+	}
+
+	parents := r.headParents()
+	if amend {
+		parents = head.Parents
+	}
+	if r.mergeHead != "" {
+		parents = append(parents, r.mergeHead)
+	}
+	var headTree map[string]string
+	if head != nil {
+		headTree = head.Tree
+	}
+	tree := copyFiles(r.Index)
+	if !amend && r.mergeHead == "" && !a.has("--allow-empty") && len(unchangedPaths(headTree, tree)) == len(unionKeys(headTree, tree)) {
+		return "", fmt.Errorf("On branch %s\nnothing to commit, working tree clean", r.Head)
+	}
+
+	name := r.fake.userName(r)
+	email := r.fake.userEmail(r)
+	if author := a.value("--author"); author != "" {
+		authorName, authorEmail, ok := strings.Cut(author, "<")
+		if !ok {
+			return "", fmt.Errorf("fatal: --author '%s' is not 'Name <email>'", author)
+		}
+		name = strings.TrimSpace(authorName)
+		email = strings.TrimSuffix(strings.TrimSpace(authorEmail), ">")
+	}
+	c := r.createCommit(message, tree, parents, name, email)
+	if committer := os.Getenv("GIT_COMMITTER_NAME"); committer != "" {
+		c.CommitterName = committer
+	}
+	if committer := os.Getenv("GIT_COMMITTER_EMAIL"); committer != "" {
+		c.CommitterEmail = committer
+	}
+	r.setHead(c.SHA)
+	r.mergeHead = ""
+
+	branch := r.Head
+	if branch == "" {
+		branch = "detached HEAD"
+	}
+	if len(c.Parents) == 0 {
+		branch += " (root-commit)"
+	}
+	return fmt.Sprintf("[%s %s] %s", branch, c.SHA[:7], c.Subject()), nil
+}
+
+// unchangedPaths returns the paths with the same content in both maps
+func unchangedPaths(a, b map[string]string) []string {
+	var answer []string
+	for _, p := range unionKeys(a, b) {
+		if sameEntry(a, b, p) {
+			answer = append(answer, p)
+		}
+	}
+	return answer
+}
+
+// statusEntry the status of a path in the short format
+type statusEntry struct {
+	staged   byte
+	unstaged byte
+	path     string
+}
+
+func (r *Repository) statusEntries(dir string, specs []string) []statusEntry {
+	var headTree map[string]string
+	if head := r.headCommit(); head != nil {
+		headTree = head.Tree
+	}
+	var answer []statusEntry
+	for _, p := range unionKeys(headTree, r.Index, r.Files) {
+		if len(specs) > 0 && !matchesAnyPathSpec(dir, r.Dir, specs, p) {
+			continue
+		}
+		if r.conflicts[p] {
+			answer = append(answer, statusEntry{staged: 'U', unstaged: 'U', path: p})
+			continue
+		}
+		headContent, inHead := headTree[p]
+		indexContent, inIndex := r.Index[p]
+		fileContent, inFiles := r.Files[p]
+		if !inFiles && !r.sparseIncluded(p) {
+			// files excluded by the sparse checkout are not deleted
+			inFiles = inIndex
+			fileContent = indexContent
+		}
+		e := statusEntry{staged: ' ', unstaged: ' ', path: p}
+		switch {
+		case inIndex && !inHead:
+			e.staged = 'A'
+		case !inIndex && inHead:
+			e.staged = 'D'
+		case inIndex && indexContent != headContent:
+			e.staged = 'M'
+		}
+		switch {
+		case inIndex && !inFiles:
+			e.unstaged = 'D'
+		case inIndex && fileContent != indexContent:
+			e.unstaged = 'M'
+		}
+		if e.staged != ' ' || e.unstaged != ' ' {
+			answer = append(answer, e)
+		}
+		if !inIndex && inFiles {
+			answer = append(answer, statusEntry{staged: '?', unstaged: '?', path: p})
+		}
+	}
+	return answer
+}
+
+func (r *Repository) status(dir string, a *args) (string, error) {
+	if r.Bare {
+		return "", fmt.Errorf("fatal: this operation must be run in a work tree")
+	}
+	entries := r.statusEntries(dir, append(a.positional, a.paths...))
+	if a.value("--porcelain") == "v2" {
+		return r.statusPorcelainV2(entries, a), nil
+	}
+	if a.has("-s", "--short", "--porcelain") {
+		var lines []string
+		for _, e := range entries {
+			lines = append(lines, fmt.Sprintf("%c%c %s", e.staged, e.unstaged, e.path))
+		}
+		return strings.Join(lines, "\n"), nil
+	}
+
+	var lines []string
+	if r.Head != "" {
+		lines = append(lines, "On branch "+r.Head)
+	} else {
+		lines = append(lines, "HEAD detached at "+shortSHA(r.DetachedHead))
+	}
+	var staged, unstaged, untracked, unmerged []string
+	for _, e := range entries {
+		switch {
+		case e.staged == '?':
+			untracked = append(untracked, "\t"+e.path)
+			continue
+		case e.staged == 'U':
+			unmerged = append(unmerged, "\tboth modified:   "+e.path)
+			continue
+		}
+		if e.staged != ' ' {
+			staged = append(staged, fmt.Sprintf("\t%-12s%s", statusDescription(e.staged)+":", e.path))
+		}
+		if e.unstaged != ' ' {
+			unstaged = append(unstaged, fmt.Sprintf("\t%-12s%s", statusDescription(e.unstaged)+":", e.path))
+		}
+	}
+	if len(entries) == 0 {
+		lines = append(lines, "nothing to commit, working tree clean")
+		return strings.Join(lines, "\n"), nil
+	}
+	for _, section := range []struct {
+		title string
+		lines []string
+	}{
+		{"Changes to be committed:", staged},
+		{"Unmerged paths:", unmerged},
+		{"Changes not staged for commit:", unstaged},
+		{"Untracked files:", untracked},
+	} {
+		if len(section.lines) > 0 {
+			lines = append(lines, "", section.title)
+			lines = append(lines, section.lines...)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// statusPorcelainV2 returns the status in the 'git status --porcelain=v2' format
+func (r *Repository) statusPorcelainV2(entries []statusEntry, a *args) string {
+	var lines []string
+	if a.has("--branch", "-b") {
+		oid := r.headSHA()
+		if oid == "" {
+			oid = "(initial)"
+		}
+		lines = append(lines, "# branch.oid "+oid)
+		if r.Head != "" {
+			lines = append(lines, "# branch.head "+r.Head)
+		} else {
+			lines = append(lines, "# branch.head (detached)")
+		}
+		if upstream := r.Upstreams[r.Head]; r.Head != "" && upstream != "" {
+			lines = append(lines, "# branch.upstream "+upstream)
+			if upstreamSHA := r.RemoteBranches[upstream]; upstreamSHA != "" {
+				ahead := len(r.history(oid, []string{upstreamSHA}))
+				behind := len(r.history(upstreamSHA, []string{oid}))
+				lines = append(lines, fmt.Sprintf("# branch.ab +%d -%d", ahead, behind))
+			}
+		}
+	}
+	var headTree map[string]string
+	if head := r.headCommit(); head != nil {
+		headTree = head.Tree
+	}
+	zeroSHA := strings.Repeat("0", 40)
+	for _, e := range entries {
+		switch e.staged {
+		case '?':
+			if a.value("--untracked-files", "-u") != "no" {
+				lines = append(lines, "? "+e.path)
+			}
+		case 'U':
+			lines = append(lines, fmt.Sprintf("u UU N... 100644 100644 100644 100644 %s %s %s %s", zeroSHA, zeroSHA, zeroSHA, e.path))
+		default:
+			modeHead, modeIndex, modeWorkTree := "100644", "100644", "100644"
+			if _, ok := headTree[e.path]; !ok {
+				modeHead = "000000"
+			}
+			if _, ok := r.Index[e.path]; !ok {
+				modeIndex = "000000"
+			}
+			if e.unstaged == 'D' || (e.staged == 'D' && e.unstaged == ' ') {
+				modeWorkTree = "000000"
+			}
+			lines = append(lines, fmt.Sprintf("1 %c%c N... %s %s %s %s %s %s", porcelainV2Code(e.staged), porcelainV2Code(e.unstaged),
+				modeHead, modeIndex, modeWorkTree, blobSHA(headTree, e.path), blobSHA(r.Index, e.path), e.path))
+		}
+	}
+	if a.has("-z") {
+		if len(lines) == 0 {
+			return ""
+		}
+		return strings.Join(lines, "\x00") + "\x00"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func porcelainV2Code(code byte) byte {
+	if code == ' ' {
+		return '.'
+	}
+	return code
+}
+
+func statusDescription(code byte) string {
+	switch code {
+	case 'A':
+		return "new file"
+	case 'D':
+		return "deleted"
+	default:
+		return "modified"
+	}
+}
+
+func (r *Repository) sparseCheckout(a *args) (string, error) {
+	if len(a.positional) == 0 {
+		return "", fmt.Errorf("usage: git sparse-checkout (init | list | set | add | reapply | disable)")
+	}
+	action := a.positional[0]
+	patterns := a.positional[1:]
+	switch action {
+	case "init":
+		r.Sparse = true
+		if r.SparseCheckout == nil {
+			r.SparseCheckout = []string{}
+		}
+	case "set":
+		r.Sparse = true
+		r.SparseCheckout = append([]string{}, patterns...)
+	case "add":
+		r.Sparse = true
+		r.SparseCheckout = append(r.SparseCheckout, patterns...)
+	case "disable":
+		r.Sparse = false
+		r.SparseCheckout = nil
+	case "list":
+		return strings.Join(r.SparseCheckout, "\n"), nil
+	case "reapply":
+	default:
+		return "", fmt.Errorf("error: unknown subcommand: %s", action)
+	}
+	if r.noCheckout {
+		return "", nil
+	}
+	files := r.sparseFiles(r.Index)
+	for p, content := range r.Files {
+		if _, tracked := r.Index[p]; !tracked {
+			files[p] = content
+		}
+	}
+	return "", r.updateWorkTree(files)
+}
+
+// sparseFiles returns the files of the tree included by the sparse checkout patterns
+func (r *Repository) sparseFiles(tree map[string]string) map[string]string {
+	answer := map[string]string{}
+	for p, content := range tree {
+		if r.sparseIncluded(p) {
+			answer[p] = content
+		}
+	}
+	return answer
+}
+
+// sparseIncluded returns true if the path is included in the working tree by the sparse checkout patterns
+// which are interpreted as in .gitignore. With no patterns only the files in the root directory are included
+func (r *Repository) sparseIncluded(p string) bool {
+	if !r.Sparse {
+		return true
+	}
+	if len(r.SparseCheckout) == 0 {
+		return !strings.Contains(p, "/")
+	}
+	included := false
+	for _, pattern := range r.SparseCheckout {
+		negate := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+		if matchesIgnorePattern(pattern, p) {
+			included = !negate
+		}
+	}
+	return included
+}
+
+// matchesIgnorePattern returns true if the .gitignore style pattern matches the path or any of its parent directories
+func matchesIgnorePattern(pattern, p string) bool {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+	parts := strings.Split(p, "/")
+	for i := 1; i <= len(parts); i++ {
+		if dirOnly && i == len(parts) {
+			break
+		}
+		candidate := strings.Join(parts[:i], "/")
+		if !anchored {
+			candidate = parts[i-1]
+		}
+		if matched, _ := path.Match(pattern, candidate); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// matchingPaths returns the sorted repository relative paths of the candidates matching any of the path specs
+// which are relative to the directory
+func (r *Repository) matchingPaths(dir string, specs []string, candidates map[string]string) ([]string, error) {
+	var answer []string
+	for _, spec := range specs {
+		rel := relativePath(dir, r.Dir, spec)
+		found := rel == "."
+		for p := range candidates {
+			if matchesPathSpec(rel, p) {
+				answer = append(answer, p)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("fatal: pathspec '%s' did not match any files", spec)
+		}
+	}
+	sort.Strings(answer)
+	var unique []string
+	for i, p := range answer {
+		if i == 0 || answer[i-1] != p {
+			unique = append(unique, p)
+		}
+	}
+	return unique, nil
+}
+
+func matchesAnyPathSpec(dir, root string, specs []string, p string) bool {
+	for _, spec := range specs {
+		if matchesPathSpec(relativePath(dir, root, spec), p) {
+			return true
+		}
+	}
+	return false
+}
+
+// relativePath converts the path spec relative to the directory into a path relative to the repository root
+func relativePath(dir, root, spec string) string {
+	if root == "" {
+		return filepath.ToSlash(spec)
+	}
+	abs := spec
+	if !filepath.IsAbs(spec) {
+		abs = filepath.Join(dir, spec)
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil {
+		return filepath.ToSlash(spec)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// matchesPathSpec returns true if the path is matched by the git path spec where wildcards can match '/'
+func matchesPathSpec(spec, p string) bool {
+	if spec == "." || spec == "" || spec == p || strings.HasPrefix(p, strings.TrimSuffix(spec, "/")+"/") {
+		return true
+	}
+	if !strings.ContainsAny(spec, "*?[") {
+		return false
+	}
+	expr := "^" + strings.NewReplacer(`\*`, ".*", `\?`, ".", `\[`, "[", `\]`, "]").Replace(regexp.QuoteMeta(spec)) + "$"
+	re, err := regexp.Compile(expr)
+	return err == nil && re.MatchString(p)
+}
@@ -0,0 +1,322 @@
+package gitcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/homedir"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// EnvEnabled the environment variable which enables the default mirror cache when set to 'true'
+	EnvEnabled = "JX_GIT_CACHE"
+
+	// EnvDir the environment variable used to override the default mirror cache directory
+	EnvDir = "JX_GIT_CACHE_DIR"
+
+	// DefaultLockTimeout the default time to wait for another process to finish refreshing a mirror
+	DefaultLockTimeout = 5 * time.Minute
+
+	// mirrorSuffix the suffix of the mirror directories
+	mirrorSuffix = ".git"
+
+	// lockSuffix the suffix of the lock files of the mirror directories
+	lockSuffix = ".lock"
+
+	// lastUsedFile the file inside a mirror whose modification time records when the mirror was last used
+	lastUsedFile = "jx-last-used"
+)
+
+var (
+	schemeRegex   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+	scpLikeRegex  = regexp.MustCompile(`^(?:[^@/]+@)?([^:/]+):(.*)$`)
+	unsafeKeyChar = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// Cache a local cache of bare mirror repositories which are used as references to speed up repeated clones
+type Cache struct {
+	// Dir the directory containing the mirrors
+	Dir string
+	// RefreshInterval mirrors fetched more recently than this are not fetched again. Zero means always fetch
+	RefreshInterval time.Duration
+	// MaxAge mirrors not used for longer than this are removed by Evict. Zero means no age limit
+	MaxAge time.Duration
+	// MaxSize the maximum total size in bytes of the mirrors before the least recently used are removed by Evict.
+	// Zero means no size limit
+	MaxSize int64
+	// LockTimeout the time to wait for another process to finish updating a mirror which defaults to DefaultLockTimeout
+	LockTimeout time.Duration
+	// LocalClone clones from the mirror itself rather than from the git URL using the mirror as a reference
+	// so that no network access is needed after the mirror is refreshed
+	LocalClone bool
+	// Now returns the current time which can be faked for testing
+	Now func() time.Time
+}
+
+// Register registers the cache with gitclient so that gitclient.CloneToDir and gitclient.PartialCloneToDir use it
+// when it is enabled via the $JX_GIT_CACHE environment variable. Binaries which want cached clones call this on startup
+func Register() {
+	gitclient.RegisterCloneCache(func() (gitclient.CloneCache, error) {
+		c, err := FromEnvironment()
+		if err != nil || c == nil {
+			return nil, err
+		}
+		return c, nil
+	})
+}
+
+// NewCache creates a cache in the given directory or the default directory if it is empty
+func NewCache(dir string) (*Cache, error) {
+	if dir == "" {
+		var err error
+		dir, err = DefaultDir()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := os.MkdirAll(dir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create git cache dir %s: %w", dir, err)
+	}
+	return &Cache{Dir: dir}, nil
+}
+
+// DefaultDir returns the default directory of the mirror cache
+func DefaultDir() (string, error) {
+	if dir := os.Getenv(EnvDir); dir != "" {
+		return dir, nil
+	}
+	cacheDir, err := homedir.CacheDir(os.Getenv("JX3_HOME"), ".jx3")
+	if err != nil {
+		return "", fmt.Errorf("failed to find the cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "git-mirrors"), nil
+}
+
+// FromEnvironment returns the default cache if it is enabled via the $JX_GIT_CACHE environment variable or nil
+func FromEnvironment() (*Cache, error) {
+	if os.Getenv(EnvEnabled) != "true" {
+		return nil, nil
+	}
+	return NewCache("")
+}
+
+// NormalizeURL returns the git URL without the scheme, user, port or .git suffix so that the https and ssh
+// URLs of a repository map to the same value such as 'github.com/myorg/myrepo'
+func NormalizeURL(gitURL string) string {
+	text := strings.TrimSpace(gitURL)
+	switch {
+	case schemeRegex.MatchString(text):
+		u, err := url.Parse(text)
+		if err == nil {
+			text = u.Hostname() + "/" + strings.TrimPrefix(u.Path, "/")
+			if u.Host == "" {
+				text = u.Path
+			}
+		}
+	case !filepath.IsAbs(text) && scpLikeRegex.MatchString(text):
+		groups := scpLikeRegex.FindStringSubmatch(text)
+		text = groups[1] + "/" + strings.TrimPrefix(groups[2], "/")
+	}
+	text = strings.TrimSuffix(strings.TrimSuffix(text, "/"), mirrorSuffix)
+	if host, path, ok := strings.Cut(text, "/"); ok && !strings.HasPrefix(text, "/") {
+		text = strings.ToLower(host) + "/" + path
+	}
+	return text
+}
+
+// MirrorDir returns the directory of the mirror of the git URL
+func (c *Cache) MirrorDir(gitURL string) string {
+	key := NormalizeURL(gitURL)
+	hash := sha256.Sum256([]byte(key))
+	name := strings.Trim(unsafeKeyChar.ReplaceAllString(key, "_"), "_")
+	return filepath.Join(c.Dir, name+"-"+hex.EncodeToString(hash[:])[:8]+mirrorSuffix)
+}
+
+// Mirror creates or refreshes the mirror of the git URL returning its directory. Concurrent refreshes of the same
+// mirror from different processes are serialised using a lock file
+func (c *Cache) Mirror(g gitclient.Interface, gitURL string) (string, error) {
+	dir, release, err := c.mirror(g, gitURL, false)
+	if err != nil {
+		return "", err
+	}
+	release()
+	return dir, nil
+}
+
+// Reference creates or refreshes the mirror of the git URL returning its directory and a function to release it.
+// The mirror is not evicted until the function is called so it can be safely used as a clone reference
+func (c *Cache) Reference(g gitclient.Interface, gitURL string) (string, func(), error) {
+	return c.mirror(g, gitURL, true)
+}
+
+// mirror creates or refreshes the mirror of the git URL while holding its lock returning its directory and the
+// function to release the shared lock if shared is true
+func (c *Cache) mirror(g gitclient.Interface, gitURL string, shared bool) (string, func(), error) {
+	dir := c.MirrorDir(gitURL)
+	unlock, err := c.lock(dir)
+	if err != nil {
+		return "", nil, err
+	}
+	defer unlock()
+
+	exists, err := files.DirExists(dir)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to check if mirror %s exists: %w", dir, err)
+	}
+	if !exists {
+		err = c.createMirror(g, gitURL, dir)
+		if err != nil {
+			return "", nil, err
+		}
+	} else if c.needsRefresh(dir) {
+		log.Logger().Debugf("refreshing git mirror %s", termcolor.ColorInfo(dir))
+		_, err = g.Command(dir, "fetch", "--prune", "--force", gitURL, "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to refresh mirror %s of %s: %w", dir, NormalizeURL(gitURL), err)
+		}
+		err = c.touch(filepath.Join(dir, "FETCH_HEAD"))
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	err = c.touch(filepath.Join(dir, lastUsedFile))
+	if err != nil {
+		return "", nil, err
+	}
+	release := func() {}
+	if shared {
+		release, err = c.sharedLock(dir)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	return dir, release, nil
+}
+
+// createMirror creates the mirror in a temporary directory and renames it so a partial mirror is never used
+func (c *Cache) createMirror(g gitclient.Interface, gitURL, dir string) error {
+	log.Logger().Debugf("creating git mirror of %s in %s", termcolor.ColorInfo(NormalizeURL(gitURL)), termcolor.ColorInfo(dir))
+	tmpDir, err := os.MkdirTemp(c.Dir, filepath.Base(dir)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary mirror dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir) //nolint:errcheck
+
+	_, err = g.Command(c.Dir, "clone", "--mirror", gitURL, tmpDir)
+	if err != nil {
+		return fmt.Errorf("failed to mirror %s: %w", NormalizeURL(gitURL), err)
+	}
+	// avoid storing any credentials from the URL in the mirror
+	_, err = g.Command(tmpDir, "remote", "set-url", "origin", removeUserInfo(gitURL))
+	if err != nil {
+		return fmt.Errorf("failed to set the remote URL of mirror %s: %w", dir, err)
+	}
+	err = c.touch(filepath.Join(tmpDir, "FETCH_HEAD"))
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tmpDir, dir)
+	if err != nil {
+		return fmt.Errorf("failed to rename mirror %s to %s: %w", tmpDir, dir, err)
+	}
+	return nil
+}
+
+// CloneToDir clones the git URL to the given directory or a temporary one if it is empty using the mirror of
+// the repository to avoid downloading objects which are already cached. If the mirror cannot be updated a normal
+// clone is performed
+func (c *Cache) CloneToDir(g gitclient.Interface, gitURL, dir string) (string, error) {
+	var err error
+	if dir == "" {
+		dir, err = os.MkdirTemp("", "jx-git-")
+		if err != nil {
+			return "", fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+	} else {
+		err = os.MkdirAll(dir, files.DefaultDirWritePermissions)
+		if err != nil {
+			return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	parentDir := filepath.Dir(dir)
+	mirrorDir, release, err := c.Reference(g, gitURL)
+	switch {
+	case err != nil:
+		log.Logger().Warnf("failed to use the git mirror cache so cloning %s without it: %s", NormalizeURL(gitURL), err.Error())
+		_, err = g.Command(parentDir, "clone", gitURL, dir)
+	case c.LocalClone:
+		// the shared lock stops the mirror being evicted until the clone has completed
+		defer release()
+		_, err = g.Command(parentDir, "clone", mirrorDir, dir)
+		if err == nil {
+			_, err = g.Command(dir, "remote", "set-url", "origin", gitURL)
+		}
+	default:
+		defer release()
+		_, err = g.Command(parentDir, "clone", "--reference", mirrorDir, "--dissociate", gitURL, dir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to clone repository %s to directory: %s: %w", NormalizeURL(gitURL), dir, err)
+	}
+	return dir, nil
+}
+
+// needsRefresh returns true if the mirror was fetched longer ago than the refresh interval
+func (c *Cache) needsRefresh(dir string) bool {
+	if c.RefreshInterval <= 0 {
+		return true
+	}
+	info, err := os.Stat(filepath.Join(dir, "FETCH_HEAD"))
+	if err != nil {
+		return true
+	}
+	return c.now().Sub(info.ModTime()) >= c.RefreshInterval
+}
+
+// touch creates the file if it does not exist and sets its modification time to now
+func (c *Cache) touch(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, files.DefaultFileWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close file %s: %w", path, err)
+	}
+	now := c.now()
+	err = os.Chtimes(path, now, now)
+	if err != nil {
+		return fmt.Errorf("failed to update the time of file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Cache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// removeUserInfo removes any user and password from the git URL
+func removeUserInfo(gitURL string) string {
+	u, err := url.Parse(gitURL)
+	if err != nil || u.User == nil || u.Scheme == "ssh" {
+		return gitURL
+	}
+	u.User = nil
+	return u.String()
+}
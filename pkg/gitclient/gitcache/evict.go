@@ -0,0 +1,128 @@
+package gitcache
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+// MirrorInfo the details of a mirror in the cache
+type MirrorInfo struct {
+	// Dir the directory of the mirror
+	Dir string
+	// LastUsed when the mirror was last used
+	LastUsed time.Time
+	// Size the size of the mirror in bytes
+	Size int64
+}
+
+// Mirrors returns the mirrors in the cache ordered by most recently used first
+func (c *Cache) Mirrors() ([]MirrorInfo, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read git cache dir %s: %w", c.Dir, err)
+	}
+	var answer []MirrorInfo
+	for _, e := range entries {
+		if !e.IsDir() || !strings.HasSuffix(e.Name(), mirrorSuffix) {
+			continue
+		}
+		dir := filepath.Join(c.Dir, e.Name())
+		info, err := os.Stat(filepath.Join(dir, lastUsedFile))
+		if err != nil {
+			info, err = os.Stat(dir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat mirror %s: %w", dir, err)
+			}
+		}
+		size, err := dirSize(dir)
+		if err != nil {
+			return nil, err
+		}
+		answer = append(answer, MirrorInfo{Dir: dir, LastUsed: info.ModTime(), Size: size})
+	}
+	sort.Slice(answer, func(i, j int) bool {
+		return answer[i].LastUsed.After(answer[j].LastUsed)
+	})
+	return answer, nil
+}
+
+// Evict removes the mirrors which have not been used within the maximum age and then the least recently used
+// mirrors until the total size is within the maximum size. Mirrors which are locked or being used as a clone
+// reference are skipped.
+// The directories of the removed mirrors are returned
+func (c *Cache) Evict() ([]string, error) {
+	mirrors, err := c.Mirrors()
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, m := range mirrors {
+		total += m.Size
+	}
+
+	var answer []string
+	now := c.now()
+	// iterate from the least recently used
+	for i := len(mirrors) - 1; i >= 0; i-- {
+		m := mirrors[i]
+		tooOld := c.MaxAge > 0 && now.Sub(m.LastUsed) > c.MaxAge
+		tooBig := c.MaxSize > 0 && total > c.MaxSize
+		if !tooOld && !tooBig {
+			continue
+		}
+		unlock, ok := c.tryLock(m.Dir)
+		if !ok {
+			log.Logger().Debugf("not evicting git mirror %s as it is locked", m.Dir)
+			continue
+		}
+		if c.hasSharedLocks(m.Dir) {
+			unlock()
+			log.Logger().Debugf("not evicting git mirror %s as it is being used", m.Dir)
+			continue
+		}
+		err = os.RemoveAll(m.Dir)
+		if err == nil {
+			err = os.RemoveAll(m.Dir + readersSuffix)
+		}
+		unlock()
+		if err != nil {
+			return answer, fmt.Errorf("failed to remove git mirror %s: %w", m.Dir, err)
+		}
+		total -= m.Size
+		answer = append(answer, m.Dir)
+	}
+	return answer, nil
+}
+
+// dirSize returns the total size of the files in the directory
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to calculate the size of %s: %w", dir, err)
+	}
+	return size, nil
+}
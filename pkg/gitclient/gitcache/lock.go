@@ -0,0 +1,163 @@
+package gitcache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+)
+
+// lockPollInterval how often to check if a lock has been released
+var lockPollInterval = 100 * time.Millisecond
+
+// readersSuffix the suffix of the directory containing the shared lock files of the clones using a mirror
+const readersSuffix = ".readers"
+
+// lock acquires the exclusive lock file of the mirror directory returning the function to release it. The
+// modification time of the lock file is refreshed while it is held so that a lock which has not been refreshed
+// for longer than the lock timeout is assumed to be left over from a process which died and is removed
+func (c *Cache) lock(dir string) (func(), error) {
+	path := dir + lockSuffix
+	timeout := c.lockTimeout()
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, files.DefaultFileWritePermissions)
+		if err == nil {
+			_, err = f.WriteString(strconv.Itoa(os.Getpid()))
+			closeErr := f.Close()
+			if err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path) //nolint:errcheck
+				return nil, fmt.Errorf("failed to write lock file %s: %w", path, err)
+			}
+			stop := c.heartbeat(path)
+			return func() {
+				stop()
+				os.Remove(path) //nolint:errcheck
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file %s: %w", path, err)
+		}
+
+		if c.isStale(path) {
+			// the process holding the lock has most likely died
+			os.Remove(path) //nolint:errcheck
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for lock file %s", timeout.String(), path)
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// tryLock acquires the exclusive lock file of the mirror directory without waiting returning false if it is
+// already locked
+func (c *Cache) tryLock(dir string) (func(), bool) {
+	path := dir + lockSuffix
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, files.DefaultFileWritePermissions)
+	if err != nil {
+		if !errors.Is(err, os.ErrExist) || !c.isStale(path) {
+			return nil, false
+		}
+		os.Remove(path) //nolint:errcheck
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, files.DefaultFileWritePermissions)
+		if err != nil {
+			return nil, false
+		}
+	}
+	f.Close() //nolint:errcheck
+	stop := c.heartbeat(path)
+	return func() {
+		stop()
+		os.Remove(path) //nolint:errcheck
+	}, true
+}
+
+// sharedLock registers a shared lock on the mirror directory returning the function to release it so that the
+// mirror is not evicted while it is used as a reference. It must be called while holding the exclusive lock so
+// that Evict, which also holds the exclusive lock, never misses a new shared lock
+func (c *Cache) sharedLock(dir string) (func(), error) {
+	readersDir := dir + readersSuffix
+	err := os.MkdirAll(readersDir, files.DefaultDirWritePermissions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", readersDir, err)
+	}
+	f, err := os.CreateTemp(readersDir, strconv.Itoa(os.Getpid())+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create shared lock file in %s: %w", readersDir, err)
+	}
+	path := f.Name()
+	f.Close() //nolint:errcheck
+	stop := c.heartbeat(path)
+	return func() {
+		stop()
+		os.Remove(path) //nolint:errcheck
+	}, nil
+}
+
+// hasSharedLocks returns true if the mirror directory has any shared locks removing any which are stale
+func (c *Cache) hasSharedLocks(dir string) bool {
+	readersDir := dir + readersSuffix
+	entries, err := os.ReadDir(readersDir)
+	if err != nil {
+		return false
+	}
+	answer := false
+	for _, e := range entries {
+		path := filepath.Join(readersDir, e.Name())
+		if c.isStale(path) {
+			os.Remove(path) //nolint:errcheck
+			continue
+		}
+		answer = true
+	}
+	return answer
+}
+
+// heartbeat refreshes the modification time of the lock file until the returned function is called
+func (c *Cache) heartbeat(path string) func() {
+	interval := c.lockTimeout() / 4
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				now := time.Now()
+				os.Chtimes(path, now, now) //nolint:errcheck
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// isStale returns true if the lock file has not been refreshed within the lock timeout
+func (c *Cache) isStale(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > c.lockTimeout()
+}
+
+func (c *Cache) lockTimeout() time.Duration {
+	if c.LockTimeout <= 0 {
+		return DefaultLockTimeout
+	}
+	return c.LockTimeout
+}
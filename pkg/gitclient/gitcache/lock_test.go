@@ -0,0 +1,31 @@
+//go:build unit
+// +build unit
+
+package gitcache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockIsRefreshedWhileHeld(t *testing.T) {
+	c := &Cache{Dir: t.TempDir(), LockTimeout: 200 * time.Millisecond}
+	dir := filepath.Join(c.Dir, "myrepo.git")
+
+	unlock, err := c.lock(dir)
+	require.NoError(t, err, "failed to lock")
+
+	// hold the lock for longer than the timeout like a slow first clone of a large repository
+	time.Sleep(3 * c.LockTimeout)
+	_, ok := c.tryLock(dir)
+	assert.False(t, ok, "a held lock should not be considered stale")
+
+	unlock()
+	unlock2, ok := c.tryLock(dir)
+	require.True(t, ok, "should lock once released")
+	unlock2()
+}
@@ -0,0 +1,300 @@
+package gitconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/homedir"
+)
+
+// Scope the scope of a git configuration file
+type Scope string
+
+const (
+	// ScopeLocal the configuration of a repository in .git/config
+	ScopeLocal Scope = "local"
+
+	// ScopeGlobal the configuration of the user in ~/.gitconfig or $GIT_CONFIG_GLOBAL
+	ScopeGlobal Scope = "global"
+
+	// ScopeXDG the configuration of the user in $XDG_CONFIG_HOME/git/config or ~/.config/git/config
+	ScopeXDG Scope = "xdg"
+)
+
+// Path returns the path of the git configuration file for the scope. The directory is only used to find the
+// repository for the local scope
+func Path(scope Scope, dir string) (string, error) {
+	switch scope {
+	case ScopeLocal:
+		return localPath(dir)
+	case ScopeGlobal:
+		if path := os.Getenv("GIT_CONFIG_GLOBAL"); path != "" {
+			return path, nil
+		}
+		return filepath.Join(homedir.HomeDir(), ".gitconfig"), nil
+	case ScopeXDG:
+		configHome := os.Getenv("XDG_CONFIG_HOME")
+		if configHome == "" {
+			configHome = filepath.Join(homedir.HomeDir(), ".config")
+		}
+		return filepath.Join(configHome, "git", "config"), nil
+	default:
+		return "", fmt.Errorf("unknown git configuration scope %s", scope)
+	}
+}
+
+// LoadScope loads the git configuration file for the scope
+func LoadScope(scope Scope, dir string) (*File, error) {
+	path, err := Path(scope, dir)
+	if err != nil {
+		return nil, err
+	}
+	return Load(path)
+}
+
+// SetValue sets the value of the key in the git configuration file of the scope
+func SetValue(scope Scope, dir, key, value string) error {
+	f, err := LoadScope(scope, dir)
+	if err != nil {
+		return err
+	}
+	err = f.Set(key, value)
+	if err != nil {
+		return err
+	}
+	return f.Save()
+}
+
+// Lookup returns the value of the key from the configuration files of the scopes which are in increasing order of
+// precedence. If no scopes are specified the XDG, global and local scopes are used like git
+func Lookup(dir, key string, scopes ...Scope) (string, bool, error) {
+	if len(scopes) == 0 {
+		scopes = []Scope{ScopeXDG, ScopeGlobal, ScopeLocal}
+	}
+	answer := ""
+	found := false
+	for _, scope := range scopes {
+		if scope == ScopeLocal && !inRepository(dir) {
+			continue
+		}
+		f, err := LoadScope(scope, dir)
+		if err != nil {
+			return "", false, err
+		}
+		if value, ok := f.Get(key); ok {
+			answer = value
+			found = true
+		}
+	}
+	return answer, found, nil
+}
+
+// InsteadOfRewrites returns the url.<base>.insteadOf rewrites from the XDG, global and local configuration files
+// mapping each URL prefix to the base URL which replaces it
+func InsteadOfRewrites(dir string) (map[string]string, error) {
+	answer := map[string]string{}
+	for _, scope := range []Scope{ScopeXDG, ScopeGlobal, ScopeLocal} {
+		if scope == ScopeLocal && !inRepository(dir) {
+			continue
+		}
+		f, err := LoadScope(scope, dir)
+		if err != nil {
+			return nil, err
+		}
+		for prefix, base := range f.InsteadOf() {
+			answer[prefix] = base
+		}
+	}
+	return answer, nil
+}
+
+// Remote a remote repository in the git configuration
+type Remote struct {
+	// Name the name of the remote such as origin
+	Name string
+	// URLs the URLs of the remote
+	URLs []string
+	// PushURLs the optional URLs used for pushing
+	PushURLs []string
+	// Fetch the refspecs to fetch
+	Fetch []string
+}
+
+// URL returns the first URL of the remote or an empty string
+func (r *Remote) URL() string {
+	if len(r.URLs) == 0 {
+		return ""
+	}
+	return r.URLs[0]
+}
+
+// Remotes returns the remotes in the order they are defined
+func (f *File) Remotes() []*Remote {
+	var answer []*Remote
+	for _, name := range f.Subsections("remote") {
+		answer = append(answer, f.Remote(name))
+	}
+	return answer
+}
+
+// Remote returns the remote of the given name or nil if it does not exist
+func (f *File) Remote(name string) *Remote {
+	r := &Remote{
+		Name:     name,
+		URLs:     f.GetAll(joinKey("remote", name, "url")),
+		PushURLs: f.GetAll(joinKey("remote", name, "pushurl")),
+		Fetch:    f.GetAll(joinKey("remote", name, "fetch")),
+	}
+	if len(r.URLs) == 0 && len(r.PushURLs) == 0 && len(r.Fetch) == 0 {
+		return nil
+	}
+	return r
+}
+
+// SetRemote sets the URL of the remote adding the default fetch refspec if the remote is new
+func (f *File) SetRemote(name, gitURL string) error {
+	isNew := f.Remote(name) == nil
+	err := f.Set(joinKey("remote", name, "url"), gitURL)
+	if err != nil {
+		return err
+	}
+	if isNew {
+		return f.Add(joinKey("remote", name, "fetch"), fmt.Sprintf("+refs/heads/*:refs/remotes/%s/*", name))
+	}
+	return nil
+}
+
+// RemoveRemote removes the remote returning true if it existed
+func (f *File) RemoveRemote(name string) bool {
+	return f.RemoveSection("remote", name)
+}
+
+// InsteadOf returns the url.<base>.insteadOf rewrites mapping each URL prefix to the base URL which replaces it
+func (f *File) InsteadOf() map[string]string {
+	answer := map[string]string{}
+	for _, base := range f.Subsections("url") {
+		for _, prefix := range f.GetAll(joinKey("url", base, "insteadof")) {
+			answer[prefix] = base
+		}
+	}
+	return answer
+}
+
+// AddInsteadOf adds a url.<base>.insteadOf rewrite so that URLs starting with the prefix use the base URL instead
+func (f *File) AddInsteadOf(base, prefix string) error {
+	key := joinKey("url", base, "insteadof")
+	for _, existing := range f.GetAll(key) {
+		if existing == prefix {
+			return nil
+		}
+	}
+	return f.Add(key, prefix)
+}
+
+// CredentialHelpers returns the credential helpers in the order git uses them. Like git an empty value
+// clears the helpers defined before it
+func (f *File) CredentialHelpers() []string {
+	var answer []string
+	for _, helper := range f.GetAll("credential.helper") {
+		if strings.TrimSpace(helper) == "" {
+			answer = nil
+			continue
+		}
+		answer = append(answer, helper)
+	}
+	return answer
+}
+
+// SetCredentialHelper sets the credential helper such as 'store' replacing any existing helpers
+func (f *File) SetCredentialHelper(helper string) error {
+	return f.Set("credential.helper", helper)
+}
+
+// User returns the user name and email
+func (f *File) User() (string, string) {
+	name, _ := f.Get("user.name")
+	email, _ := f.Get("user.email")
+	return name, email
+}
+
+// SetUser sets the user name and email. Empty values are not modified
+func (f *File) SetUser(name, email string) error {
+	if name != "" {
+		err := f.Set("user.name", name)
+		if err != nil {
+			return err
+		}
+	}
+	if email != "" {
+		return f.Set("user.email", email)
+	}
+	return nil
+}
+
+// localPath returns the path of the configuration file of the repository containing the directory
+func localPath(dir string) (string, error) {
+	gitDir, err := findGitDir(dir)
+	if err != nil {
+		return "", err
+	}
+	if gitDir == "" {
+		return "", fmt.Errorf("failed to find a git repository in %s or its parent directories", dir)
+	}
+	// linked worktrees share the configuration of the main repository
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err == nil {
+		commonDir := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(gitDir, commonDir)
+		}
+		gitDir = commonDir
+	}
+	return filepath.Join(gitDir, "config"), nil
+}
+
+// inRepository returns true if the directory is inside a git repository
+func inRepository(dir string) bool {
+	gitDir, err := findGitDir(dir)
+	return err == nil && gitDir != ""
+}
+
+// findGitDir returns the .git directory of the repository containing the directory or an empty string
+func findGitDir(dir string) (string, error) {
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the current directory: %w", err)
+		}
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the absolute path of %s: %w", dir, err)
+	}
+	for {
+		path := filepath.Join(dir, ".git")
+		info, err := os.Stat(path)
+		if err == nil {
+			if info.IsDir() {
+				return path, nil
+			}
+			// submodules and worktrees use a .git file pointing at the git directory
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			gitDir := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), "gitdir:"))
+			if !filepath.IsAbs(gitDir) {
+				gitDir = filepath.Join(dir, gitDir)
+			}
+			return gitDir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,469 @@
+package gitconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
+)
+
+// File a git configuration file which can be read and modified while keeping the existing formatting and comments
+type File struct {
+	// Path the path of the file
+	Path string
+
+	lines []*line
+}
+
+// line a logical line of the file which may span multiple physical lines if a value is continued
+type line struct {
+	// text the original text of the line
+	text string
+	// header true if the line is a section header
+	header bool
+	// section the lower case section name of the header or entry
+	section string
+	// subsection the subsection of the header or entry
+	subsection string
+	// name the lower case variable name of an entry or empty for headers, comments and blank lines
+	name string
+	// value the parsed value of an entry
+	value string
+}
+
+// key returns the key of the entry such as 'remote.origin.url'
+func (l *line) key() string {
+	return joinKey(l.section, l.subsection, l.name)
+}
+
+// Load loads the git configuration file returning an empty file if it does not exist
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &File{Path: path}, nil
+		}
+		return nil, fmt.Errorf("failed to load %s: %w", path, err)
+	}
+	f, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	f.Path = path
+	return f, nil
+}
+
+// Parse parses the git configuration file contents
+func Parse(data []byte) (*File, error) {
+	f := &File{}
+	section := ""
+	subsection := ""
+	physical := strings.SplitAfter(string(data), "\n")
+	for i := 0; i < len(physical); i++ {
+		text := physical[i]
+		if text == "" {
+			continue
+		}
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "" || trimmed[0] == '#' || trimmed[0] == ';':
+			f.lines = append(f.lines, &line{text: text, section: section, subsection: subsection})
+
+		case trimmed[0] == '[':
+			var err error
+			section, subsection, err = parseHeader(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			f.lines = append(f.lines, &line{text: text, header: true, section: section, subsection: subsection})
+
+		default:
+			if section == "" {
+				return nil, fmt.Errorf("line %d: entry %q is not in a section", i+1, trimmed)
+			}
+			// join any continuation lines
+			for continues(text) && i+1 < len(physical) {
+				i++
+				text += physical[i]
+			}
+			name, value, err := parseEntry(text)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			f.lines = append(f.lines, &line{text: text, section: section, subsection: subsection, name: name, value: value})
+		}
+	}
+	return f, nil
+}
+
+// Bytes returns the contents of the file
+func (f *File) Bytes() []byte {
+	var buf strings.Builder
+	for _, l := range f.lines {
+		buf.WriteString(l.text)
+	}
+	return []byte(buf.String())
+}
+
+// Save saves the file to its path creating the parent directory if required
+func (f *File) Save() error {
+	if f.Path == "" {
+		return fmt.Errorf("no path for the git configuration file")
+	}
+	err := os.MkdirAll(filepath.Dir(f.Path), files.DefaultDirWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", f.Path, err)
+	}
+	err = os.WriteFile(f.Path, f.Bytes(), files.DefaultFileWritePermissions)
+	if err != nil {
+		return fmt.Errorf("failed to save %s: %w", f.Path, err)
+	}
+	return nil
+}
+
+// Get returns the last value of the key such as 'user.name' and whether it exists
+func (f *File) Get(key string) (string, bool) {
+	values := f.GetAll(key)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetAll returns all the values of the key in order
+func (f *File) GetAll(key string) []string {
+	k, err := normalizeKey(key)
+	if err != nil {
+		return nil
+	}
+	var answer []string
+	for _, l := range f.lines {
+		if l.name != "" && l.key() == k {
+			answer = append(answer, l.value)
+		}
+	}
+	return answer
+}
+
+// Subsections returns the names of the subsections of the section in order such as the remote names
+// of the 'remote' section
+func (f *File) Subsections(section string) []string {
+	section = strings.ToLower(section)
+	var answer []string
+	found := map[string]bool{}
+	for _, l := range f.lines {
+		if l.header && l.section == section && l.subsection != "" && !found[l.subsection] {
+			found[l.subsection] = true
+			answer = append(answer, l.subsection)
+		}
+	}
+	return answer
+}
+
+// Set sets the value of the key replacing any existing values. An existing entry is updated in place otherwise
+// the entry is added to the end of the section which is created if it does not exist
+func (f *File) Set(key, value string) error {
+	section, subsection, name, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	k := joinKey(section, subsection, name)
+	last := -1
+	for i, l := range f.lines {
+		if l.name != "" && l.key() == k {
+			last = i
+		}
+	}
+	if last < 0 {
+		return f.Add(key, value)
+	}
+	existing := f.lines[last]
+	existing.text = indentation(existing.text) + entryName(existing.text) + " = " + formatValue(value) + "\n"
+	existing.value = value
+	f.removeLines(func(i int, l *line) bool {
+		return i != last && l.name != "" && l.key() == k
+	})
+	return nil
+}
+
+// Add adds a value to the key after any existing values
+func (f *File) Add(key, value string) error {
+	section, subsection, name, err := splitKey(key)
+	if err != nil {
+		return err
+	}
+	l := &line{text: "\t" + name + " = " + formatValue(value) + "\n", section: section, subsection: subsection, name: name, value: value}
+
+	// insert after the last entry of the key or of the section
+	k := l.key()
+	idx := -1
+	for i, existing := range f.lines {
+		if existing.section != section || existing.subsection != subsection {
+			continue
+		}
+		if existing.header && idx < 0 {
+			idx = i
+		}
+		if existing.name != "" && (existing.key() == k || !f.hasKey(k)) {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		f.ensureTrailingNewline()
+		f.lines = append(f.lines, &line{text: formatHeader(section, subsection) + "\n", header: true, section: section, subsection: subsection}, l)
+		return nil
+	}
+	if !strings.HasSuffix(f.lines[idx].text, "\n") {
+		f.lines[idx].text += "\n"
+	}
+	f.lines = append(f.lines[:idx+1], append([]*line{l}, f.lines[idx+1:]...)...)
+	return nil
+}
+
+// Unset removes all the values of the key returning true if any were removed
+func (f *File) Unset(key string) bool {
+	k, err := normalizeKey(key)
+	if err != nil {
+		return false
+	}
+	return f.removeLines(func(_ int, l *line) bool {
+		return l.name != "" && l.key() == k
+	})
+}
+
+// RemoveSection removes the section or subsection and all its entries and comments returning true if it existed
+func (f *File) RemoveSection(section, subsection string) bool {
+	section = strings.ToLower(section)
+	return f.removeLines(func(_ int, l *line) bool {
+		return l.section == section && l.subsection == subsection
+	})
+}
+
+// hasKey returns true if the key has a value
+func (f *File) hasKey(k string) bool {
+	for _, l := range f.lines {
+		if l.name != "" && l.key() == k {
+			return true
+		}
+	}
+	return false
+}
+
+// removeLines removes the lines matching the filter returning true if any were removed
+func (f *File) removeLines(filter func(i int, l *line) bool) bool {
+	var answer []*line
+	for i, l := range f.lines {
+		if !filter(i, l) {
+			answer = append(answer, l)
+		}
+	}
+	removed := len(answer) != len(f.lines)
+	f.lines = answer
+	return removed
+}
+
+// ensureTrailingNewline makes sure the last line ends with a newline before appending lines
+func (f *File) ensureTrailingNewline() {
+	if len(f.lines) > 0 {
+		last := f.lines[len(f.lines)-1]
+		if !strings.HasSuffix(last.text, "\n") {
+			last.text += "\n"
+		}
+	}
+}
+
+// parseHeader parses a section header such as [core], [remote "origin"] or the deprecated [branch.master]
+func parseHeader(text string) (string, string, error) {
+	end := strings.LastIndex(text, "]")
+	if end < 0 {
+		return "", "", fmt.Errorf("invalid section header %q", text)
+	}
+	rest := strings.TrimSpace(text[end+1:])
+	if rest != "" && rest[0] != '#' && rest[0] != ';' {
+		return "", "", fmt.Errorf("unsupported entry after section header %q", text)
+	}
+	inner := strings.TrimSpace(text[1:end])
+	if idx := strings.IndexAny(inner, " \t"); idx >= 0 {
+		section := strings.ToLower(inner[:idx])
+		quoted := strings.TrimSpace(inner[idx+1:])
+		if len(quoted) < 2 || quoted[0] != '"' || quoted[len(quoted)-1] != '"' {
+			return "", "", fmt.Errorf("invalid subsection in section header %q", text)
+		}
+		subsection := strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(quoted[1 : len(quoted)-1])
+		return section, subsection, nil
+	}
+	if section, subsection, ok := strings.Cut(inner, "."); ok {
+		// the deprecated [section.subsection] syntax has a case insensitive subsection
+		return strings.ToLower(section), strings.ToLower(subsection), nil
+	}
+	if inner == "" {
+		return "", "", fmt.Errorf("empty section header %q", text)
+	}
+	return strings.ToLower(inner), "", nil
+}
+
+// formatHeader returns the section header for the section and optional subsection
+func formatHeader(section, subsection string) string {
+	if subsection == "" {
+		return "[" + section + "]"
+	}
+	return "[" + section + ` "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(subsection) + `"]`
+}
+
+// parseEntry parses an entry such as 'name = value' returning the lower case name and the value.
+// An entry without a value is a boolean true
+func parseEntry(text string) (string, string, error) {
+	trimmed := strings.TrimLeft(text, " \t")
+	nameEnd := strings.IndexFunc(trimmed, func(r rune) bool {
+		return !(r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+	if nameEnd < 0 {
+		nameEnd = len(trimmed)
+	}
+	name := strings.ToLower(trimmed[:nameEnd])
+	if name == "" {
+		return "", "", fmt.Errorf("invalid entry %q", strings.TrimSpace(text))
+	}
+	rest := strings.TrimLeft(trimmed[nameEnd:], " \t")
+	if rest == "" || rest[0] == '\n' || rest[0] == '\r' || rest[0] == '#' || rest[0] == ';' {
+		return name, "true", nil
+	}
+	if rest[0] != '=' {
+		return "", "", fmt.Errorf("invalid entry %q", strings.TrimSpace(text))
+	}
+	value, err := parseValue(rest[1:])
+	if err != nil {
+		return "", "", fmt.Errorf("invalid value for %s: %w", name, err)
+	}
+	return name, value, nil
+}
+
+// parseValue parses the value handling quotes, escapes, comments and continuation lines
+func parseValue(text string) (string, error) {
+	var buf strings.Builder
+	quoted := false
+	// pending whitespace is only kept if it is followed by more of the value
+	pending := ""
+	started := false
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case c == '\r' || c == '\n':
+			if quoted {
+				return "", fmt.Errorf("missing closing quote")
+			}
+			return buf.String(), nil
+		case !quoted && (c == ' ' || c == '\t'):
+			if started {
+				pending += string(c)
+			}
+			continue
+		case !quoted && (c == '#' || c == ';'):
+			return buf.String(), nil
+		case c == '"':
+			quoted = !quoted
+		case c == '\\':
+			if i+1 >= len(text) {
+				return "", fmt.Errorf("incomplete escape sequence")
+			}
+			i++
+			switch text[i] {
+			case '\n':
+				// continuation line
+				continue
+			case '\r':
+				if i+1 < len(text) && text[i+1] == '\n' {
+					i++
+				}
+				continue
+			case 'n':
+				buf.WriteString(pending + "\n")
+			case 't':
+				buf.WriteString(pending + "\t")
+			case 'b':
+				buf.WriteString(pending + "\b")
+			case '"', '\\':
+				buf.WriteString(pending + string(text[i]))
+			default:
+				return "", fmt.Errorf("unknown escape sequence \\%c", text[i])
+			}
+		default:
+			buf.WriteString(pending)
+			buf.WriteByte(c)
+		}
+		pending = ""
+		started = true
+	}
+	if quoted {
+		return "", fmt.Errorf("missing closing quote")
+	}
+	return buf.String(), nil
+}
+
+// formatValue formats the value quoting and escaping it if required
+func formatValue(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\b", `\b`).Replace(value)
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;") {
+		return `"` + escaped + `"`
+	}
+	return escaped
+}
+
+// continues returns true if the physical line of an entry ends with a backslash continuing it onto the next line
+func continues(text string) bool {
+	text = strings.TrimRight(text, "\r\n")
+	if text == "" || !strings.HasSuffix(text, "\\") {
+		return false
+	}
+	// an even number of backslashes is an escaped backslash
+	count := len(text) - len(strings.TrimRight(text, "\\"))
+	return count%2 == 1
+}
+
+// indentation returns the leading whitespace of the text
+func indentation(text string) string {
+	return text[:len(text)-len(strings.TrimLeft(text, " \t"))]
+}
+
+// entryName returns the variable name of the entry as it is written in the text
+func entryName(text string) string {
+	trimmed := strings.TrimLeft(text, " \t")
+	end := strings.IndexAny(trimmed, " \t=#;\r\n")
+	if end < 0 {
+		return trimmed
+	}
+	return trimmed[:end]
+}
+
+// splitKey splits the key such as 'remote.origin.url' into its lower case section, subsection and lower case name
+func splitKey(key string) (string, string, string, error) {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", fmt.Errorf("invalid git configuration key %q: must be of the form section[.subsection].name", key)
+	}
+	subsection := ""
+	if last > first {
+		subsection = key[first+1 : last]
+	}
+	return strings.ToLower(key[:first]), subsection, strings.ToLower(key[last+1:]), nil
+}
+
+// normalizeKey returns the key with the section and name in lower case
+func normalizeKey(key string) (string, error) {
+	section, subsection, name, err := splitKey(key)
+	if err != nil {
+		return "", err
+	}
+	return joinKey(section, subsection, name), nil
+}
+
+// joinKey joins the section, optional subsection and name into a key
+func joinKey(section, subsection, name string) string {
+	if subsection == "" {
+		return section + "." + name
+	}
+	return section + "." + subsection + "." + name
+}
@@ -0,0 +1,260 @@
+//go:build unit
+// +build unit
+
+package gitconfig_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitconfig"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleConfig = `# the repository configuration
+[core]
+	repositoryformatversion = 0
+	bare = false   ; not a bare repository
+	logallrefupdates
+[remote "origin"]
+	url = https://github.com/myorg/myrepo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+
+    # the upstream repository
+[Remote "upstream"]
+	URL = git@github.com:upstream/myrepo.git
+[branch.Master]
+	remote = origin
+[alias]
+	lg = "log --oneline # not a comment"
+	st = status \
+--short
+	quoted = "  spaces\t\"here\"  "
+[credential]
+	helper = cache
+	helper =
+	helper = store
+[url "https://mirror.example.com/"]
+	insteadOf = https://github.com/
+	insteadOf = gh:
+`
+
+func TestParse(t *testing.T) {
+	f, err := gitconfig.Parse([]byte(sampleConfig))
+	require.NoError(t, err)
+	assert.Equal(t, sampleConfig, string(f.Bytes()), "contents should be unchanged")
+
+	testCases := map[string]string{
+		"core.repositoryformatversion": "0",
+		"core.bare":                    "false",
+		"CORE.LogAllRefUpdates":        "true",
+		"remote.origin.url":            "https://github.com/myorg/myrepo.git",
+		"remote.upstream.url":          "git@github.com:upstream/myrepo.git",
+		"branch.master.remote":         "origin",
+		"alias.lg":                     "log --oneline # not a comment",
+		"alias.st":                     "status --short",
+		"alias.quoted":                 "  spaces\t\"here\"  ",
+		"credential.helper":            "store",
+	}
+	for key, expected := range testCases {
+		value, ok := f.Get(key)
+		assert.True(t, ok, "should find %s", key)
+		assert.Equal(t, expected, value, "value of %s", key)
+	}
+	_, ok := f.Get("remote.Origin.url")
+	assert.False(t, ok, "subsections should be case sensitive")
+
+	assertSameAsGit(t, sampleConfig, testCases)
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, text := range []string{
+		"name = value\n",
+		"[core\n",
+		"[remote origin]\n",
+		"[core]\n\tname = \"unterminated\n",
+		"[core]\n\t= value\n",
+	} {
+		_, err := gitconfig.Parse([]byte(text))
+		assert.Error(t, err, "should fail to parse %q", text)
+	}
+}
+
+func TestModify(t *testing.T) {
+	f, err := gitconfig.Parse([]byte(sampleConfig))
+	require.NoError(t, err)
+
+	require.NoError(t, f.Set("core.bare", "true"))
+	require.NoError(t, f.Set("remote.upstream.url", "https://github.com/upstream/myrepo.git"))
+	require.NoError(t, f.Set("credential.helper", "store --file ~/.git-credentials"))
+	require.NoError(t, f.Add("remote.origin.fetch", "+refs/tags/*:refs/tags/*"))
+	require.NoError(t, f.Set("core.editor", "vim"))
+	require.NoError(t, f.Set("user.name", "jenkins-x-bot"))
+	require.NoError(t, f.Set("user.email", "jenkins-x@googlegroups.com"))
+	require.NoError(t, f.Set("alias.hash", "log # with a hash"))
+	assert.True(t, f.Unset("alias.st"), "should unset alias.st")
+	assert.False(t, f.Unset("alias.missing"), "should not unset a missing key")
+	require.Error(t, f.Set("nosection", "value"), "should fail with an invalid key")
+
+	expected := `# the repository configuration
+[core]
+	repositoryformatversion = 0
+	bare = true
+	logallrefupdates
+	editor = vim
+[remote "origin"]
+	url = https://github.com/myorg/myrepo.git
+	fetch = +refs/heads/*:refs/remotes/origin/*
+	fetch = +refs/tags/*:refs/tags/*
+
+    # the upstream repository
+[Remote "upstream"]
+	URL = https://github.com/upstream/myrepo.git
+[branch.Master]
+	remote = origin
+[alias]
+	lg = "log --oneline # not a comment"
+	quoted = "  spaces\t\"here\"  "
+	hash = "log # with a hash"
+[credential]
+	helper = store --file ~/.git-credentials
+[url "https://mirror.example.com/"]
+	insteadOf = https://github.com/
+	insteadOf = gh:
+[user]
+	name = jenkins-x-bot
+	email = jenkins-x@googlegroups.com
+`
+	assert.Equal(t, expected, string(f.Bytes()), "modified contents")
+	assertSameAsGit(t, expected, map[string]string{
+		"core.bare":         "true",
+		"alias.hash":        "log # with a hash",
+		"credential.helper": "store --file ~/.git-credentials",
+		"user.email":        "jenkins-x@googlegroups.com",
+	})
+
+	assert.True(t, f.RemoveSection("alias", ""), "should remove the alias section")
+	assert.NotContains(t, string(f.Bytes()), "[alias]")
+}
+
+func TestTypedValues(t *testing.T) {
+	f, err := gitconfig.Parse([]byte(sampleConfig))
+	require.NoError(t, err)
+
+	remotes := f.Remotes()
+	require.Len(t, remotes, 2)
+	assert.Equal(t, "origin", remotes[0].Name)
+	assert.Equal(t, "https://github.com/myorg/myrepo.git", remotes[0].URL())
+	assert.Equal(t, []string{"+refs/heads/*:refs/remotes/origin/*"}, remotes[0].Fetch)
+	assert.Equal(t, "upstream", remotes[1].Name)
+	assert.Nil(t, f.Remote("missing"))
+
+	require.NoError(t, f.SetRemote("fork", "https://github.com/me/myrepo.git"))
+	fork := f.Remote("fork")
+	require.NotNil(t, fork)
+	assert.Equal(t, []string{"https://github.com/me/myrepo.git"}, fork.URLs)
+	assert.Equal(t, []string{"+refs/heads/*:refs/remotes/fork/*"}, fork.Fetch)
+	assert.True(t, f.RemoveRemote("fork"))
+	assert.Nil(t, f.Remote("fork"))
+
+	assert.Equal(t, []string{"store"}, f.CredentialHelpers(), "an empty helper should reset the list")
+	require.NoError(t, f.SetCredentialHelper("cache"))
+	assert.Equal(t, []string{"cache"}, f.CredentialHelpers())
+
+	require.NoError(t, f.SetUser("jenkins-x-bot", ""))
+	name, email := f.User()
+	assert.Equal(t, "jenkins-x-bot", name)
+	assert.Equal(t, "", email)
+
+	require.NoError(t, f.AddInsteadOf("https://mirror.example.com/", "gh:"), "adding an existing rewrite")
+	require.NoError(t, f.AddInsteadOf("ssh://git@git.example.com:2222/", "https://git.example.com/"))
+	insteadOf := f.InsteadOf()
+	assert.Equal(t, map[string]string{
+		"https://github.com/":      "https://mirror.example.com/",
+		"gh:":                      "https://mirror.example.com/",
+		"https://git.example.com/": "ssh://git@git.example.com:2222/",
+	}, insteadOf)
+
+	info, err := giturl.ParseGitURLWithOptions("gh:myorg/myrepo", &giturl.ParseOptions{InsteadOf: insteadOf})
+	require.NoError(t, err)
+	assert.Equal(t, "mirror.example.com", info.Host)
+	assert.Equal(t, "myrepo", info.Name)
+}
+
+func TestScopes(t *testing.T) {
+	tmpDir := t.TempDir()
+	home := filepath.Join(tmpDir, "home")
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+
+	repoDir := filepath.Join(tmpDir, "repo")
+	subDir := filepath.Join(repoDir, "charts", "myapp")
+	require.NoError(t, os.MkdirAll(filepath.Join(repoDir, ".git"), 0o755))
+	require.NoError(t, os.MkdirAll(subDir, 0o755))
+
+	path, err := gitconfig.Path(gitconfig.ScopeGlobal, "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".gitconfig"), path)
+	path, err = gitconfig.Path(gitconfig.ScopeXDG, "")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(home, ".config", "git", "config"), path)
+	path, err = gitconfig.Path(gitconfig.ScopeLocal, subDir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(repoDir, ".git", "config"), path)
+	_, err = gitconfig.Path(gitconfig.ScopeLocal, home)
+	assert.Error(t, err, "should not find a repository")
+
+	require.NoError(t, gitconfig.SetValue(gitconfig.ScopeXDG, "", "user.name", "xdg-user"))
+	require.NoError(t, gitconfig.SetValue(gitconfig.ScopeXDG, "", "user.email", "xdg@example.com"))
+	require.NoError(t, gitconfig.SetValue(gitconfig.ScopeGlobal, "", "user.name", "global-user"))
+	require.NoError(t, gitconfig.SetValue(gitconfig.ScopeLocal, subDir, "user.name", "local-user"))
+	require.NoError(t, gitconfig.SetValue(gitconfig.ScopeGlobal, "", `url.https://mirror.example.com/.insteadOf`, "https://github.com/"))
+
+	testCases := []struct {
+		dir      string
+		key      string
+		scopes   []gitconfig.Scope
+		expected string
+	}{
+		{dir: subDir, key: "user.name", expected: "local-user"},
+		{dir: home, key: "user.name", expected: "global-user"},
+		{dir: subDir, key: "user.name", scopes: []gitconfig.Scope{gitconfig.ScopeXDG, gitconfig.ScopeGlobal}, expected: "global-user"},
+		{dir: subDir, key: "user.email", expected: "xdg@example.com"},
+		{dir: subDir, key: "user.missing", expected: ""},
+	}
+	for _, tc := range testCases {
+		value, found, err := gitconfig.Lookup(tc.dir, tc.key, tc.scopes...)
+		require.NoError(t, err)
+		assert.Equal(t, tc.expected != "", found, "found %s in %s", tc.key, tc.dir)
+		assert.Equal(t, tc.expected, value, "value of %s in %s", tc.key, tc.dir)
+	}
+
+	rewrites, err := gitconfig.InsteadOfRewrites(subDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"https://github.com/": "https://mirror.example.com/"}, rewrites)
+}
+
+// assertSameAsGit asserts that git reads the same values from the configuration file
+func assertSameAsGit(t *testing.T, text string, values map[string]string) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return
+	}
+	path := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, os.WriteFile(path, []byte(text), 0o600))
+	for key, expected := range values {
+		out, err := exec.Command("git", "config", "--file", path, "--get", key).Output()
+		require.NoError(t, err, "git failed to get %s", key)
+		actual := strings.TrimSuffix(string(out), "\n")
+		if expected == "true" && actual == "" {
+			// git returns an empty value for an entry without a value unless a bool type is used
+			continue
+		}
+		assert.Equal(t, expected, actual, "git value of %s", key)
+	}
+}
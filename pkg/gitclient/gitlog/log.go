@@ -0,0 +1,295 @@
+package gitlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+)
+
+const (
+	// recordSeparator separates the commits in the log output
+	recordSeparator = "\x1e"
+
+	// fieldSeparator separates the fields of a commit in the log output
+	fieldSeparator = "\x1f"
+
+	// LogFormat the machine readable git log format parsed by ParseLog. It should be used with
+	// the '-z' and '--name-status' arguments so that the changed files follow each commit
+	LogFormat = "%x1e%H%x1f%P%x1f%an%x1f%ae%x1f%aI%x1f%cn%x1f%ce%x1f%cI%x1f%s%x1f%b%x1f%(trailers:only,unfold)%x1f"
+
+	// logFieldCount the number of fields in the LogFormat
+	logFieldCount = 11
+)
+
+// FileStatus the status of a file changed by a commit
+type FileStatus string
+
+const (
+	// FileAdded the file was added
+	FileAdded FileStatus = "A"
+	// FileCopied the file was copied from another file
+	FileCopied FileStatus = "C"
+	// FileDeleted the file was deleted
+	FileDeleted FileStatus = "D"
+	// FileModified the file was modified
+	FileModified FileStatus = "M"
+	// FileRenamed the file was renamed from another file
+	FileRenamed FileStatus = "R"
+	// FileTypeChanged the type of the file changed such as to a symbolic link
+	FileTypeChanged FileStatus = "T"
+	// FileUnmerged the file is unmerged
+	FileUnmerged FileStatus = "U"
+)
+
+// LogCommit a commit returned from the git log with the parsed metadata and changed files
+type LogCommit struct {
+	// SHA the git commit sha of the commit
+	SHA string
+	// Parents the SHAs of the parent commits
+	Parents []string
+	// Author the author of the commit
+	Author Signature
+	// Committer the committer of the commit
+	Committer Signature
+	// Subject the first line of the commit message
+	Subject string
+	// Body the commit message after the subject including any trailers
+	Body string
+	// Trailers the trailers of the commit message such as Signed-off-by
+	Trailers []Trailer
+	// Files the files changed by the commit compared to its first parent
+	Files []ChangedFile
+}
+
+// Signature the name, email and date of the author or committer of a commit
+type Signature struct {
+	// Name the name of the person
+	Name string
+	// Email the email of the person
+	Email string
+	// Date the date of the signature
+	Date time.Time
+}
+
+// Trailer a trailer of a commit message such as 'Signed-off-by: Jane Doe <jane@example.com>'
+type Trailer struct {
+	// Key the key of the trailer such as Signed-off-by
+	Key string
+	// Value the value of the trailer
+	Value string
+}
+
+// ChangedFile a file changed by a commit
+type ChangedFile struct {
+	// Status the kind of change
+	Status FileStatus
+	// Path the path of the file
+	Path string
+	// OriginalPath the path of the file before it was renamed or copied
+	OriginalPath string
+	// Score the similarity percentage of a renamed or copied file
+	Score int
+}
+
+// LogOptions the options used to query the git log
+type LogOptions struct {
+	// From the revision such as a tag whose history is excluded. If empty the whole history is included
+	From string
+	// To the revision whose history is included which defaults to HEAD
+	To string
+	// Paths only includes commits changing any of the paths if specified
+	Paths []string
+	// MaxCount limits the number of commits if greater than zero
+	MaxCount int
+	// NoMerges excludes merge commits
+	NoMerges bool
+	// FirstParent only follows the first parent of merge commits
+	FirstParent bool
+	// Reverse returns the oldest commits first
+	Reverse bool
+}
+
+// String returns the name and email of the signature
+func (s Signature) String() string {
+	return fmt.Sprintf("%s <%s>", s.Name, s.Email)
+}
+
+// Message returns the full commit message
+func (c *LogCommit) Message() string {
+	if c.Body == "" {
+		return c.Subject
+	}
+	return c.Subject + "\n\n" + c.Body
+}
+
+// IsMerge returns true if the commit is a merge commit
+func (c *LogCommit) IsMerge() bool {
+	return len(c.Parents) > 1
+}
+
+// TrailerValues returns the values of the trailers with the given key ignoring case
+func (c *LogCommit) TrailerValues(key string) []string {
+	var answer []string
+	for _, t := range c.Trailers {
+		if strings.EqualFold(t.Key, key) {
+			answer = append(answer, t.Value)
+		}
+	}
+	return answer
+}
+
+// Log returns the commits of the git log in the given directory for the options
+func Log(g gitclient.Interface, dir string, o *LogOptions) ([]*LogCommit, error) {
+	if o == nil {
+		o = &LogOptions{}
+	}
+	args := []string{"log", "-z", "--name-status", "-M", "--format=" + LogFormat}
+	if o.MaxCount > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", o.MaxCount))
+	}
+	if o.NoMerges {
+		args = append(args, "--no-merges")
+	}
+	if o.FirstParent {
+		args = append(args, "--first-parent")
+	}
+	if o.Reverse {
+		args = append(args, "--reverse")
+	}
+	to := o.To
+	if to == "" {
+		to = "HEAD"
+	}
+	revision := to
+	if o.From != "" {
+		revision = o.From + ".." + to
+	}
+	args = append(args, revision, "--")
+	args = append(args, o.Paths...)
+
+	text, err := g.Command(dir, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the git log of %s in dir %s: %w", revision, dir, err)
+	}
+	return ParseLog(text)
+}
+
+// LogRange returns the commits reachable from the 'to' revision but not from the 'from' revision
+func LogRange(g gitclient.Interface, dir, from, to string) ([]*LogCommit, error) {
+	return Log(g, dir, &LogOptions{From: from, To: to})
+}
+
+// LogSinceTag returns the commits on the HEAD since the given tag
+func LogSinceTag(g gitclient.Interface, dir, tag string) ([]*LogCommit, error) {
+	return Log(g, dir, &LogOptions{From: tag})
+}
+
+// LogSinceLatestTag returns the commits on the HEAD since the most recently created tag and the name of the tag.
+// If there are no tags the whole history is returned
+func LogSinceLatestTag(g gitclient.Interface, dir string) ([]*LogCommit, string, error) {
+	_, tag, err := gitclient.NthTag(g, dir, 1)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to find the latest tag: %w", err)
+	}
+	commits, err := LogSinceTag(g, dir, tag)
+	return commits, tag, err
+}
+
+// ParseLog parses the output of git log using the LogFormat with the '-z' and '--name-status' arguments
+func ParseLog(text string) ([]*LogCommit, error) {
+	var answer []*LogCommit
+	for _, record := range strings.Split(text, recordSeparator) {
+		if strings.Trim(record, "\x00\n") == "" {
+			continue
+		}
+		fields := strings.SplitN(record, fieldSeparator, logFieldCount+1)
+		if len(fields) != logFieldCount+1 {
+			return answer, fmt.Errorf("unexpected git log record with %d fields: '%s'", len(fields), record)
+		}
+		c := &LogCommit{
+			SHA:       fields[0],
+			Parents:   strings.Fields(fields[1]),
+			Author:    Signature{Name: fields[2], Email: fields[3]},
+			Committer: Signature{Name: fields[5], Email: fields[6]},
+			Subject:   fields[8],
+			Body:      strings.TrimSpace(fields[9]),
+			Trailers:  parseTrailers(fields[10]),
+		}
+		var err error
+		c.Author.Date, err = parseDate(fields[4])
+		if err != nil {
+			return answer, fmt.Errorf("failed to parse the author date of commit %s: %w", c.SHA, err)
+		}
+		c.Committer.Date, err = parseDate(fields[7])
+		if err != nil {
+			return answer, fmt.Errorf("failed to parse the committer date of commit %s: %w", c.SHA, err)
+		}
+		c.Files, err = parseChangedFiles(fields[11])
+		if err != nil {
+			return answer, fmt.Errorf("failed to parse the changed files of commit %s: %w", c.SHA, err)
+		}
+		answer = append(answer, c)
+	}
+	return answer, nil
+}
+
+func parseDate(text string) (time.Time, error) {
+	if text == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, text)
+}
+
+// parseTrailers parses the 'Key: value' lines output by %(trailers:only,unfold)
+func parseTrailers(text string) []Trailer {
+	var answer []Trailer
+	for _, line := range strings.Split(text, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		answer = append(answer, Trailer{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value)})
+	}
+	return answer
+}
+
+// parseChangedFiles parses the NUL separated output of --name-status -z such as 'M\0path\0R100\0old\0new\0'
+func parseChangedFiles(text string) ([]ChangedFile, error) {
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "\x00"), "\n")
+	var tokens []string
+	for _, t := range strings.Split(text, "\x00") {
+		if t != "" {
+			tokens = append(tokens, t)
+		}
+	}
+	var answer []ChangedFile
+	for i := 0; i < len(tokens); i++ {
+		status := tokens[i]
+		f := ChangedFile{Status: FileStatus(status[:1])}
+		if len(status) > 1 {
+			score, err := strconv.Atoi(status[1:])
+			if err != nil {
+				return answer, fmt.Errorf("invalid file status '%s': %w", status, err)
+			}
+			f.Score = score
+		}
+		paths := 1
+		if f.Status == FileRenamed || f.Status == FileCopied {
+			paths = 2
+		}
+		if i+paths >= len(tokens) {
+			return answer, fmt.Errorf("missing path for file status '%s'", status)
+		}
+		if paths == 2 {
+			i++
+			f.OriginalPath = tokens[i]
+		}
+		i++
+		f.Path = tokens[i]
+		answer = append(answer, f)
+	}
+	return answer, nil
+}
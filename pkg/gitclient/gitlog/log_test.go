@@ -0,0 +1,116 @@
+//go:build unit
+// +build unit
+
+package gitlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	machineLogOutput = "\x1e93f98aeff81650" +
+		"\x1f39b478f8ae18864b\x1fJames Strachan\x1fjames@example.com\x1f2020-12-14T17:36:08+00:00" +
+		"\x1fjenkins-x-bot\x1fjenkins-x@googlegroups.com\x1f2020-12-14T18:00:00+01:00" +
+		"\x1ffeat: add thing\x1fsome details\n\nSigned-off-by: Joe <joe@example.com>\nCo-authored-by: Ann <ann@example.com>\n" +
+		"\x1fSigned-off-by: Joe <joe@example.com>\nCo-authored-by: Ann <ann@example.com>\n" +
+		"\x1f\x00\nD\x00b.txt\x00R100\x00a.txt\x00c.txt\x00A\x00n.txt\x00" +
+		"\x1e39b478f8ae18864b\x1f\x1fJames Strachan\x1fjames@example.com\x1f2020-12-14T17:33:32+00:00" +
+		"\x1fJames Strachan\x1fjames@example.com\x1f2020-12-14T17:33:32+00:00" +
+		"\x1finitial commit\x1f\x1f\x1f\x00\nA\x00a.txt\x00A\x00b.txt\x00"
+)
+
+func TestParseLog(t *testing.T) {
+	commits, err := gitlog.ParseLog(machineLogOutput)
+	require.NoError(t, err, "failed to parse log")
+	require.Len(t, commits, 2, "number of commits")
+
+	c := commits[0]
+	assert.Equal(t, "93f98aeff81650", c.SHA, "SHA")
+	assert.Equal(t, []string{"39b478f8ae18864b"}, c.Parents, "Parents")
+	assert.Equal(t, "James Strachan <james@example.com>", c.Author.String(), "Author")
+	assert.Equal(t, time.Date(2020, 12, 14, 17, 36, 8, 0, time.UTC), c.Author.Date.UTC(), "Author.Date")
+	assert.Equal(t, "jenkins-x-bot", c.Committer.Name, "Committer.Name")
+	assert.Equal(t, time.Date(2020, 12, 14, 17, 0, 0, 0, time.UTC), c.Committer.Date.UTC(), "Committer.Date")
+	assert.Equal(t, "feat: add thing", c.Subject, "Subject")
+	assert.Equal(t, "some details\n\nSigned-off-by: Joe <joe@example.com>\nCo-authored-by: Ann <ann@example.com>", c.Body, "Body")
+	assert.Equal(t, []gitlog.Trailer{
+		{Key: "Signed-off-by", Value: "Joe <joe@example.com>"},
+		{Key: "Co-authored-by", Value: "Ann <ann@example.com>"},
+	}, c.Trailers, "Trailers")
+	assert.Equal(t, []string{"Ann <ann@example.com>"}, c.TrailerValues("co-authored-by"), "TrailerValues")
+	assert.Equal(t, []gitlog.ChangedFile{
+		{Status: gitlog.FileDeleted, Path: "b.txt"},
+		{Status: gitlog.FileRenamed, Path: "c.txt", OriginalPath: "a.txt", Score: 100},
+		{Status: gitlog.FileAdded, Path: "n.txt"},
+	}, c.Files, "Files")
+
+	c = commits[1]
+	assert.Empty(t, c.Parents, "Parents of root commit")
+	assert.Equal(t, "initial commit", c.Message(), "Message")
+	assert.Empty(t, c.Trailers, "Trailers")
+	assert.Len(t, c.Files, 2, "Files")
+
+	_, err = gitlog.ParseLog("\x1enot enough fields")
+	require.Error(t, err, "should fail to parse an invalid record")
+}
+
+func TestLog(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "jenkins-x@googlegroups.com")
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	dir := t.TempDir()
+	err := gitclient.Init(g, dir)
+	require.NoError(t, err, "failed to init")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o600))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "initial commit")
+	require.NoError(t, err, "failed to commit")
+	_, err = g.Command(dir, "tag", "v1.0.0")
+	require.NoError(t, err, "failed to tag")
+
+	_, err = g.Command(dir, "mv", "a.txt", "b.txt")
+	require.NoError(t, err, "failed to rename")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c.txt"), []byte("c\n"), 0o600))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "fix: rename a\n\nmore details\n\nSigned-off-by: Joe <joe@example.com>")
+	require.NoError(t, err, "failed to commit")
+
+	commits, tag, err := gitlog.LogSinceLatestTag(g, dir)
+	require.NoError(t, err, "failed to get log")
+	assert.Equal(t, "v1.0.0", tag, "latest tag")
+	require.Len(t, commits, 1, "commits since tag")
+
+	c := commits[0]
+	assert.Equal(t, "fix: rename a", c.Subject, "Subject")
+	assert.Equal(t, "more details\n\nSigned-off-by: Joe <joe@example.com>", c.Body, "Body")
+	assert.Equal(t, []string{"Joe <joe@example.com>"}, c.TrailerValues("Signed-off-by"), "Signed-off-by")
+	assert.Equal(t, "jenkins-x-bot <jenkins-x@googlegroups.com>", c.Author.String(), "Author")
+	assert.False(t, c.Author.Date.IsZero(), "Author.Date")
+	assert.Len(t, c.Parents, 1, "Parents")
+	assert.Equal(t, []gitlog.ChangedFile{
+		{Status: gitlog.FileRenamed, Path: "b.txt", OriginalPath: "a.txt", Score: 100},
+		{Status: gitlog.FileAdded, Path: "c.txt"},
+	}, c.Files, "Files")
+
+	commits, err = gitlog.Log(g, dir, &gitlog.LogOptions{Reverse: true})
+	require.NoError(t, err, "failed to get log")
+	require.Len(t, commits, 2, "all commits")
+	assert.Equal(t, "initial commit", commits[0].Subject, "first commit")
+	assert.Equal(t, commits[0].SHA, commits[1].Parents[0], "parent SHA")
+
+	commits, err = gitlog.Log(g, dir, &gitlog.LogOptions{Paths: []string{"c.txt"}})
+	require.NoError(t, err, "failed to get log")
+	require.Len(t, commits, 1, "commits changing c.txt")
+}
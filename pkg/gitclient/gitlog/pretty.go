@@ -0,0 +1,227 @@
+package gitlog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MediumDateFormat the default date format of git log
+	MediumDateFormat = "Mon Jan 2 15:04:05 2006 -0700"
+
+	// rfc2822DateFormat the date format used by %aD and %cD
+	rfc2822DateFormat = "Mon, 2 Jan 2006 15:04:05 -0700"
+
+	// isoStrictDateFormat the date format used by %aI and %cI
+	isoStrictDateFormat = "2006-01-02T15:04:05-07:00"
+)
+
+// PrettyCommit a commit formatted with the git log pretty formats by git clients which do not use the git binary
+type PrettyCommit struct {
+	// SHA the git commit sha of the commit
+	SHA string
+	// Tree the SHA of the tree of the commit if known
+	Tree string
+	// Parents the SHAs of the parent commits
+	Parents []string
+	// Author the author of the commit
+	Author Signature
+	// Committer the committer of the commit
+	Committer Signature
+	// Message the full commit message
+	Message string
+}
+
+// UnsupportedFormatError the error returned when a pretty format or placeholder is not supported by FormatCommit
+type UnsupportedFormatError struct {
+	// Format the unsupported format or placeholder
+	Format string
+}
+
+// Error returns the error message
+func (e *UnsupportedFormatError) Error() string {
+	return fmt.Sprintf("unsupported git log format %s", e.Format)
+}
+
+// FormatCommit formats the commit with a git log pretty format such as 'medium', 'oneline', 'format:<string>'
+// or 'tformat:<string>'. The returned boolean is true if the text is terminated after each commit like the tformat
+// formats rather than separated from the next commit. An UnsupportedFormatError is returned for unknown formats
+func FormatCommit(c *PrettyCommit, format string) (string, bool, error) {
+	switch {
+	case format == "" || format == "medium":
+		return FormatMedium(c), false, nil
+	case format == "oneline":
+		text, err := FormatPlaceholders(c, "%H %s")
+		return text, true, err
+	case strings.HasPrefix(format, "format:"):
+		text, err := FormatPlaceholders(c, strings.TrimPrefix(format, "format:"))
+		return text, false, err
+	case strings.HasPrefix(format, "tformat:"):
+		text, err := FormatPlaceholders(c, strings.TrimPrefix(format, "tformat:"))
+		return text, true, err
+	case strings.Contains(format, "%"):
+		text, err := FormatPlaceholders(c, format)
+		return text, true, err
+	default:
+		return "", false, &UnsupportedFormatError{Format: format}
+	}
+}
+
+// FormatMedium formats the commit like the default git log format so it can be parsed by ParseGitLog
+func FormatMedium(c *PrettyCommit) string {
+	buf := strings.Builder{}
+	buf.WriteString("commit " + c.SHA + "\n")
+	if len(c.Parents) > 1 {
+		buf.WriteString("Merge: " + strings.Join(shortSHAs(c.Parents), " ") + "\n")
+	}
+	buf.WriteString(fmt.Sprintf("Author: %s <%s>\n", c.Author.Name, c.Author.Email))
+	buf.WriteString("Date:   " + c.Author.Date.Format(MediumDateFormat) + "\n\n")
+	for _, line := range strings.Split(strings.TrimRight(c.Message, "\n"), "\n") {
+		if line == "" {
+			buf.WriteString("\n")
+			continue
+		}
+		buf.WriteString("    " + line + "\n")
+	}
+	return buf.String()
+}
+
+// FormatPlaceholders expands the git log pretty format placeholders such as %H, %s or %an for the commit.
+// An UnsupportedFormatError is returned for unknown placeholders
+func FormatPlaceholders(c *PrettyCommit, format string) (string, error) {
+	buf := strings.Builder{}
+	for i := 0; i < len(format); i++ {
+		ch := format[i]
+		if ch != '%' || i+1 >= len(format) {
+			buf.WriteByte(ch)
+			continue
+		}
+		rest := format[i+1:]
+		value, size, ok := placeholder(c, rest)
+		if !ok {
+			end := 1
+			if len(rest) > 1 {
+				end = 2
+			}
+			if rest[0] == '(' && strings.Contains(rest, ")") {
+				end = strings.Index(rest, ")") + 1
+			}
+			return "", &UnsupportedFormatError{Format: "%" + rest[:end]}
+		}
+		buf.WriteString(value)
+		i += size
+	}
+	return buf.String(), nil
+}
+
+// placeholder returns the value of the placeholder at the start of the text and the number of characters it uses
+func placeholder(c *PrettyCommit, text string) (string, int, bool) {
+	switch text[0] {
+	case 'H':
+		return c.SHA, 1, true
+	case 'h':
+		return shortSHA(c.SHA), 1, true
+	case 'T':
+		return c.Tree, 1, c.Tree != ""
+	case 't':
+		return shortSHA(c.Tree), 1, c.Tree != ""
+	case 'P':
+		return strings.Join(c.Parents, " "), 1, true
+	case 'p':
+		return strings.Join(shortSHAs(c.Parents), " "), 1, true
+	case 's':
+		subject, _ := splitMessage(c.Message)
+		return subject, 1, true
+	case 'b':
+		_, body := splitMessage(c.Message)
+		if body == "" {
+			return "", 1, true
+		}
+		return body + "\n", 1, true
+	case 'B':
+		return strings.TrimRight(c.Message, "\n") + "\n", 1, true
+	case 'n':
+		return "\n", 1, true
+	case '%':
+		return "%", 1, true
+	case 'x':
+		if len(text) < 3 {
+			return "", 0, false
+		}
+		b, err := strconv.ParseUint(text[1:3], 16, 8)
+		if err != nil {
+			return "", 0, false
+		}
+		return string([]byte{byte(b)}), 3, true
+	case '(':
+		for _, name := range []string{"(trailers:only,unfold)", "(trailers:only)", "(trailers)"} {
+			if strings.HasPrefix(text, name) {
+				return formatTrailers(c.Message), len(name), true
+			}
+		}
+	case 'a', 'c':
+		if len(text) < 2 {
+			return "", 0, false
+		}
+		sig := c.Author
+		if text[0] == 'c' {
+			sig = c.Committer
+		}
+		switch text[1] {
+		case 'n':
+			return sig.Name, 2, true
+		case 'e':
+			return sig.Email, 2, true
+		case 'd':
+			return sig.Date.Format(MediumDateFormat), 2, true
+		case 'D':
+			return sig.Date.Format(rfc2822DateFormat), 2, true
+		case 'I':
+			return sig.Date.Format(isoStrictDateFormat), 2, true
+		case 't':
+			return strconv.FormatInt(sig.Date.Unix(), 10), 2, true
+		}
+	}
+	return "", 0, false
+}
+
+// splitMessage splits the commit message into the subject, with the lines of the first paragraph joined, and the body
+func splitMessage(message string) (string, string) {
+	message = strings.TrimSpace(message)
+	subject, body, _ := strings.Cut(message, "\n\n")
+	subject = strings.Join(strings.Fields(strings.ReplaceAll(subject, "\n", " ")), " ")
+	return subject, strings.TrimSpace(body)
+}
+
+// formatTrailers returns the 'Key: value' trailer lines of the last paragraph of the message
+func formatTrailers(message string) string {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	if len(paragraphs) < 2 {
+		return ""
+	}
+	buf := strings.Builder{}
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return ""
+		}
+		buf.WriteString(key + ": " + strings.TrimSpace(value) + "\n")
+	}
+	return buf.String()
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
+func shortSHAs(shas []string) []string {
+	answer := make([]string, 0, len(shas))
+	for _, sha := range shas {
+		answer = append(answer, shortSHA(sha))
+	}
+	return answer
+}
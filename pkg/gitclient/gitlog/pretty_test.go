@@ -0,0 +1,83 @@
+//go:build unit
+// +build unit
+
+package gitlog_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatCommitMatchesGit(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "James Strachan")
+	t.Setenv("GIT_AUTHOR_EMAIL", "james@example.com")
+	t.Setenv("GIT_AUTHOR_DATE", "2020-12-14T17:36:08+01:00")
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv("GIT_COMMITTER_DATE", "2020-12-15T09:00:00-05:00")
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	dir := t.TempDir()
+	require.NoError(t, gitclient.Init(g, dir), "failed to init")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o600))
+	_, err := gitclient.AddAndCommitFiles(g, dir, "initial commit")
+	require.NoError(t, err, "failed to commit")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("b\n"), 0o600))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "feat: add thing\n\nsome details\n\nSigned-off-by: Joe <joe@example.com>")
+	require.NoError(t, err, "failed to commit")
+
+	commits, err := gitlog.Log(g, dir, nil)
+	require.NoError(t, err, "failed to get log")
+	require.Len(t, commits, 2, "commits")
+	tree, err := g.Command(dir, "rev-parse", "HEAD^{tree}")
+	require.NoError(t, err, "failed to get tree")
+	c := prettyCommit(commits[0], tree)
+
+	// git indents the blank lines of the message which ParseGitLog does not expect so use a single line message
+	expected, err := g.Command(dir, "log", "-1", "HEAD~1")
+	require.NoError(t, err, "failed to get git log")
+	assert.Equal(t, expected, strings.TrimSpace(gitlog.FormatMedium(prettyCommit(commits[1], ""))), "medium format")
+
+	formats := []string{
+		"oneline",
+		"format:%H %h %T %t %P %p",
+		"tformat:%s|%b|%B",
+		"%an <%ae> %ad|%aD|%aI|%at",
+		"%cn <%ce> %cd|%cD|%cI|%ct",
+		"%x1e%s%x1f%(trailers:only,unfold)%n%%",
+	}
+	for _, format := range formats {
+		expected, err := g.Command(dir, "log", "-1", "--pretty="+format)
+		require.NoError(t, err, "failed to get git log for %s", format)
+
+		text, _, err := gitlog.FormatCommit(c, format)
+		require.NoError(t, err, "failed to format %s", format)
+		assert.Equal(t, expected, strings.TrimSpace(text), "format %s", format)
+	}
+
+	_, _, err = gitlog.FormatCommit(c, "fuller")
+	require.Error(t, err, "fuller should not be supported")
+	_, err = gitlog.FormatPlaceholders(c, "%s %GK")
+	require.Error(t, err, "signature placeholders should not be supported")
+	assert.Equal(t, "unsupported git log format %GK", err.Error())
+}
+
+func prettyCommit(c *gitlog.LogCommit, tree string) *gitlog.PrettyCommit {
+	return &gitlog.PrettyCommit{
+		SHA:       c.SHA,
+		Tree:      tree,
+		Parents:   c.Parents,
+		Author:    c.Author,
+		Committer: c.Committer,
+		Message:   c.Message(),
+	}
+}
@@ -1,6 +1,8 @@
 package giturl
 
 const (
+	// KindAzure git kind for Azure DevOps
+	KindAzure = "azure"
 	// KindBitBucketCloud git kind for BitBucket Cloud
 	KindBitBucketCloud = "bitbucketcloud"
 	// KindBitBucketServer git kind for BitBucket Server
@@ -21,8 +23,14 @@ const (
 
 	// FakeGitURL the default URL for the fake git provider
 	FakeGitURL = "https://fake.git"
+
+	// AzureDevOpsHost the host of Azure DevOps
+	AzureDevOpsHost = "dev.azure.com"
+
+	// AzureDevOpsURL the URL of Azure DevOps
+	AzureDevOpsURL = "https://dev.azure.com"
 )
 
 var (
-	KindGits = []string{KindBitBucketCloud, KindBitBucketServer, KindGitea, KindGitHub, KindGitlab}
+	KindGits = []string{KindAzure, KindBitBucketCloud, KindBitBucketServer, KindGitea, KindGitHub, KindGitlab}
 )
@@ -16,91 +16,104 @@ const (
 	gitPrefix = "git@"
 )
 
+// scpLikeRegex matches the scp-like git URLs of the form [user@]host:path
+var scpLikeRegex = regexp.MustCompile(`^(?:[^@/:]+@)?([^:/]+):(.*)$`)
+
+// ParseOptions the options for parsing git URLs
+type ParseOptions struct {
+	// Kind the git kind of the server such as KindGitlab if it is known. If empty the kind is found from the
+	// registered servers or the host name
+	Kind string
+	// InsteadOf the url.<base>.insteadOf rewrites from the git configuration which map a URL prefix
+	// to the base URL it is replaced with
+	InsteadOf map[string]string
+}
+
 // ParseGitURL attempts to parse the given text as a URL or git URL-like string to determine
 // the protocol, host, organisation and name
 func ParseGitURL(text string) (*GitRepository, error) {
-	answer := GitRepository{
-		URL: text,
-	}
-	u, err := url.Parse(text)
-	if err == nil && u != nil {
-		answer.Host = u.Host
-		// lets default to github
-		if answer.Host == "" {
-			answer.Host = GitHubHost
-		}
-		if answer.Scheme == "" {
-			answer.Scheme = "https"
-		}
-		answer.Scheme = u.Scheme
-		return parsePath(u.Path, &answer, true)
-	}
+	return ParseGitURLWithOptions(text, nil)
+}
 
-	// handle git@ kinds of URIs
-	if strings.HasPrefix(text, gitPrefix) {
-		t := strings.TrimPrefix(text, gitPrefix)
-		t = strings.TrimPrefix(t, "/")
-		t = strings.TrimPrefix(t, "/")
-		t = strings.TrimSuffix(t, "/")
-		t = strings.TrimSuffix(t, ".git")
-
-		arr := stringhelpers.RegexpSplit(t, ":|/")
-		if len(arr) >= 3 {
-			answer.Scheme = "git"
-			answer.Host = arr[0]
-			answer.Organisation = arr[1]
-			// Dont do exact match on gitlab.com as there can be custom gitlab domains
-			if strings.Contains(answer.Host, "gitlab") {
-				answer.Name = strings.Join(arr[2:], "/")
-			} else {
-				answer.Name = arr[len(arr)-1]
-			}
-			return &answer, nil
-		}
-	}
-	return nil, fmt.Errorf("could not parse Git URL %s", text)
+// ParseGitURLWithOptions attempts to parse the given text as a URL or git URL-like string to determine
+// the protocol, host, organisation and name using the options to rewrite the URL or to specify the git kind
+func ParseGitURLWithOptions(text string, o *ParseOptions) (*GitRepository, error) {
+	return parseGitURL(text, o, true)
 }
 
 // ParseGitOrganizationURL attempts to parse the given text as a URL or git URL-like string to determine
 // the protocol, host, organisation
 func ParseGitOrganizationURL(text string) (*GitRepository, error) {
+	return parseGitURL(text, nil, false)
+}
+
+func parseGitURL(text string, o *ParseOptions, requireRepo bool) (*GitRepository, error) {
+	if o == nil {
+		o = &ParseOptions{}
+	}
+	text = RewriteURL(text, o.InsteadOf)
 	answer := GitRepository{
 		URL: text,
 	}
 	u, err := url.Parse(text)
-	if err == nil && u != nil {
+	if err == nil && u != nil && u.Opaque == "" {
 		answer.Host = u.Host
-
 		// lets default to github
 		if answer.Host == "" {
 			answer.Host = GitHubHost
 		}
+		answer.Scheme = u.Scheme
 		if answer.Scheme == "" {
 			answer.Scheme = "https"
 		}
-		answer.Scheme = u.Scheme
-		return parsePath(u.Path, &answer, false)
+		if isSSHScheme(answer.Scheme) && u.Port() != "" {
+			// the ssh port is not the port of the web server
+			answer.Host = u.Hostname()
+			answer.SSHPort = u.Port()
+		}
+		answer.Kind = serverKind(answer.Host, o.Kind)
+		return parsePath(u.Path, &answer, requireRepo)
 	}
+
 	// handle git@ kinds of URIs
-	if strings.HasPrefix(text, gitPrefix) {
-		t := strings.TrimPrefix(text, gitPrefix)
-		t = strings.TrimPrefix(t, "/")
-		t = strings.TrimPrefix(t, "/")
-		t = strings.TrimSuffix(t, "/")
-		t = strings.TrimSuffix(t, ".git")
-
-		arr := stringhelpers.RegexpSplit(t, ":|/")
-		if len(arr) >= 3 {
-			answer.Scheme = "git"
-			answer.Host = arr[0]
-			answer.Organisation = arr[1]
-			return &answer, nil
-		}
+	groups := scpLikeRegex.FindStringSubmatch(text)
+	if len(groups) > 0 {
+		answer.Scheme = "git"
+		answer.Host = groups[1]
+		answer.Kind = serverKind(answer.Host, o.Kind)
+		return parsePath(groups[2], &answer, requireRepo)
 	}
 	return nil, fmt.Errorf("could not parse Git URL %s", text)
 }
 
+// RewriteURL rewrites the git URL using the url.<base>.insteadOf rewrites which map a URL prefix to the base URL.
+// Like git the longest matching prefix is used
+func RewriteURL(text string, insteadOf map[string]string) string {
+	longest := ""
+	for prefix := range insteadOf {
+		if strings.HasPrefix(text, prefix) && len(prefix) > len(longest) {
+			longest = prefix
+		}
+	}
+	if longest == "" {
+		return text
+	}
+	return insteadOf[longest] + strings.TrimPrefix(text, longest)
+}
+
 func parsePath(path string, info *GitRepository, requireRepo bool) (*GitRepository, error) {
+	switch info.Kind {
+	case KindAzure:
+		return parseAzurePath(path, info, requireRepo)
+	case KindBitBucketServer:
+		return parseBitBucketServerPath(path, info, requireRepo)
+	case KindGitlab:
+		// remove the links to pages inside the project such as /-/merge_requests/1
+		if idx := strings.Index(path, "/-/"); idx >= 0 {
+			path = path[:idx]
+		}
+	}
+
 	// This is necessary for Bitbucket Server in some cases.
 	trimPath := strings.TrimPrefix(path, "/scm")
 
@@ -127,9 +140,13 @@ func parsePath(path string, info *GitRepository, requireRepo bool) (*GitReposito
 		// We're assuming the beginning of the path is of the form /<org>/<repo> or /<org>/<subgroup>/.../<repo>
 		info.Organisation = arr[0]
 		info.Project = arr[0]
-		if strings.Contains(info.Host, "gitlab") {
+		switch info.Kind {
+		case KindGitlab:
 			info.Name = strings.Join(arr[1:], "/")
-		} else {
+		case KindGitHub, KindGitea, KindBitBucketCloud:
+			// any remaining path is a page inside the repository such as /tree/main
+			info.Name = strings.TrimSuffix(arr[1], ".git")
+		default:
 			info.Name = arr[len(arr)-1]
 		}
 
@@ -144,6 +161,103 @@ func parsePath(path string, info *GitRepository, requireRepo bool) (*GitReposito
 	return info, fmt.Errorf("invalid path %s could not determine organisation and repository name", path)
 }
 
+// parseAzurePath parses the Azure DevOps paths /<org>/<project>/_git/<repo> or /<org>/_git/<repo> if the
+// project and repository have the same name, /v3/<org>/<project>/<repo> for SSH and
+// /[DefaultCollection/]<project>/_git/<repo> on the legacy <org>.visualstudio.com hosts
+func parseAzurePath(path string, info *GitRepository, requireRepo bool) (*GitRepository, error) {
+	arr := splitPath(path)
+	if len(arr) > 0 && arr[0] == "v3" {
+		// ssh://git@ssh.dev.azure.com/v3/<org>/<project>/<repo>
+		info.Host = AzureDevOpsHost
+		if len(arr) >= 4 {
+			info.Organisation, info.Project, info.Name = arr[1], arr[2], arr[3]
+			return info, nil
+		}
+		if len(arr) >= 2 && !requireRepo {
+			info.Organisation = arr[1]
+			return info, nil
+		}
+		return info, fmt.Errorf("invalid Azure DevOps path %s could not determine organisation and repository name", path)
+	}
+
+	if org := visualStudioOrganisation(info.Host); org != "" {
+		if len(arr) > 0 && strings.EqualFold(arr[0], "DefaultCollection") {
+			arr = arr[1:]
+		}
+		arr = append([]string{org}, arr...)
+	}
+	info.Organisation = arr[0]
+	if idx := stringhelpers.StringArrayIndex(arr, "_git"); idx > 0 && idx+1 < len(arr) {
+		info.Project = arr[idx-1]
+		if idx == 1 {
+			// the project has the same name as the repository
+			info.Project = arr[idx+1]
+		}
+		info.Name = arr[idx+1]
+		return info, nil
+	}
+	if len(arr) >= 2 {
+		info.Project = arr[1]
+	}
+	if info.Organisation != "" && !requireRepo {
+		return info, nil
+	}
+	return info, fmt.Errorf("invalid Azure DevOps path %s could not determine organisation and repository name", path)
+}
+
+// parseBitBucketServerPath parses the Bitbucket Server paths /projects/<project>/repos/<repo>/...,
+// /users/<user>/repos/<repo>/... and the clone paths /scm/<project>/<repo>.git and /<project>/<repo>.git
+func parseBitBucketServerPath(path string, info *GitRepository, requireRepo bool) (*GitRepository, error) {
+	arr := splitPath(path)
+	if len(arr) > 0 && arr[0] == "scm" {
+		arr = arr[1:]
+	}
+	if len(arr) >= 2 && (arr[0] == "projects" || arr[0] == "users") {
+		org := arr[1]
+		if arr[0] == "users" {
+			org = "~" + org
+		}
+		arr[1] = org
+		arr = arr[1:]
+		if len(arr) >= 2 && arr[1] == "repos" {
+			arr = append(arr[:1], arr[2:]...)
+		}
+	}
+	if len(arr) >= 2 {
+		info.Organisation = arr[0]
+		info.Project = arr[0]
+		info.Name = strings.TrimSuffix(arr[1], ".git")
+		return info, nil
+	}
+	if len(arr) == 1 && !requireRepo {
+		info.Organisation = arr[0]
+		info.Project = arr[0]
+		return info, nil
+	}
+	return info, fmt.Errorf("invalid Bitbucket Server path %s could not determine organisation and repository name", path)
+}
+
+// splitPath splits the URL path into its non empty segments
+func splitPath(path string) []string {
+	var answer []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			answer = append(answer, s)
+		}
+	}
+	return answer
+}
+
+// isSSHScheme returns true if the URL scheme uses ssh
+func isSSHScheme(scheme string) bool {
+	switch scheme {
+	case "ssh", "git+ssh", "ssh+git":
+		return true
+	default:
+		return false
+	}
+}
+
 // HttpCloneURL returns the HTTPS git URL this repository
 func HttpCloneURL(repo *GitRepository, kind string) string {
 	if kind == KindBitBucketServer {
@@ -154,5 +268,9 @@ func HttpCloneURL(repo *GitRepository, kind string) string {
 		return stringhelpers.UrlJoin(host, "scm", repo.Organisation, repo.Name) + ".git"
 
 	}
+	if kind == KindAzure || repo.Kind == KindAzure {
+		// Azure DevOps clone URLs do not use a .git suffix
+		return repo.HttpsURL()
+	}
 	return repo.HttpsURL() + ".git"
 }
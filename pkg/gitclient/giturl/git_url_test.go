@@ -8,6 +8,7 @@ import (
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/giturl"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type parseGitURLData struct {
@@ -242,3 +243,105 @@ func TestGitInfoHttpCloneURL(t *testing.T) {
 		})
 	}
 }
+
+func TestParseGitURLConformance(t *testing.T) {
+	giturl.RegisterServer("https://code.acme.io", giturl.KindGitlab)
+	t.Cleanup(func() {
+		giturl.UnregisterServer("https://code.acme.io")
+	})
+
+	testCases := []struct {
+		url          string
+		kind         string
+		expectedKind string
+		host         string
+		sshPort      string
+		organisation string
+		project      string
+		name         string
+		cloneURL     string
+	}{
+		// GitHub
+		{url: "https://github.com/myorg/myrepo.git", expectedKind: giturl.KindGitHub, host: "github.com", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://github.com/myorg/myrepo.git"},
+		{url: "https://github.com/myorg/myrepo/tree/main/charts", expectedKind: giturl.KindGitHub, host: "github.com", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://github.com/myorg/myrepo.git"},
+		{url: "git@github.com:myorg/myrepo.git", expectedKind: giturl.KindGitHub, host: "github.com", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://github.com/myorg/myrepo.git"},
+		{url: "ssh://git@github.com/myorg/myrepo.git", expectedKind: giturl.KindGitHub, host: "github.com", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://github.com/myorg/myrepo.git"},
+
+		// GitLab
+		{url: "https://gitlab.com/group/sub/myrepo.git", expectedKind: giturl.KindGitlab, host: "gitlab.com", organisation: "group", project: "group", name: "sub/myrepo", cloneURL: "https://gitlab.com/group/sub/myrepo.git"},
+		{url: "https://gitlab.com/group/sub/myrepo/-/merge_requests/12", expectedKind: giturl.KindGitlab, host: "gitlab.com", organisation: "group", project: "group", name: "sub/myrepo", cloneURL: "https://gitlab.com/group/sub/myrepo.git"},
+		{url: "https://git.example.com/group/sub/myrepo.git", kind: giturl.KindGitlab, expectedKind: giturl.KindGitlab, host: "git.example.com", organisation: "group", project: "group", name: "sub/myrepo", cloneURL: "https://git.example.com/group/sub/myrepo.git"},
+		{url: "git@git.example.com:group/sub/myrepo.git", kind: giturl.KindGitlab, expectedKind: giturl.KindGitlab, host: "git.example.com", organisation: "group", project: "group", name: "sub/myrepo", cloneURL: "https://git.example.com/group/sub/myrepo.git"},
+		{url: "ssh://git@git.example.com:2222/group/sub/myrepo.git", kind: giturl.KindGitlab, expectedKind: giturl.KindGitlab, host: "git.example.com", sshPort: "2222", organisation: "group", project: "group", name: "sub/myrepo", cloneURL: "https://git.example.com/group/sub/myrepo.git"},
+		{url: "https://code.acme.io/group/sub/myrepo", expectedKind: giturl.KindGitlab, host: "code.acme.io", organisation: "group", project: "group", name: "sub/myrepo", cloneURL: "https://code.acme.io/group/sub/myrepo.git"},
+		{url: "https://git.example.com/group/sub/myrepo", host: "git.example.com", organisation: "group", project: "group", name: "myrepo", cloneURL: "https://git.example.com/group/myrepo.git"},
+
+		// Azure DevOps
+		{url: "https://dev.azure.com/myorg/myproject/_git/myrepo", expectedKind: giturl.KindAzure, host: "dev.azure.com", organisation: "myorg", project: "myproject", name: "myrepo", cloneURL: "https://dev.azure.com/myorg/myproject/_git/myrepo"},
+		{url: "https://myorg@dev.azure.com/myorg/myproject/_git/myrepo", expectedKind: giturl.KindAzure, host: "dev.azure.com", organisation: "myorg", project: "myproject", name: "myrepo", cloneURL: "https://dev.azure.com/myorg/myproject/_git/myrepo"},
+		{url: "https://dev.azure.com/myorg/myproject/_git/myrepo/pullrequest/5", expectedKind: giturl.KindAzure, host: "dev.azure.com", organisation: "myorg", project: "myproject", name: "myrepo", cloneURL: "https://dev.azure.com/myorg/myproject/_git/myrepo"},
+		{url: "https://dev.azure.com/myorg/_git/myrepo", expectedKind: giturl.KindAzure, host: "dev.azure.com", organisation: "myorg", project: "myrepo", name: "myrepo", cloneURL: "https://dev.azure.com/myorg/myrepo/_git/myrepo"},
+		{url: "git@ssh.dev.azure.com:v3/myorg/myproject/myrepo", expectedKind: giturl.KindAzure, host: "dev.azure.com", organisation: "myorg", project: "myproject", name: "myrepo", cloneURL: "https://dev.azure.com/myorg/myproject/_git/myrepo"},
+		{url: "https://myorg.visualstudio.com/myproject/_git/myrepo", expectedKind: giturl.KindAzure, host: "myorg.visualstudio.com", organisation: "myorg", project: "myproject", name: "myrepo", cloneURL: "https://myorg.visualstudio.com/myproject/_git/myrepo"},
+		{url: "https://myorg.visualstudio.com/DefaultCollection/myproject/_git/myrepo", expectedKind: giturl.KindAzure, host: "myorg.visualstudio.com", organisation: "myorg", project: "myproject", name: "myrepo", cloneURL: "https://myorg.visualstudio.com/myproject/_git/myrepo"},
+		{url: "myorg@vs-ssh.visualstudio.com:v3/myorg/myproject/myrepo", expectedKind: giturl.KindAzure, host: "dev.azure.com", organisation: "myorg", project: "myproject", name: "myrepo", cloneURL: "https://dev.azure.com/myorg/myproject/_git/myrepo"},
+
+		// Gitea
+		{url: "https://gitea.example.com/myorg/myrepo.git", kind: giturl.KindGitea, expectedKind: giturl.KindGitea, host: "gitea.example.com", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://gitea.example.com/myorg/myrepo.git"},
+		{url: "https://gitea.example.com/myorg/myrepo/src/branch/main/charts", kind: giturl.KindGitea, expectedKind: giturl.KindGitea, host: "gitea.example.com", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://gitea.example.com/myorg/myrepo.git"},
+		{url: "ssh://git@gitea.example.com:2222/myorg/myrepo.git", kind: giturl.KindGitea, expectedKind: giturl.KindGitea, host: "gitea.example.com", sshPort: "2222", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://gitea.example.com/myorg/myrepo.git"},
+
+		// Bitbucket Server
+		{url: "https://bbs.example.com/scm/proj/myrepo.git", kind: giturl.KindBitBucketServer, expectedKind: giturl.KindBitBucketServer, host: "bbs.example.com", organisation: "proj", project: "proj", name: "myrepo", cloneURL: "https://bbs.example.com/scm/proj/myrepo.git"},
+		{url: "https://bbs.example.com/projects/PROJ/repos/myrepo/browse", kind: giturl.KindBitBucketServer, expectedKind: giturl.KindBitBucketServer, host: "bbs.example.com", organisation: "PROJ", project: "PROJ", name: "myrepo", cloneURL: "https://bbs.example.com/scm/PROJ/myrepo.git"},
+		{url: "https://bbs.example.com/projects/PROJ/repos/myrepo/pull-requests/1/overview", kind: giturl.KindBitBucketServer, expectedKind: giturl.KindBitBucketServer, host: "bbs.example.com", organisation: "PROJ", project: "PROJ", name: "myrepo", cloneURL: "https://bbs.example.com/scm/PROJ/myrepo.git"},
+		{url: "https://bbs.example.com/users/jdoe/repos/myrepo/browse", kind: giturl.KindBitBucketServer, expectedKind: giturl.KindBitBucketServer, host: "bbs.example.com", organisation: "~jdoe", project: "~jdoe", name: "myrepo", cloneURL: "https://bbs.example.com/scm/~jdoe/myrepo.git"},
+		{url: "ssh://git@bbs.example.com:7999/proj/myrepo.git", kind: giturl.KindBitBucketServer, expectedKind: giturl.KindBitBucketServer, host: "bbs.example.com", sshPort: "7999", organisation: "proj", project: "proj", name: "myrepo", cloneURL: "https://bbs.example.com/scm/proj/myrepo.git"},
+
+		// Bitbucket Cloud
+		{url: "https://bitbucket.org/myorg/myrepo/src/main/", expectedKind: giturl.KindBitBucketCloud, host: "bitbucket.org", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://bitbucket.org/myorg/myrepo.git"},
+		{url: "git@bitbucket.org:myorg/myrepo.git", expectedKind: giturl.KindBitBucketCloud, host: "bitbucket.org", organisation: "myorg", project: "myorg", name: "myrepo", cloneURL: "https://bitbucket.org/myorg/myrepo.git"},
+	}
+	for _, tc := range testCases {
+		info, err := giturl.ParseGitURLWithOptions(tc.url, &giturl.ParseOptions{Kind: tc.kind})
+		require.NoError(t, err, "failed to parse %s", tc.url)
+		assert.Equal(t, tc.expectedKind, info.Kind, "Kind for input %s", tc.url)
+		assert.Equal(t, tc.host, info.Host, "Host for input %s", tc.url)
+		assert.Equal(t, tc.sshPort, info.SSHPort, "SSHPort for input %s", tc.url)
+		assert.Equal(t, tc.organisation, info.Organisation, "Organisation for input %s", tc.url)
+		assert.Equal(t, tc.project, info.Project, "Project for input %s", tc.url)
+		assert.Equal(t, tc.name, info.Name, "Name for input %s", tc.url)
+		assert.Equal(t, tc.cloneURL, giturl.HttpCloneURL(info, info.Kind), "HttpCloneURL for input %s", tc.url)
+	}
+}
+
+func TestParseGitURLInsteadOf(t *testing.T) {
+	t.Parallel()
+	insteadOf := map[string]string{
+		"gh:":                               "https://github.com/",
+		"https://git.example.com/":          "ssh://git@git.example.com:2222/",
+		"https://git.example.com/mirrored/": "https://mirror.example.com/",
+	}
+	testCases := []struct {
+		url          string
+		expected     string
+		host         string
+		organisation string
+		name         string
+	}{
+		{url: "gh:myorg/myrepo", expected: "https://github.com/myorg/myrepo", host: "github.com", organisation: "myorg", name: "myrepo"},
+		{url: "https://git.example.com/myorg/myrepo.git", expected: "ssh://git@git.example.com:2222/myorg/myrepo.git", host: "git.example.com", organisation: "myorg", name: "myrepo"},
+		{url: "https://git.example.com/mirrored/myorg/myrepo.git", expected: "https://mirror.example.com/myorg/myrepo.git", host: "mirror.example.com", organisation: "myorg", name: "myrepo"},
+		{url: "https://other.example.com/myorg/myrepo.git", expected: "https://other.example.com/myorg/myrepo.git", host: "other.example.com", organisation: "myorg", name: "myrepo"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, giturl.RewriteURL(tc.url, insteadOf), "RewriteURL for input %s", tc.url)
+
+		info, err := giturl.ParseGitURLWithOptions(tc.url, &giturl.ParseOptions{InsteadOf: insteadOf})
+		require.NoError(t, err, "failed to parse %s", tc.url)
+		assert.Equal(t, tc.expected, info.URL, "URL for input %s", tc.url)
+		assert.Equal(t, tc.host, info.Host, "Host for input %s", tc.url)
+		assert.Equal(t, tc.organisation, info.Organisation, "Organisation for input %s", tc.url)
+		assert.Equal(t, tc.name, info.Name, "Name for input %s", tc.url)
+	}
+}
@@ -28,6 +28,10 @@ type GitRepository struct {
 	HasWiki          bool
 	HasProjects      bool
 	Archived         bool
+	// Kind the git kind of the server if it is known
+	Kind string
+	// SSHPort the port of a ssh URL if it is not the default port
+	SSHPort string
 }
 
 func (i *GitRepository) IsGitHub() bool {
@@ -45,7 +49,7 @@ func (i *GitRepository) HttpURL() string {
 	if !strings.Contains(host, ":/") {
 		host = "http://" + host
 	}
-	return stringhelpers.UrlJoin(host, i.Organisation, i.Name)
+	return i.browseURL(host)
 }
 
 // HttpsURL returns the URL to browse this repository in a web browser
@@ -54,7 +58,18 @@ func (i *GitRepository) HttpsURL() string {
 	if !strings.Contains(host, ":/") {
 		host = "https://" + host
 	}
-	return stringhelpers.UrlJoin(host, i.Organisation, i.Name)
+	return i.browseURL(host)
+}
+
+// browseURL returns the URL to browse this repository on the host URL
+func (i *GitRepository) browseURL(hostURL string) string {
+	if i.Kind == KindAzure {
+		if visualStudioOrganisation(i.Host) != "" {
+			return stringhelpers.UrlJoin(hostURL, i.Project, "_git", i.Name)
+		}
+		return stringhelpers.UrlJoin(hostURL, i.Organisation, i.Project, "_git", i.Name)
+	}
+	return stringhelpers.UrlJoin(hostURL, i.Organisation, i.Name)
 }
 
 // HostURL returns the URL to the host
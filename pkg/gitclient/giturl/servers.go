@@ -0,0 +1,109 @@
+package giturl
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	serversLock sync.RWMutex
+
+	// servers the git kinds of the registered self-hosted git servers indexed by host
+	servers = map[string]string{}
+
+	// saasHosts the git kinds of the well known SaaS git hosts
+	saasHosts = map[string]string{
+		GitHubHost:                KindGitHub,
+		"gitlab.com":              KindGitlab,
+		"bitbucket.org":           KindBitBucketCloud,
+		AzureDevOpsHost:           KindAzure,
+		"ssh.dev.azure.com":       KindAzure,
+		"vs-ssh.visualstudio.com": KindAzure,
+		"fake.git":                KindGitFake,
+	}
+)
+
+// RegisterServer registers the git kind of a self-hosted git server such as a GitLab server which does not have
+// gitlab in its host name so that URLs on the server are parsed correctly. The server is a URL or host name
+func RegisterServer(server, kind string) {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+
+	servers[serverHost(server)] = kind
+}
+
+// UnregisterServer removes a server registered via RegisterServer
+func UnregisterServer(server string) {
+	serversLock.Lock()
+	defer serversLock.Unlock()
+
+	delete(servers, serverHost(server))
+}
+
+// ServerKind returns the git kind of the host from the registered servers, the well known SaaS hosts or
+// by guessing from the host name. An empty string is returned if the kind is unknown
+func ServerKind(host string) string {
+	return serverKind(host, "")
+}
+
+// serverKind returns the kind hint if it is not empty or the kind of the host
+func serverKind(host, kind string) string {
+	if kind != "" {
+		return kind
+	}
+	host = serverHost(host)
+
+	serversLock.RLock()
+	kind = servers[host]
+	if kind == "" {
+		// lets try without the port
+		kind = servers[stripPort(host)]
+	}
+	serversLock.RUnlock()
+	if kind != "" {
+		return kind
+	}
+
+	hostname := stripPort(host)
+	if kind = saasHosts[hostname]; kind != "" {
+		return kind
+	}
+	switch {
+	case visualStudioOrganisation(hostname) != "":
+		return KindAzure
+	// Dont do exact match on gitlab.com as there can be custom gitlab domains
+	case strings.Contains(hostname, "gitlab"):
+		return KindGitlab
+	}
+	return ""
+}
+
+// serverHost returns the lower case host and port of the server URL or host name
+func serverHost(server string) string {
+	server = strings.ToLower(strings.TrimSpace(server))
+	if strings.Contains(server, "://") {
+		u, err := url.Parse(server)
+		if err == nil {
+			return u.Host
+		}
+	}
+	return strings.TrimSuffix(server, "/")
+}
+
+// stripPort removes any port from the host
+func stripPort(host string) string {
+	u := url.URL{Host: host}
+	return u.Hostname()
+}
+
+// visualStudioOrganisation returns the organisation of a legacy <org>.visualstudio.com Azure DevOps host or an
+// empty string if the host is not a legacy Azure DevOps host
+func visualStudioOrganisation(host string) string {
+	hostname := stripPort(host)
+	org := strings.TrimSuffix(hostname, ".visualstudio.com")
+	if org == hostname || org == "" || org == "vs-ssh" || strings.Contains(org, ".") {
+		return ""
+	}
+	return org
+}
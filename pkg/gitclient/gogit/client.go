@@ -0,0 +1,212 @@
+package gogit
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// AuthFunc returns the authentication to use for the given git URL or nil if none is required
+type AuthFunc func(gitURL string) (transport.AuthMethod, error)
+
+type client struct {
+	auth AuthFunc
+}
+
+// NewGoGitClient creates a new in-process git client backed by go-git which supports the git sub commands
+// used by the gitclient helpers without requiring a git binary.
+// If no auth function is supplied then any user and password in the git URL are used
+func NewGoGitClient(auth AuthFunc) *client {
+	if auth == nil {
+		auth = URLUserInfoAuth
+	}
+	return &client{
+		auth: auth,
+	}
+}
+
+// Command runs the git sub command such as 'commit' or 'clone' in the given directory with the arguments
+func (c *client) Command(dir string, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("no git sub command specified")
+	}
+	if dir == "" {
+		var err error
+		dir, err = os.Getwd()
+		if err != nil {
+			return "", fmt.Errorf("failed to get the current directory: %w", err)
+		}
+	}
+	subCommand := args[0]
+	args = args[1:]
+	switch subCommand {
+	case "init":
+		return c.init(dir, args)
+	case "clone":
+		return c.clone(dir, args)
+	case "fetch":
+		return c.fetch(dir, args)
+	case "checkout":
+		return c.checkout(dir, args)
+	case "add":
+		return c.add(dir, args)
+	case "commit":
+		return c.commit(dir, args)
+	case "push":
+		return c.push(dir, args)
+	case "tag":
+		return c.tag(dir, args)
+	case "status":
+		return c.status(dir, args)
+	case "rev-parse":
+		return c.revParse(dir, args)
+	case "log":
+		return c.log(dir, args)
+	case "remote":
+		return c.remote(dir, args)
+	default:
+		return "", UnsupportedError{Command: subCommand}
+	}
+}
+
+// UnsupportedError is returned for git sub commands or arguments which are not supported by the go-git client
+type UnsupportedError struct {
+	// Command the git sub command
+	Command string
+	// Argument the unsupported argument if the sub command is supported
+	Argument string
+}
+
+func (e UnsupportedError) Error() string {
+	if e.Argument != "" {
+		return fmt.Sprintf("the argument '%s' of 'git %s' is not supported by the go-git client", e.Argument, e.Command)
+	}
+	return fmt.Sprintf("'git %s' is not supported by the go-git client", e.Command)
+}
+
+// IsUnsupported returns true if the error is caused by an unsupported git sub command or argument
+func IsUnsupported(err error) bool {
+	var unsupported UnsupportedError
+	return errors.As(err, &unsupported)
+}
+
+// URLUserInfoAuth returns basic authentication for any user and password in the given http(s) git URL
+func URLUserInfoAuth(gitURL string) (transport.AuthMethod, error) {
+	u, err := url.Parse(gitURL)
+	if err != nil || u.User == nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, nil
+	}
+	password, _ := u.User.Password()
+	return &http.BasicAuth{
+		Username: u.User.Username(),
+		Password: password,
+	}, nil
+}
+
+// openRepository opens the repository containing the given directory
+func openRepository(dir string) (*git.Repository, error) {
+	r, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open git repository in dir %s: %w", dir, err)
+	}
+	return r, nil
+}
+
+// openWorktree opens the repository and work tree containing the given directory
+func openWorktree(dir string) (*git.Repository, *git.Worktree, error) {
+	r, err := openRepository(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := r.Worktree()
+	if err != nil {
+		return r, nil, fmt.Errorf("failed to open the work tree in dir %s: %w", dir, err)
+	}
+	return r, w, nil
+}
+
+// resolvePath resolves the path relative to the given directory
+func resolvePath(dir, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// flagSpec describes the names of a flag and whether it takes a value
+type flagSpec struct {
+	name  string
+	value bool
+	names []string
+}
+
+func boolFlag(name string, names ...string) flagSpec {
+	return flagSpec{name: name, names: names}
+}
+
+func valueFlag(name string, names ...string) flagSpec {
+	return flagSpec{name: name, value: true, names: names}
+}
+
+// parsedArgs the flags, positional arguments and paths after any '--' of a sub command
+type parsedArgs struct {
+	flags      map[string][]string
+	positional []string
+	paths      []string
+}
+
+func (a *parsedArgs) has(name string) bool {
+	_, ok := a.flags[name]
+	return ok
+}
+
+func (a *parsedArgs) value(name string) string {
+	values := a.flags[name]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[len(values)-1]
+}
+
+// parseArgs parses the arguments of the sub command returning an UnsupportedError for any unknown flags
+func parseArgs(command string, args []string, specs ...flagSpec) (*parsedArgs, error) {
+	answer := &parsedArgs{flags: map[string][]string{}}
+	lookup := map[string]flagSpec{}
+	for _, s := range specs {
+		for _, n := range s.names {
+			lookup[n] = s
+		}
+	}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			answer.paths = append(answer.paths, args[i+1:]...)
+			break
+		}
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			answer.positional = append(answer.positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		s, ok := lookup[name]
+		if !ok {
+			return nil, UnsupportedError{Command: command, Argument: name}
+		}
+		if s.value && !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("missing value for argument %s of git %s", name, command)
+			}
+			i++
+			value = args[i]
+		}
+		answer.flags[s.name] = append(answer.flags[s.name], value)
+	}
+	return answer, nil
+}
@@ -0,0 +1,271 @@
+//go:build unit
+// +build unit
+
+package gogit_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gogit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoGitClient(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "jenkins-x@googlegroups.com")
+
+	var g gitclient.Interface = gogit.NewGoGitClient(nil)
+
+	tmpDir := t.TempDir()
+	dir := filepath.Join(tmpDir, "source")
+	require.NoError(t, os.MkdirAll(dir, 0o755))
+
+	_, err := g.Command(dir, "init", "-b", "main")
+	require.NoError(t, err, "failed to init")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello\n"), 0o600))
+
+	out, err := g.Command(dir, "status", "-s")
+	require.NoError(t, err, "failed to get status")
+	assert.Equal(t, "?? README.md", out, "status")
+
+	changes, err := gitclient.HasChanges(g, dir)
+	require.NoError(t, err, "failed to check changes")
+	assert.True(t, changes, "should have changes")
+
+	err = gitclient.Add(g, dir, "README.md")
+	require.NoError(t, err, "failed to add")
+
+	out, err = g.Command(dir, "status", "-s")
+	require.NoError(t, err, "failed to get status")
+	assert.Equal(t, "A  README.md", out, "status")
+
+	err = gitclient.CommitIfChanges(g, dir, "initial commit")
+	require.NoError(t, err, "failed to commit")
+
+	changes, err = gitclient.HasChanges(g, dir)
+	require.NoError(t, err, "failed to check changes")
+	assert.False(t, changes, "should not have changes after commit")
+
+	branch, err := gitclient.Branch(g, dir)
+	require.NoError(t, err, "failed to get branch")
+	assert.Equal(t, "main", branch, "branch")
+
+	_, err = g.Command(dir, "tag", "v1.0.0")
+	require.NoError(t, err, "failed to tag")
+	_, err = g.Command(dir, "tag", "-a", "v1.1.0", "-m", "release 1.1.0")
+	require.NoError(t, err, "failed to create annotated tag")
+
+	out, err = g.Command(dir, "tag", "-l", "v1.*")
+	require.NoError(t, err, "failed to list tags")
+	assert.Equal(t, "v1.0.0\nv1.1.0", out, "tags")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("hello world\n"), 0o600))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "fix: update readme\n\nsome more detail")
+	require.NoError(t, err, "failed to commit")
+
+	sha, err := gitclient.GetLatestCommitSha(g, dir)
+	require.NoError(t, err, "failed to get latest sha")
+	assert.Len(t, sha, 40, "sha")
+
+	out, err = g.Command(dir, "rev-parse", "HEAD")
+	require.NoError(t, err, "failed to rev-parse")
+	assert.Equal(t, sha, out, "rev-parse HEAD")
+
+	out, err = g.Command(dir, "rev-parse", "--short", "HEAD")
+	require.NoError(t, err, "failed to rev-parse")
+	assert.Equal(t, sha[:7], out, "rev-parse --short HEAD")
+
+	message, err := gitclient.GetLatestCommitMessage(g, dir)
+	require.NoError(t, err, "failed to get latest message")
+	assert.Equal(t, "fix: update readme\n\nsome more detail", message, "latest commit message")
+
+	out, err = g.Command(dir, "log")
+	require.NoError(t, err, "failed to get log")
+	commits := gitlog.ParseGitLog(out)
+	require.Len(t, commits, 2, "commits")
+	assert.Equal(t, sha, commits[0].SHA, "first commit SHA")
+	assert.Equal(t, "jenkins-x-bot <jenkins-x@googlegroups.com>", commits[0].Author, "author")
+	assert.Equal(t, "fix: update readme\n\nsome more detail\n", commits[0].Comment, "comment")
+	assert.Equal(t, "initial commit", commits[1].Comment, "comment")
+
+	out, err = g.Command(dir, "log", "--pretty=format:%s", "v1.0.0..HEAD")
+	require.NoError(t, err, "failed to get log range")
+	assert.Equal(t, "fix: update readme", out, "log range")
+
+	out, err = g.Command(dir, "log", "-1", "--reverse", "--format=%an|%ae")
+	require.NoError(t, err, "failed to get log")
+	assert.Equal(t, "jenkins-x-bot|jenkins-x@googlegroups.com", out, "log format")
+
+	// lets push to a bare repository and clone it
+	bareDir := filepath.Join(tmpDir, "bare.git")
+	_, err = g.Command(tmpDir, "init", "--bare", bareDir)
+	assert.True(t, gogit.IsUnsupported(err), "should not support bare repositories: %v", err)
+
+	remoteDir := filepath.Join(tmpDir, "remote")
+	require.NoError(t, os.MkdirAll(remoteDir, 0o755))
+	_, err = g.Command(remoteDir, "init", "-b", "other")
+	require.NoError(t, err, "failed to init remote")
+
+	err = gitclient.AddRemote(g, dir, "origin", remoteDir)
+	require.NoError(t, err, "failed to add remote")
+
+	out, err = g.Command(dir, "remote", "get-url", "origin")
+	require.NoError(t, err, "failed to get remote URL")
+	assert.Equal(t, remoteDir, out, "remote URL")
+
+	_, err = g.Command(dir, "push", "-u", "origin", "HEAD", "--tags")
+	require.NoError(t, err, "failed to push")
+
+	out, err = g.Command(dir, "rev-parse", "--abbrev-ref", "@{u}")
+	require.NoError(t, err, "failed to get upstream")
+	assert.Equal(t, "origin/main", out, "upstream")
+
+	cloneDir := filepath.Join(tmpDir, "clone")
+	_, err = g.Command(tmpDir, "clone", "-b", "main", remoteDir, cloneDir)
+	require.NoError(t, err, "failed to clone")
+
+	data, err := os.ReadFile(filepath.Join(cloneDir, "README.md"))
+	require.NoError(t, err, "failed to read cloned file")
+	assert.Equal(t, "hello world\n", string(data), "cloned README.md")
+
+	_, err = g.Command(tmpDir, "clone", "--filter=blob:none", remoteDir, filepath.Join(tmpDir, "partial"))
+	assert.True(t, gogit.IsUnsupported(err), "should not support partial clones: %v", err)
+
+	cloneSha, err := gitclient.GetLatestCommitSha(g, cloneDir)
+	require.NoError(t, err, "failed to get latest sha of clone")
+	assert.Equal(t, sha, cloneSha, "cloned sha")
+
+	_, err = g.Command(cloneDir, "checkout", "-b", "feature")
+	require.NoError(t, err, "failed to create branch")
+	branch, err = gitclient.Branch(g, cloneDir)
+	require.NoError(t, err, "failed to get branch")
+	assert.Equal(t, "feature", branch, "branch")
+
+	_, err = g.Command(dir, "rebase", "origin/main")
+	require.Error(t, err, "rebase should not be supported")
+	assert.True(t, gogit.IsUnsupported(err), "should be an unsupported error: %v", err)
+}
+
+func TestGoGitStatusMatchesGit(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "jenkins-x@googlegroups.com")
+
+	gitCLI := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	var g gitclient.Interface = gogit.NewGoGitClient(nil)
+
+	tmpDir := t.TempDir()
+	remoteDir := filepath.Join(tmpDir, "remote")
+	require.NoError(t, os.MkdirAll(remoteDir, 0o755))
+	_, err := gitCLI.Command(remoteDir, "init", "-b", "main")
+	require.NoError(t, err)
+	writeFiles(t, remoteDir, map[string]string{"README.md": "hello\n", "src/a.txt": "a\n", "src/b.txt": "b\n"})
+	_, err = gitclient.AddAndCommitFiles(gitCLI, remoteDir, "initial commit")
+	require.NoError(t, err)
+
+	dir := filepath.Join(tmpDir, "local")
+	_, err = gitCLI.Command(tmpDir, "clone", remoteDir, dir)
+	require.NoError(t, err)
+
+	writeFiles(t, remoteDir, map[string]string{"README.md": "hello remote\n"})
+	_, err = gitclient.AddAndCommitFiles(gitCLI, remoteDir, "remote change")
+	require.NoError(t, err)
+	_, err = gitCLI.Command(dir, "fetch")
+	require.NoError(t, err)
+
+	writeFiles(t, dir, map[string]string{"src/a.txt": "a local\n"})
+	_, err = gitclient.AddAndCommitFiles(gitCLI, dir, "local change")
+	require.NoError(t, err)
+
+	writeFiles(t, dir, map[string]string{
+		"README.md":         "staged\n",
+		"added.txt":         "added\n",
+		"src/a.txt":         "not staged\n",
+		"newdir/one.txt":    "one\n",
+		"newdir/sub/two.md": "two\n",
+		"src/untracked.txt": "untracked\n",
+	})
+	require.NoError(t, gitclient.Add(gitCLI, dir, "README.md", "added.txt"))
+	require.NoError(t, os.Remove(filepath.Join(dir, "src", "b.txt")))
+
+	for _, args := range [][]string{
+		{"status", "--porcelain=v2", "--branch", "-z", "--untracked-files=normal"},
+		{"status", "--porcelain=v2", "--branch", "-z", "--untracked-files=all"},
+		{"status", "--porcelain=v2", "--untracked-files=no"},
+		{"status", "--porcelain=v2", "--", "src"},
+		{"status", "--porcelain", "-b"},
+		{"status", "-s", "-z"},
+		{"status", "-s", "src"},
+		{"status"},
+		{"status", "--untracked-files=no"},
+	} {
+		expected, err := gitCLI.Command(dir, args...)
+		require.NoError(t, err, "git %v", args)
+		actual, err := g.Command(dir, args...)
+		require.NoError(t, err, "go-git %v", args)
+		// the command runner trims the output of the git binary
+		assert.Equal(t, expected, strings.TrimSpace(actual), "git %v", args)
+	}
+
+	// the long format shows paths relative to the current directory
+	expected, err := gitCLI.Command(filepath.Join(dir, "src"), "status")
+	require.NoError(t, err)
+	actual, err := g.Command(filepath.Join(dir, "src"), "status")
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual, "long status in a sub directory")
+
+	status, err := gitclient.GetStatus(g, dir, nil)
+	require.NoError(t, err, "failed to get status")
+	assert.Equal(t, "main", status.Branch.Head, "head")
+	assert.Equal(t, "origin/main", status.Branch.Upstream, "upstream")
+	assert.Equal(t, 1, status.Branch.Ahead, "ahead")
+	assert.Equal(t, 1, status.Branch.Behind, "behind")
+	assert.Len(t, status.Entries, 7, "entries")
+
+	_, err = gitclient.GetStatus(g, dir, &gitclient.StatusOptions{Ignored: true})
+	assert.True(t, gogit.IsUnsupported(err), "should not support ignored files: %v", err)
+
+	newDir := filepath.Join(tmpDir, "new")
+	require.NoError(t, os.MkdirAll(newDir, 0o755))
+	_, err = gitCLI.Command(newDir, "init", "-b", "main")
+	require.NoError(t, err)
+	assertSameLongStatus := func(message string) {
+		expected, err := gitCLI.Command(newDir, "status")
+		require.NoError(t, err)
+		actual, err := g.Command(newDir, "status")
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual, message)
+	}
+	assertSameLongStatus("empty repository")
+	writeFiles(t, newDir, map[string]string{"README.md": "hello\n"})
+	assertSameLongStatus("untracked file without commits")
+	require.NoError(t, gitclient.Add(gitCLI, newDir, "README.md"))
+	assertSameLongStatus("staged file without commits")
+	_, err = gitCLI.Command(newDir, "commit", "-m", "initial commit")
+	require.NoError(t, err)
+	assertSameLongStatus("clean repository")
+
+	out, err := gitclient.Status(g, dir)
+	require.NoError(t, err)
+	assert.Contains(t, out, "Changes to be committed:", "status of the helper")
+}
+
+func writeFiles(t *testing.T, dir string, fileContents map[string]string) {
+	for name, content := range fileContents {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+}
@@ -0,0 +1,844 @@
+package gogit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func (c *client) init(dir string, args []string) (string, error) {
+	a, err := parseArgs("init", args,
+		valueFlag("branch", "-b", "--initial-branch"),
+		boolFlag("quiet", "-q", "--quiet"),
+	)
+	if err != nil {
+		return "", err
+	}
+	target := dir
+	if len(a.positional) > 0 {
+		target = resolvePath(dir, a.positional[0])
+	}
+	opts := &git.PlainInitOptions{}
+	if b := a.value("branch"); b != "" {
+		opts.InitOptions.DefaultBranch = plumbing.NewBranchReferenceName(b)
+	}
+	_, err = git.PlainInitWithOptions(target, opts)
+	if err != nil && !errors.Is(err, git.ErrRepositoryAlreadyExists) {
+		return "", fmt.Errorf("failed to init git repository in %s: %w", target, err)
+	}
+	return fmt.Sprintf("Initialized empty Git repository in %s", filepath.Join(target, git.GitDirName)), nil
+}
+
+func (c *client) clone(dir string, args []string) (string, error) {
+	a, err := parseArgs("clone", args,
+		valueFlag("depth", "--depth"),
+		valueFlag("branch", "-b", "--branch"),
+		valueFlag("origin", "-o", "--origin"),
+		boolFlag("single-branch", "--single-branch"),
+		boolFlag("no-checkout", "-n", "--no-checkout"),
+		boolFlag("quiet", "-q", "--quiet"),
+	)
+	if err != nil {
+		return "", err
+	}
+	if len(a.positional) == 0 {
+		return "", fmt.Errorf("no repository specified to clone")
+	}
+	gitURL := a.positional[0]
+	var target string
+	if len(a.positional) > 1 {
+		target = resolvePath(dir, a.positional[1])
+	} else {
+		target = filepath.Join(dir, strings.TrimSuffix(path.Base(strings.TrimSuffix(gitURL, "/")), ".git"))
+	}
+	auth, err := c.auth(gitURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the credentials for %s: %w", gitURL, err)
+	}
+	opts := &git.CloneOptions{
+		URL:          gitURL,
+		Auth:         auth,
+		RemoteName:   a.value("origin"),
+		SingleBranch: a.has("single-branch"),
+		NoCheckout:   a.has("no-checkout"),
+	}
+	if d := a.value("depth"); d != "" {
+		opts.Depth, err = strconv.Atoi(d)
+		if err != nil {
+			return "", fmt.Errorf("invalid depth %s: %w", d, err)
+		}
+		// git implies --single-branch when using --depth
+		opts.SingleBranch = true
+	}
+	if b := a.value("branch"); b != "" {
+		opts.ReferenceName = plumbing.NewBranchReferenceName(b)
+	}
+	_, err = git.PlainClone(target, false, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to clone %s to %s: %w", gitURL, target, err)
+	}
+	return fmt.Sprintf("Cloning into '%s'...", target), nil
+}
+
+func (c *client) fetch(dir string, args []string) (string, error) {
+	a, err := parseArgs("fetch", args,
+		valueFlag("depth", "--depth"),
+		boolFlag("tags", "-t", "--tags"),
+		boolFlag("prune", "-p", "--prune"),
+		boolFlag("force", "-f", "--force"),
+		boolFlag("quiet", "-q", "--quiet"),
+	)
+	if err != nil {
+		return "", err
+	}
+	r, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	remoteName := git.DefaultRemoteName
+	remoteURL := ""
+	if len(a.positional) > 0 {
+		remoteName = a.positional[0]
+		if isURL(remoteName) {
+			remoteURL = remoteName
+			remoteName = git.DefaultRemoteName
+		}
+	}
+	if remoteURL == "" {
+		remoteURL, err = c.remoteURL(r, remoteName)
+		if err != nil {
+			return "", err
+		}
+	}
+	auth, err := c.auth(remoteURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the credentials for %s: %w", remoteURL, err)
+	}
+	opts := &git.FetchOptions{
+		RemoteName: remoteName,
+		RemoteURL:  remoteURL,
+		Auth:       auth,
+		Prune:      a.has("prune"),
+		Force:      a.has("force"),
+	}
+	if a.has("tags") {
+		opts.Tags = git.AllTags
+	}
+	if d := a.value("depth"); d != "" {
+		opts.Depth, err = strconv.Atoi(d)
+		if err != nil {
+			return "", fmt.Errorf("invalid depth %s: %w", d, err)
+		}
+	}
+	for _, refspec := range a.positional[1:] {
+		opts.RefSpecs = append(opts.RefSpecs, fetchRefSpec(remoteName, refspec))
+	}
+	if a.has("tags") && len(opts.RefSpecs) == 0 {
+		opts.RefSpecs = append(opts.RefSpecs, config.RefSpec("+refs/tags/*:refs/tags/*"))
+	}
+	err = r.Fetch(opts)
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", fmt.Errorf("failed to fetch from %s: %w", remoteName, err)
+	}
+	return "", nil
+}
+
+// fetchRefSpec converts the refspec argument of git fetch into a full refspec
+func fetchRefSpec(remoteName, refspec string) config.RefSpec {
+	if strings.Contains(refspec, ":") {
+		src, dst, _ := strings.Cut(refspec, ":")
+		return config.RefSpec(fullRefName(src, "refs/heads/") + ":" + fullRefName(dst, "refs/heads/"))
+	}
+	force := strings.HasPrefix(refspec, "+")
+	name := strings.TrimPrefix(refspec, "+")
+	src := fullRefName(name, "refs/heads/")
+	dst := src
+	if strings.HasPrefix(src, "refs/heads/") {
+		dst = "refs/remotes/" + remoteName + "/" + strings.TrimPrefix(src, "refs/heads/")
+	}
+	answer := src + ":" + dst
+	if force || dst != src {
+		answer = "+" + answer
+	}
+	return config.RefSpec(answer)
+}
+
+// fullRefName returns the full reference name adding the given prefix if the name is not already a full reference
+func fullRefName(name, prefix string) string {
+	force := strings.HasPrefix(name, "+")
+	name = strings.TrimPrefix(name, "+")
+	if name != "" && !strings.HasPrefix(name, "refs/") && name != "HEAD" {
+		name = prefix + name
+	}
+	if force {
+		return "+" + name
+	}
+	return name
+}
+
+func (c *client) checkout(dir string, args []string) (string, error) {
+	a, err := parseArgs("checkout", args,
+		valueFlag("branch", "-b"),
+		valueFlag("force-branch", "-B"),
+		boolFlag("track", "-t", "--track"),
+		boolFlag("force", "-f", "--force"),
+		boolFlag("quiet", "-q", "--quiet"),
+	)
+	if err != nil {
+		return "", err
+	}
+	r, w, err := openWorktree(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(a.paths) > 0 {
+		err = w.Restore(&git.RestoreOptions{Worktree: true, Files: worktreePaths(w, dir, a.paths)})
+		if err != nil {
+			return "", fmt.Errorf("failed to checkout paths %s: %w", strings.Join(a.paths, " "), err)
+		}
+		return "", nil
+	}
+
+	force := a.has("force")
+	branch := a.value("branch")
+	if b := a.value("force-branch"); b != "" {
+		branch = b
+		force = true
+	}
+	startPoint := ""
+	if len(a.positional) > 0 {
+		startPoint = a.positional[0]
+	}
+	if branch == "" && a.has("track") {
+		// lets default the branch name from the remote tracking branch
+		_, branch, _ = strings.Cut(startPoint, "/")
+	}
+
+	if branch != "" {
+		if startPoint == "" {
+			startPoint = "HEAD"
+		}
+		hash, err := r.ResolveRevision(plumbing.Revision(startPoint))
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", startPoint, err)
+		}
+		refName := plumbing.NewBranchReferenceName(branch)
+		if force {
+			err = r.Storer.SetReference(plumbing.NewHashReference(refName, *hash))
+			if err != nil {
+				return "", fmt.Errorf("failed to reset branch %s: %w", branch, err)
+			}
+		}
+		err = w.Checkout(&git.CheckoutOptions{Branch: refName, Hash: *hash, Create: !force, Keep: true})
+		if err != nil {
+			return "", fmt.Errorf("failed to checkout new branch %s: %w", branch, err)
+		}
+		if a.has("track") {
+			err = setTracking(r, branch, startPoint)
+			if err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("Switched to a new branch '%s'", branch), nil
+	}
+
+	if startPoint == "" {
+		return "", nil
+	}
+	opts := &git.CheckoutOptions{Force: a.has("force"), Keep: !a.has("force")}
+	localRef := plumbing.NewBranchReferenceName(startPoint)
+	remoteRef := plumbing.NewRemoteReferenceName(git.DefaultRemoteName, startPoint)
+	if _, err = r.Reference(localRef, false); err == nil {
+		opts.Branch = localRef
+	} else if ref, err := r.Reference(remoteRef, true); err == nil {
+		// lets create a local branch tracking the remote branch like git does
+		opts.Branch = localRef
+		opts.Hash = ref.Hash()
+		opts.Create = true
+		err = w.Checkout(opts)
+		if err != nil {
+			return "", fmt.Errorf("failed to checkout %s: %w", startPoint, err)
+		}
+		err = setTracking(r, startPoint, remoteRef.Short())
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Switched to a new branch '%s'", startPoint), nil
+	} else {
+		hash, err := r.ResolveRevision(plumbing.Revision(startPoint))
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", startPoint, err)
+		}
+		opts.Hash = *hash
+	}
+	err = w.Checkout(opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to checkout %s: %w", startPoint, err)
+	}
+	return fmt.Sprintf("Switched to '%s'", startPoint), nil
+}
+
+// setTracking configures the branch to track the given remote tracking branch such as origin/main
+func setTracking(r *git.Repository, branch, remoteBranch string) error {
+	remote, name, ok := strings.Cut(remoteBranch, "/")
+	if !ok {
+		return nil
+	}
+	cfg, err := r.Config()
+	if err != nil {
+		return fmt.Errorf("failed to load git config: %w", err)
+	}
+	if _, ok := cfg.Remotes[remote]; !ok {
+		return nil
+	}
+	cfg.Branches[branch] = &config.Branch{
+		Name:   branch,
+		Remote: remote,
+		Merge:  plumbing.NewBranchReferenceName(name),
+	}
+	err = r.SetConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to set the upstream of branch %s to %s: %w", branch, remoteBranch, err)
+	}
+	return nil
+}
+
+func (c *client) add(dir string, args []string) (string, error) {
+	a, err := parseArgs("add", args,
+		boolFlag("all", "-A", "--all"),
+		boolFlag("force", "-f", "--force"),
+	)
+	if err != nil {
+		return "", err
+	}
+	_, w, err := openWorktree(dir)
+	if err != nil {
+		return "", err
+	}
+	paths := append(a.positional, a.paths...)
+	if a.has("all") && len(paths) == 0 {
+		paths = []string{"."}
+	}
+	for _, p := range worktreePaths(w, dir, paths) {
+		switch {
+		case p == "." || p == "*":
+			err = w.AddWithOptions(&git.AddOptions{All: true})
+		case strings.ContainsAny(p, "*?["):
+			err = w.AddGlob(p)
+		default:
+			_, err = w.Add(p)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to add %s: %w", p, err)
+		}
+	}
+	return "", nil
+}
+
+// worktreePaths converts the paths relative to the directory into paths relative to the root of the work tree
+func worktreePaths(w *git.Worktree, dir string, paths []string) []string {
+	root := w.Filesystem.Root()
+	var answer []string
+	for _, p := range paths {
+		rel, err := filepath.Rel(root, resolvePath(dir, p))
+		if err != nil {
+			rel = p
+		}
+		answer = append(answer, filepath.ToSlash(rel))
+	}
+	return answer
+}
+
+func (c *client) commit(dir string, args []string) (string, error) {
+	a, err := parseArgs("commit", args,
+		valueFlag("message", "-m", "--message"),
+		boolFlag("all", "-a", "--all"),
+		boolFlag("allow-empty", "--allow-empty"),
+		boolFlag("amend", "--amend"),
+		boolFlag("no-verify", "-n", "--no-verify"),
+		boolFlag("quiet", "-q", "--quiet"),
+	)
+	if err != nil {
+		return "", err
+	}
+	messages := a.flags["message"]
+	if len(messages) == 0 && !a.has("amend") {
+		return "", fmt.Errorf("no commit message specified")
+	}
+	r, w, err := openWorktree(dir)
+	if err != nil {
+		return "", err
+	}
+	opts := &git.CommitOptions{
+		All:               a.has("all"),
+		AllowEmptyCommits: a.has("allow-empty"),
+		Amend:             a.has("amend"),
+		Author:            envSignature("GIT_AUTHOR_NAME", "GIT_AUTHOR_EMAIL"),
+		Committer:         envSignature("GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"),
+	}
+	message := strings.Join(messages, "\n\n")
+	if message == "" {
+		head, err := headCommit(r)
+		if err != nil {
+			return "", err
+		}
+		message = head.Message
+	}
+	hash, err := w.Commit(message, opts)
+	if err != nil {
+		if errors.Is(err, git.ErrEmptyCommit) {
+			return "nothing to commit, working tree clean", fmt.Errorf("nothing to commit, working tree clean: %w", err)
+		}
+		return "", fmt.Errorf("failed to commit: %w", err)
+	}
+	branch := "HEAD"
+	head, err := r.Head()
+	if err == nil && head.Name().IsBranch() {
+		branch = head.Name().Short()
+	}
+	subject, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	return fmt.Sprintf("[%s %s] %s", branch, hash.String()[:7], subject), nil
+}
+
+// envSignature returns the signature from the given environment variables if they are set
+func envSignature(nameEnv, emailEnv string) *object.Signature {
+	name := os.Getenv(nameEnv)
+	email := os.Getenv(emailEnv)
+	if name == "" || email == "" {
+		return nil
+	}
+	return &object.Signature{Name: name, Email: email, When: time.Now()}
+}
+
+func headCommit(r *git.Repository) (*object.Commit, error) {
+	head, err := r.Head()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find HEAD: %w", err)
+	}
+	commit, err := r.CommitObject(head.Hash())
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the HEAD commit %s: %w", head.Hash().String(), err)
+	}
+	return commit, nil
+}
+
+func (c *client) push(dir string, args []string) (string, error) {
+	a, err := parseArgs("push", args,
+		boolFlag("force", "-f", "--force"),
+		boolFlag("tags", "--tags"),
+		boolFlag("set-upstream", "-u", "--set-upstream"),
+		boolFlag("quiet", "-q", "--quiet"),
+	)
+	if err != nil {
+		return "", err
+	}
+	r, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	remoteName := git.DefaultRemoteName
+	remoteURL := ""
+	if len(a.positional) > 0 {
+		remoteName = a.positional[0]
+		if isURL(remoteName) {
+			remoteURL = remoteName
+			remoteName = git.DefaultRemoteName
+		}
+	}
+	if remoteURL == "" {
+		remoteURL, err = c.remoteURL(r, remoteName)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	var refSpecs []config.RefSpec
+	refspecArgs := a.positional
+	if len(refspecArgs) > 0 {
+		refspecArgs = refspecArgs[1:]
+	}
+	if len(refspecArgs) == 0 && !a.has("tags") {
+		refspecArgs = []string{"HEAD"}
+	}
+	for _, refspec := range refspecArgs {
+		rs, err := pushRefSpec(r, refspec, a.has("force"))
+		if err != nil {
+			return "", err
+		}
+		refSpecs = append(refSpecs, rs)
+	}
+	if a.has("tags") {
+		refSpecs = append(refSpecs, config.RefSpec("refs/tags/*:refs/tags/*"))
+	}
+
+	auth, err := c.auth(remoteURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the credentials for %s: %w", remoteURL, err)
+	}
+	err = r.Push(&git.PushOptions{
+		RemoteName: remoteName,
+		RemoteURL:  remoteURL,
+		RefSpecs:   refSpecs,
+		Auth:       auth,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", fmt.Errorf("failed to push to %s: %w", remoteName, err)
+	}
+
+	if a.has("set-upstream") {
+		for _, rs := range refSpecs {
+			src := rs.Src()
+			if plumbing.ReferenceName(src).IsBranch() {
+				branch := plumbing.ReferenceName(src).Short()
+				dst := plumbing.ReferenceName(rs.Dst(plumbing.ReferenceName(src))).Short()
+				err = setTracking(r, branch, remoteName+"/"+dst)
+				if err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+	return "", nil
+}
+
+// pushRefSpec converts the refspec argument of git push into a full refspec
+func pushRefSpec(r *git.Repository, refspec string, force bool) (config.RefSpec, error) {
+	if strings.HasPrefix(refspec, "+") {
+		force = true
+		refspec = strings.TrimPrefix(refspec, "+")
+	}
+	src, dst, hasDst := strings.Cut(refspec, ":")
+	if src == "HEAD" {
+		head, err := r.Head()
+		if err != nil {
+			return "", fmt.Errorf("failed to find HEAD: %w", err)
+		}
+		if !head.Name().IsBranch() {
+			return "", fmt.Errorf("cannot push HEAD as it is not on a branch")
+		}
+		src = head.Name().String()
+	} else if src != "" && !strings.HasPrefix(src, "refs/") {
+		if _, err := r.Reference(plumbing.NewTagReferenceName(src), false); err == nil {
+			src = plumbing.NewTagReferenceName(src).String()
+		} else {
+			src = plumbing.NewBranchReferenceName(src).String()
+		}
+	}
+	if !hasDst {
+		dst = src
+	} else if !strings.HasPrefix(dst, "refs/") {
+		if strings.HasPrefix(src, "refs/tags/") {
+			dst = plumbing.NewTagReferenceName(dst).String()
+		} else {
+			dst = plumbing.NewBranchReferenceName(dst).String()
+		}
+	}
+	answer := src + ":" + dst
+	if force {
+		answer = "+" + answer
+	}
+	return config.RefSpec(answer), nil
+}
+
+func (c *client) tag(dir string, args []string) (string, error) {
+	a, err := parseArgs("tag", args,
+		boolFlag("list", "-l", "--list"),
+		boolFlag("annotate", "-a", "--annotate"),
+		valueFlag("message", "-m", "--message"),
+		boolFlag("delete", "-d", "--delete"),
+		boolFlag("force", "-f", "--force"),
+	)
+	if err != nil {
+		return "", err
+	}
+	r, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	if a.has("delete") {
+		for _, name := range a.positional {
+			err = r.DeleteTag(name)
+			if err != nil {
+				return "", fmt.Errorf("failed to delete tag %s: %w", name, err)
+			}
+		}
+		return "", nil
+	}
+	if a.has("list") || len(a.positional) == 0 {
+		return listTags(r, a.positional)
+	}
+
+	name := a.positional[0]
+	rev := "HEAD"
+	if len(a.positional) > 1 {
+		rev = a.positional[1]
+	}
+	hash, err := r.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", rev, err)
+	}
+	if a.has("force") {
+		err = r.DeleteTag(name)
+		if err != nil && !errors.Is(err, git.ErrTagNotFound) {
+			return "", fmt.Errorf("failed to replace tag %s: %w", name, err)
+		}
+	}
+	var opts *git.CreateTagOptions
+	message := strings.Join(a.flags["message"], "\n\n")
+	if a.has("annotate") || message != "" {
+		if message == "" {
+			return "", fmt.Errorf("no message specified for annotated tag %s", name)
+		}
+		opts = &git.CreateTagOptions{
+			Message: message,
+			Tagger:  envSignature("GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"),
+		}
+	}
+	_, err = r.CreateTag(name, *hash, opts)
+	if err != nil {
+		return "", fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	return "", nil
+}
+
+// listTags returns the sorted tag names matching any of the patterns
+func listTags(r *git.Repository, patterns []string) (string, error) {
+	iter, err := r.Tags()
+	if err != nil {
+		return "", fmt.Errorf("failed to list tags: %w", err)
+	}
+	var names []string
+	err = iter.ForEach(func(ref *plumbing.Reference) error {
+		name := ref.Name().Short()
+		if len(patterns) == 0 {
+			names = append(names, name)
+			return nil
+		}
+		for _, p := range patterns {
+			if matched, _ := path.Match(p, name); matched {
+				names = append(names, name)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list tags: %w", err)
+	}
+	sort.Strings(names)
+	return strings.Join(names, "\n"), nil
+}
+
+func (c *client) revParse(dir string, args []string) (string, error) {
+	a, err := parseArgs("rev-parse", args,
+		boolFlag("abbrev-ref", "--abbrev-ref"),
+		boolFlag("short", "--short"),
+		boolFlag("verify", "--verify"),
+		boolFlag("quiet", "-q", "--quiet"),
+		boolFlag("show-toplevel", "--show-toplevel"),
+		boolFlag("git-dir", "--git-dir"),
+		boolFlag("is-inside-work-tree", "--is-inside-work-tree"),
+	)
+	if err != nil {
+		return "", err
+	}
+	r, w, err := openWorktree(dir)
+	if err != nil {
+		return "", err
+	}
+	var lines []string
+	if a.has("show-toplevel") {
+		lines = append(lines, w.Filesystem.Root())
+	}
+	if a.has("git-dir") {
+		lines = append(lines, filepath.Join(w.Filesystem.Root(), git.GitDirName))
+	}
+	if a.has("is-inside-work-tree") {
+		lines = append(lines, "true")
+	}
+	for _, rev := range a.positional {
+		if a.has("abbrev-ref") {
+			name, err := abbrevRef(r, rev)
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, name)
+			continue
+		}
+		hash, err := r.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision: %w", rev, err)
+		}
+		text := hash.String()
+		if a.has("short") {
+			n := 7
+			if v := a.value("short"); v != "" {
+				n, err = strconv.Atoi(v)
+				if err != nil {
+					return "", fmt.Errorf("invalid --short length %s: %w", v, err)
+				}
+			}
+			if n < len(text) {
+				text = text[:n]
+			}
+		}
+		lines = append(lines, text)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// abbrevRef returns the short name of the reference such as the current branch for HEAD or the upstream branch for @{u}
+func abbrevRef(r *git.Repository, rev string) (string, error) {
+	switch {
+	case rev == "HEAD":
+		head, err := r.Head()
+		if err != nil {
+			return "", fmt.Errorf("failed to find HEAD: %w", err)
+		}
+		if head.Name().IsBranch() {
+			return head.Name().Short(), nil
+		}
+		return "HEAD", nil
+	case strings.HasSuffix(rev, "@{u}") || strings.HasSuffix(rev, "@{upstream}"):
+		branch := strings.TrimSuffix(strings.TrimSuffix(rev, "@{u}"), "@{upstream}")
+		if branch == "" {
+			head, err := r.Head()
+			if err != nil {
+				return "", fmt.Errorf("failed to find HEAD: %w", err)
+			}
+			branch = head.Name().Short()
+		}
+		b, err := r.Branch(branch)
+		if err != nil || b.Remote == "" {
+			return "", fmt.Errorf("fatal: no upstream configured for branch '%s'", branch)
+		}
+		return b.Remote + "/" + b.Merge.Short(), nil
+	default:
+		for _, name := range []plumbing.ReferenceName{
+			plumbing.ReferenceName(rev),
+			plumbing.NewBranchReferenceName(rev),
+			plumbing.NewTagReferenceName(rev),
+			plumbing.ReferenceName("refs/remotes/" + rev),
+		} {
+			if ref, err := r.Reference(name, false); err == nil {
+				return ref.Name().Short(), nil
+			}
+		}
+		return "", fmt.Errorf("fatal: ambiguous argument '%s': unknown revision", rev)
+	}
+}
+
+func (c *client) remote(dir string, args []string) (string, error) {
+	a, err := parseArgs("remote", args,
+		boolFlag("verbose", "-v", "--verbose"),
+	)
+	if err != nil {
+		return "", err
+	}
+	r, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+	if len(a.positional) == 0 {
+		return listRemotes(r, a.has("verbose"))
+	}
+	action := a.positional[0]
+	params := a.positional[1:]
+	switch action {
+	case "add":
+		if len(params) != 2 {
+			return "", fmt.Errorf("usage: git remote add <name> <url>")
+		}
+		_, err = r.CreateRemote(&config.RemoteConfig{Name: params[0], URLs: []string{params[1]}})
+		if err != nil {
+			return "", fmt.Errorf("failed to add remote %s: %w", params[0], err)
+		}
+		return "", nil
+	case "set-url":
+		if len(params) != 2 {
+			return "", fmt.Errorf("usage: git remote set-url <name> <url>")
+		}
+		cfg, err := r.Config()
+		if err != nil {
+			return "", fmt.Errorf("failed to load git config: %w", err)
+		}
+		remote := cfg.Remotes[params[0]]
+		if remote == nil {
+			return "", fmt.Errorf("error: No such remote '%s'", params[0])
+		}
+		remote.URLs = []string{params[1]}
+		err = r.SetConfig(cfg)
+		if err != nil {
+			return "", fmt.Errorf("failed to set the URL of remote %s: %w", params[0], err)
+		}
+		return "", nil
+	case "get-url":
+		if len(params) != 1 {
+			return "", fmt.Errorf("usage: git remote get-url <name>")
+		}
+		return c.remoteURL(r, params[0])
+	case "remove", "rm":
+		if len(params) != 1 {
+			return "", fmt.Errorf("usage: git remote remove <name>")
+		}
+		err = r.DeleteRemote(params[0])
+		if err != nil {
+			return "", fmt.Errorf("failed to remove remote %s: %w", params[0], err)
+		}
+		return "", nil
+	default:
+		return "", UnsupportedError{Command: "remote", Argument: action}
+	}
+}
+
+func listRemotes(r *git.Repository, verbose bool) (string, error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return "", fmt.Errorf("failed to list remotes: %w", err)
+	}
+	sort.Slice(remotes, func(i, j int) bool {
+		return remotes[i].Config().Name < remotes[j].Config().Name
+	})
+	var lines []string
+	for _, remote := range remotes {
+		cfg := remote.Config()
+		if !verbose {
+			lines = append(lines, cfg.Name)
+			continue
+		}
+		for _, u := range cfg.URLs {
+			lines = append(lines, fmt.Sprintf("%s\t%s (fetch)", cfg.Name, u), fmt.Sprintf("%s\t%s (push)", cfg.Name, u))
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// remoteURL returns the first URL of the given remote
+func (c *client) remoteURL(r *git.Repository, name string) (string, error) {
+	remote, err := r.Remote(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to find remote %s: %w", name, err)
+	}
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", fmt.Errorf("remote %s has no URL", name)
+	}
+	return urls[0], nil
+}
+
+// isURL returns true if the text looks like a git URL or local path rather than a remote name
+func isURL(text string) bool {
+	return strings.Contains(text, "://") || strings.Contains(text, "@") || strings.HasPrefix(text, "/") ||
+		strings.HasPrefix(text, ".")
+}
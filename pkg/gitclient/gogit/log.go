@@ -0,0 +1,211 @@
+package gogit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitlog"
+)
+
+var numericLimitRegex = regexp.MustCompile(`^-[0-9]+$`)
+
+func (c *client) log(dir string, args []string) (string, error) {
+	// lets convert the '-N' limit into '-n N'
+	var converted []string
+	for i, arg := range args {
+		if arg == "--" {
+			converted = append(converted, args[i:]...)
+			break
+		}
+		if numericLimitRegex.MatchString(arg) {
+			converted = append(converted, "-n", strings.TrimPrefix(arg, "-"))
+			continue
+		}
+		converted = append(converted, arg)
+	}
+	a, err := parseArgs("log", converted,
+		valueFlag("max-count", "-n", "--max-count"),
+		valueFlag("pretty", "--pretty", "--format"),
+		boolFlag("oneline", "--oneline"),
+		boolFlag("reverse", "--reverse"),
+		boolFlag("no-merges", "--no-merges"),
+	)
+	if err != nil {
+		return "", err
+	}
+	r, err := openRepository(dir)
+	if err != nil {
+		return "", err
+	}
+
+	limit := -1
+	if v := a.value("max-count"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("invalid max count %s: %w", v, err)
+		}
+	}
+	format := "medium"
+	if a.has("oneline") {
+		format = "tformat:%h %s"
+	}
+	if v := a.value("pretty"); v != "" {
+		format = v
+	}
+
+	include, exclude, err := logRevisions(r, a.positional)
+	if err != nil {
+		return "", err
+	}
+	excluded := map[plumbing.Hash]bool{}
+	for _, h := range exclude {
+		iter, err := r.Log(&git.LogOptions{From: h})
+		if err != nil {
+			return "", fmt.Errorf("failed to walk the history of %s: %w", h.String(), err)
+		}
+		err = iter.ForEach(func(commit *object.Commit) error {
+			excluded[commit.Hash] = true
+			return nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to walk the history of %s: %w", h.String(), err)
+		}
+	}
+
+	opts := &git.LogOptions{Order: git.LogOrderCommitterTime}
+	if len(include) > 0 {
+		opts.From = include[0]
+	}
+	if len(a.paths) > 0 {
+		_, w, err := openWorktree(dir)
+		if err != nil {
+			return "", err
+		}
+		filters := worktreePaths(w, dir, a.paths)
+		opts.PathFilter = func(p string) bool {
+			return matchesPathFilters(filters, p)
+		}
+	}
+	iter, err := r.Log(opts)
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return "", fmt.Errorf("fatal: your current branch does not have any commits yet: %w", err)
+		}
+		return "", fmt.Errorf("failed to get the git log: %w", err)
+	}
+	var commits []*object.Commit
+	err = iter.ForEach(func(commit *object.Commit) error {
+		if limit >= 0 && len(commits) >= limit {
+			return storer.ErrStop
+		}
+		if excluded[commit.Hash] {
+			return nil
+		}
+		if a.has("no-merges") && commit.NumParents() > 1 {
+			return nil
+		}
+		commits = append(commits, commit)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the git log: %w", err)
+	}
+	if a.has("reverse") {
+		for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+			commits[i], commits[j] = commits[j], commits[i]
+		}
+	}
+
+	buf := strings.Builder{}
+	for i, commit := range commits {
+		text, terminated, err := gitlog.FormatCommit(prettyCommit(commit), format)
+		if err != nil {
+			var formatErr *gitlog.UnsupportedFormatError
+			if errors.As(err, &formatErr) {
+				return "", UnsupportedError{Command: "log", Argument: formatErr.Format}
+			}
+			return "", err
+		}
+		if i > 0 && !terminated {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(text)
+		if terminated {
+			buf.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(buf.String(), "\n"), nil
+}
+
+// logRevisions resolves the revisions and ranges of git log into the commits to include and exclude
+func logRevisions(r *git.Repository, revisions []string) ([]plumbing.Hash, []plumbing.Hash, error) {
+	var include, exclude []plumbing.Hash
+	resolve := func(rev string) (plumbing.Hash, error) {
+		if rev == "" {
+			rev = "HEAD"
+		}
+		h, err := r.ResolveRevision(plumbing.Revision(rev))
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("fatal: ambiguous argument '%s': unknown revision: %w", rev, err)
+		}
+		return *h, nil
+	}
+	for _, rev := range revisions {
+		if from, to, ok := strings.Cut(rev, ".."); ok {
+			if strings.HasPrefix(to, ".") {
+				return nil, nil, UnsupportedError{Command: "log", Argument: rev}
+			}
+			h, err := resolve(from)
+			if err != nil {
+				return nil, nil, err
+			}
+			exclude = append(exclude, h)
+			h, err = resolve(to)
+			if err != nil {
+				return nil, nil, err
+			}
+			include = append(include, h)
+			continue
+		}
+		if strings.HasPrefix(rev, "^") {
+			h, err := resolve(strings.TrimPrefix(rev, "^"))
+			if err != nil {
+				return nil, nil, err
+			}
+			exclude = append(exclude, h)
+			continue
+		}
+		h, err := resolve(rev)
+		if err != nil {
+			return nil, nil, err
+		}
+		include = append(include, h)
+	}
+	if len(include) > 1 {
+		return nil, nil, UnsupportedError{Command: "log", Argument: "multiple revisions"}
+	}
+	return include, exclude, nil
+}
+
+// prettyCommit returns the commit to format with the git log pretty formats
+func prettyCommit(commit *object.Commit) *gitlog.PrettyCommit {
+	var parents []string
+	for _, p := range commit.ParentHashes {
+		parents = append(parents, p.String())
+	}
+	return &gitlog.PrettyCommit{
+		SHA:       commit.Hash.String(),
+		Tree:      commit.TreeHash.String(),
+		Parents:   parents,
+		Author:    gitlog.Signature{Name: commit.Author.Name, Email: commit.Author.Email, Date: commit.Author.When},
+		Committer: gitlog.Signature{Name: commit.Committer.Name, Email: commit.Committer.Email, Date: commit.Committer.When},
+		Message:   commit.Message,
+	}
+}
@@ -0,0 +1,537 @@
+package gogit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// statusEntry a changed or untracked file in the output of git status
+type statusEntry struct {
+	path     string
+	original string
+	staging  git.StatusCode
+	worktree git.StatusCode
+}
+
+// branchStatus the branch information shown by git status --branch
+type branchStatus struct {
+	oid      string
+	head     string
+	upstream string
+	// hasUpstreamRef is true if the upstream branch has been fetched so that ahead and behind are known
+	hasUpstreamRef bool
+	ahead          int
+	behind         int
+}
+
+// status supports the long, short, porcelain v1 and porcelain v2 formats including --branch and -z. Ignored files
+// are not reported by go-git so --ignored returns an UnsupportedError
+func (c *client) status(dir string, args []string) (string, error) {
+	a, err := parseArgs("status", args,
+		boolFlag("short", "-s", "--short"),
+		boolFlag("porcelain", "--porcelain"),
+		boolFlag("branch", "-b", "--branch"),
+		boolFlag("null", "-z"),
+		boolFlag("untracked-files", "-u", "--untracked-files"),
+		boolFlag("ignored", "--ignored"),
+	)
+	if err != nil {
+		return "", err
+	}
+	if a.has("ignored") {
+		return "", UnsupportedError{Command: "status", Argument: "--ignored"}
+	}
+	porcelain := a.value("porcelain")
+	if a.has("porcelain") && porcelain == "" {
+		porcelain = "v1"
+	}
+	if porcelain != "" && porcelain != "v1" && porcelain != "v2" {
+		return "", UnsupportedError{Command: "status", Argument: "--porcelain=" + porcelain}
+	}
+	untracked := "normal"
+	if a.has("untracked-files") {
+		// git uses 'all' for -u without a mode
+		untracked = a.value("untracked-files")
+		if untracked == "" {
+			untracked = "all"
+		}
+	}
+	switch untracked {
+	case "no", "normal", "all":
+	default:
+		return "", fmt.Errorf("fatal: Invalid untracked files mode '%s'", untracked)
+	}
+
+	r, w, err := openWorktree(dir)
+	if err != nil {
+		return "", err
+	}
+	st, err := w.Status()
+	if err != nil {
+		return "", fmt.Errorf("failed to get the status of %s: %w", dir, err)
+	}
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the index of %s: %w", dir, err)
+	}
+	filters := worktreePaths(w, dir, append(a.positional, a.paths...))
+	entries := statusEntries(st, idx, filters, untracked)
+
+	// git uses the long format unless a short or porcelain format is requested which -z implies
+	long := !a.has("short") && porcelain == "" && !a.has("null")
+	var b *branchStatus
+	if a.has("branch") || long {
+		b, err = getBranchStatus(r)
+		if err != nil {
+			return "", err
+		}
+	}
+	if long {
+		return longStatus(dir, w.Filesystem.Root(), b, entries, untracked != "no"), nil
+	}
+	terminator := "\n"
+	if a.has("null") {
+		terminator = "\x00"
+	}
+	var records []string
+	if porcelain == "v2" {
+		records, err = porcelainV2Records(r, w, idx, b, entries, a.has("null"))
+		if err != nil {
+			return "", err
+		}
+	} else {
+		records = shortRecords(b, entries, a.has("null"))
+	}
+	if !a.has("null") {
+		return strings.Join(records, terminator), nil
+	}
+	var sb strings.Builder
+	for _, record := range records {
+		sb.WriteString(record)
+		sb.WriteString(terminator)
+	}
+	return sb.String(), nil
+}
+
+// statusEntries returns the changed files followed by the untracked files sorted by path. Untracked files are
+// omitted for the 'no' mode and replaced by their top most untracked directory for the 'normal' mode like git
+func statusEntries(st git.Status, idx *index.Index, filters []string, untracked string) []statusEntry {
+	trackedDirs := map[string]bool{}
+	for _, e := range idx.Entries {
+		for d := filepath.ToSlash(filepath.Dir(e.Name)); d != "."; d = filepath.ToSlash(filepath.Dir(d)) {
+			trackedDirs[d] = true
+		}
+	}
+	found := map[string]bool{}
+	var answer []statusEntry
+	for p, fs := range st {
+		if fs.Staging == git.Unmodified && fs.Worktree == git.Unmodified {
+			continue
+		}
+		if !matchesPathFilters(filters, p) {
+			continue
+		}
+		if fs.Staging == git.Untracked && fs.Worktree == git.Untracked {
+			if untracked == "no" {
+				continue
+			}
+			if untracked == "normal" {
+				p = untrackedPath(trackedDirs, p)
+			}
+			if found[p] {
+				continue
+			}
+			found[p] = true
+		}
+		answer = append(answer, statusEntry{path: p, original: fs.Extra, staging: fs.Staging, worktree: fs.Worktree})
+	}
+	// git lists the untracked files after the tracked files
+	sort.Slice(answer, func(i, j int) bool {
+		ui, uj := answer[i].staging == git.Untracked, answer[j].staging == git.Untracked
+		if ui != uj {
+			return uj
+		}
+		return answer[i].path < answer[j].path
+	})
+	return answer
+}
+
+// untrackedPath returns the top most directory of the untracked file which contains no tracked files or the file
+func untrackedPath(trackedDirs map[string]bool, p string) string {
+	parts := strings.Split(p, "/")
+	for i := 1; i < len(parts); i++ {
+		d := strings.Join(parts[:i], "/")
+		if !trackedDirs[d] {
+			return d + "/"
+		}
+	}
+	return p
+}
+
+// shortRecords returns the lines of the short and porcelain v1 formats
+func shortRecords(b *branchStatus, entries []statusEntry, null bool) []string {
+	var answer []string
+	if b != nil {
+		answer = append(answer, "## "+b.shortHeader())
+	}
+	for _, e := range entries {
+		switch {
+		case e.original == "":
+			answer = append(answer, fmt.Sprintf("%c%c %s", e.staging, e.worktree, e.path))
+		case null:
+			answer = append(answer, fmt.Sprintf("%c%c %s", e.staging, e.worktree, e.path), e.original)
+		default:
+			answer = append(answer, fmt.Sprintf("%c%c %s -> %s", e.staging, e.worktree, e.original, e.path))
+		}
+	}
+	return answer
+}
+
+// shortHeader returns the branch header of the short format such as 'main...origin/main [ahead 1]'
+func (b *branchStatus) shortHeader() string {
+	switch {
+	case b.head == "":
+		return "HEAD (no branch)"
+	case b.oid == "":
+		return "No commits yet on " + b.head
+	case b.upstream == "":
+		return b.head
+	case !b.hasUpstreamRef:
+		return b.head + "..." + b.upstream + " [gone]"
+	}
+	var counts []string
+	if b.ahead > 0 {
+		counts = append(counts, fmt.Sprintf("ahead %d", b.ahead))
+	}
+	if b.behind > 0 {
+		counts = append(counts, fmt.Sprintf("behind %d", b.behind))
+	}
+	text := b.head + "..." + b.upstream
+	if len(counts) > 0 {
+		text += " [" + strings.Join(counts, ", ") + "]"
+	}
+	return text
+}
+
+// longStatus returns the human readable long format of git status with the paths relative to the directory
+func longStatus(dir, root string, b *branchStatus, entries []statusEntry, showUntracked bool) string {
+	var lines []string
+	if b.head == "" {
+		lines = append(lines, "HEAD detached at "+b.oid[:7])
+	} else {
+		lines = append(lines, "On branch "+b.head)
+	}
+	if tracking := b.trackingLines(); len(tracking) > 0 {
+		lines = append(lines, tracking...)
+		lines = append(lines, "")
+	}
+	if b.oid == "" {
+		lines = append(lines, "", "No commits yet", "")
+	}
+
+	relPath := func(p string) string {
+		rel, err := filepath.Rel(dir, filepath.Join(root, filepath.FromSlash(p)))
+		if err != nil {
+			return p
+		}
+		rel = filepath.ToSlash(rel)
+		if strings.HasSuffix(p, "/") {
+			rel += "/"
+		}
+		return rel
+	}
+	var staged, unstaged, untrackedFiles []string
+	hasDeleted := false
+	for _, e := range entries {
+		if e.staging == git.Untracked {
+			untrackedFiles = append(untrackedFiles, "\t"+relPath(e.path))
+			continue
+		}
+		if e.staging != git.Unmodified {
+			path := relPath(e.path)
+			if e.original != "" {
+				path = relPath(e.original) + " -> " + path
+			}
+			staged = append(staged, fmt.Sprintf("\t%-12s%s", statusLabel(e.staging)+":", path))
+		}
+		if e.worktree != git.Unmodified {
+			hasDeleted = hasDeleted || e.worktree == git.Deleted
+			unstaged = append(unstaged, fmt.Sprintf("\t%-12s%s", statusLabel(e.worktree)+":", relPath(e.path)))
+		}
+	}
+	if len(staged) > 0 {
+		hint := `  (use "git restore --staged <file>..." to unstage)`
+		if b.oid == "" {
+			hint = `  (use "git rm --cached <file>..." to unstage)`
+		}
+		lines = append(lines, "Changes to be committed:", hint)
+		lines = append(lines, staged...)
+		lines = append(lines, "")
+	}
+	if len(unstaged) > 0 {
+		hint := `  (use "git add <file>..." to update what will be committed)`
+		if hasDeleted {
+			hint = `  (use "git add/rm <file>..." to update what will be committed)`
+		}
+		lines = append(lines, "Changes not staged for commit:", hint, `  (use "git restore <file>..." to discard changes in working directory)`)
+		lines = append(lines, unstaged...)
+		lines = append(lines, "")
+	}
+	if len(untrackedFiles) > 0 {
+		lines = append(lines, "Untracked files:", `  (use "git add <file>..." to include in what will be committed)`)
+		lines = append(lines, untrackedFiles...)
+		lines = append(lines, "")
+	} else if !showUntracked && len(staged) > 0 {
+		lines = append(lines, "Untracked files not listed (use -u option to show untracked files)")
+	}
+	if len(staged) == 0 {
+		switch {
+		case len(unstaged) > 0:
+			lines = append(lines, `no changes added to commit (use "git add" and/or "git commit -a")`)
+		case len(untrackedFiles) > 0:
+			lines = append(lines, `nothing added to commit but untracked files present (use "git add" to track)`)
+		case b.oid == "":
+			lines = append(lines, `nothing to commit (create/copy files and use "git add" to track)`)
+		case !showUntracked:
+			lines = append(lines, "nothing to commit (use -u to show untracked files)")
+		default:
+			lines = append(lines, "nothing to commit, working tree clean")
+		}
+	}
+	return strings.TrimSuffix(strings.Join(lines, "\n"), "\n")
+}
+
+// statusLabel returns the label of the status code used by the long format
+func statusLabel(code git.StatusCode) string {
+	switch code {
+	case git.Added:
+		return "new file"
+	case git.Deleted:
+		return "deleted"
+	case git.Renamed:
+		return "renamed"
+	case git.Copied:
+		return "copied"
+	case git.UpdatedButUnmerged:
+		return "both modified"
+	default:
+		return "modified"
+	}
+}
+
+// trackingLines returns the lines of the long format describing the branch compared to its upstream
+func (b *branchStatus) trackingLines() []string {
+	switch {
+	case b.upstream == "":
+		return nil
+	case !b.hasUpstreamRef:
+		return []string{
+			fmt.Sprintf("Your branch is based on '%s', but the upstream is gone.", b.upstream),
+			`  (use "git branch --unset-upstream" to fixup)`,
+		}
+	case b.ahead > 0 && b.behind > 0:
+		return []string{
+			fmt.Sprintf("Your branch and '%s' have diverged,", b.upstream),
+			fmt.Sprintf("and have %d and %d different commits each, respectively.", b.ahead, b.behind),
+			`  (use "git pull" to merge the remote branch into yours)`,
+		}
+	case b.ahead > 0:
+		return []string{
+			fmt.Sprintf("Your branch is ahead of '%s' by %s.", b.upstream, commitCount(b.ahead)),
+			`  (use "git push" to publish your local commits)`,
+		}
+	case b.behind > 0:
+		return []string{
+			fmt.Sprintf("Your branch is behind '%s' by %s, and can be fast-forwarded.", b.upstream, commitCount(b.behind)),
+			`  (use "git pull" to update your local branch)`,
+		}
+	default:
+		return []string{fmt.Sprintf("Your branch is up to date with '%s'.", b.upstream)}
+	}
+}
+
+// commitCount returns the number of commits such as '1 commit' or '2 commits'
+func commitCount(n int) string {
+	if n == 1 {
+		return "1 commit"
+	}
+	return fmt.Sprintf("%d commits", n)
+}
+
+// porcelainV2Records returns the records of the porcelain v2 format
+func porcelainV2Records(r *git.Repository, w *git.Worktree, idx *index.Index, b *branchStatus, entries []statusEntry, null bool) ([]string, error) {
+	var answer []string
+	if b != nil {
+		oid := b.oid
+		if oid == "" {
+			oid = "(initial)"
+		}
+		head := b.head
+		if head == "" {
+			head = "(detached)"
+		}
+		answer = append(answer, "# branch.oid "+oid, "# branch.head "+head)
+		if b.upstream != "" {
+			answer = append(answer, "# branch.upstream "+b.upstream)
+			if b.hasUpstreamRef {
+				answer = append(answer, fmt.Sprintf("# branch.ab +%d -%d", b.ahead, b.behind))
+			}
+		}
+	}
+	var headTree *object.Tree
+	head, err := r.Head()
+	if err == nil {
+		commit, err := r.CommitObject(head.Hash())
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the HEAD commit: %w", err)
+		}
+		headTree, err = commit.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the tree of the HEAD commit: %w", err)
+		}
+	}
+	root := w.Filesystem.Root()
+	for _, e := range entries {
+		if e.staging == git.Untracked {
+			answer = append(answer, "? "+e.path)
+			continue
+		}
+		headPath := e.path
+		if e.original != "" {
+			headPath = e.original
+		}
+		modeH, hashH := treeEntryModeAndHash(headTree, headPath)
+		modeI, hashI := filemode.Empty, plumbing.ZeroHash
+		if ie, err := idx.Entry(e.path); err == nil {
+			modeI, hashI = ie.Mode, ie.Hash
+		}
+		modeW := filemode.Empty
+		if info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(e.path))); err == nil {
+			modeW, _ = filemode.NewFromOSFileMode(info.Mode())
+		}
+		fields := fmt.Sprintf("%c%c N... %06o %06o %06o %s %s", v2Code(e.staging), v2Code(e.worktree), uint32(modeH), uint32(modeI), uint32(modeW), hashH.String(), hashI.String())
+		switch {
+		case e.original == "":
+			answer = append(answer, "1 "+fields+" "+e.path)
+		case null:
+			// go-git does not calculate a similarity score for renames
+			answer = append(answer, "2 "+fields+" R100 "+e.path, e.original)
+		default:
+			answer = append(answer, "2 "+fields+" R100 "+e.path+"\t"+e.original)
+		}
+	}
+	return answer, nil
+}
+
+// v2Code returns the status code used by the porcelain v2 format which uses '.' for unmodified
+func v2Code(code git.StatusCode) rune {
+	if code == git.Unmodified {
+		return '.'
+	}
+	return rune(code)
+}
+
+// treeEntryModeAndHash returns the mode and hash of the file in the tree or zero values if it does not exist
+func treeEntryModeAndHash(tree *object.Tree, p string) (filemode.FileMode, plumbing.Hash) {
+	if tree == nil {
+		return filemode.Empty, plumbing.ZeroHash
+	}
+	e, err := tree.FindEntry(p)
+	if err != nil {
+		return filemode.Empty, plumbing.ZeroHash
+	}
+	return e.Mode, e.Hash
+}
+
+// getBranchStatus returns the current branch, its upstream and how far ahead and behind the upstream it is
+func getBranchStatus(r *git.Repository) (*branchStatus, error) {
+	answer := &branchStatus{}
+	ref, err := r.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find HEAD: %w", err)
+	}
+	if ref.Type() == plumbing.SymbolicReference {
+		answer.head = ref.Target().Short()
+	}
+	head, err := r.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			// there are no commits yet
+			return answer, nil
+		}
+		return nil, fmt.Errorf("failed to find HEAD: %w", err)
+	}
+	answer.oid = head.Hash().String()
+	if answer.head == "" {
+		return answer, nil
+	}
+	b, err := r.Branch(answer.head)
+	if err != nil || b.Remote == "" || b.Merge == "" {
+		return answer, nil
+	}
+	answer.upstream = b.Remote + "/" + b.Merge.Short()
+	upstream, err := r.Reference(plumbing.NewRemoteReferenceName(b.Remote, b.Merge.Short()), true)
+	if err != nil {
+		return answer, nil
+	}
+	answer.hasUpstreamRef = true
+	local, err := ancestors(r, head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	remote, err := ancestors(r, upstream.Hash())
+	if err != nil {
+		return nil, err
+	}
+	for h := range local {
+		if !remote[h] {
+			answer.ahead++
+		}
+	}
+	for h := range remote {
+		if !local[h] {
+			answer.behind++
+		}
+	}
+	return answer, nil
+}
+
+// ancestors returns the hashes of the commit and all of its ancestors
+func ancestors(r *git.Repository, hash plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	iter, err := r.Log(&git.LogOptions{From: hash})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk the history of %s: %w", hash.String(), err)
+	}
+	answer := map[plumbing.Hash]bool{}
+	err = iter.ForEach(func(commit *object.Commit) error {
+		answer[commit.Hash] = true
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk the history of %s: %w", hash.String(), err)
+	}
+	return answer, nil
+}
+
+// matchesPathFilters returns true if there are no filters or the path is, or is inside, one of the filters
+func matchesPathFilters(filters []string, p string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, f := range filters {
+		if f == "." || p == f || strings.HasPrefix(p, strings.TrimSuffix(f, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -131,6 +131,12 @@ func RemoteBranches(gitter Interface, dir string) ([]string, error) {
 
 // ShallowCloneBranch clones a single branch of the given git URL into the given directory
 func ShallowCloneBranch(g Interface, gitURL string, branch string, dir string) error {
+	return ShallowCloneBranchWithOptions(g, gitURL, branch, dir, nil)
+}
+
+// ShallowCloneBranchWithOptions clones a single branch of the given git URL into the given directory
+// also cloning any submodules and fetching any Git LFS files if enabled in the options
+func ShallowCloneBranchWithOptions(g Interface, gitURL string, branch string, dir string, o *CloneOptions) error {
 	remoteName := "origin"
 	_, err := g.Command(dir, "init")
 	if err != nil {
@@ -179,7 +185,7 @@ func ShallowCloneBranch(g Interface, gitURL string, branch string, dir string) e
 
 		}
 	}
-	return nil
+	return o.setup(g, gitURL, dir, true)
 }
 
 // AddRemote adds a remote repository at the given URL and with the given name
@@ -331,6 +337,13 @@ func NthTag(g Interface, dir string, n int) (string, string, error) {
 
 // CloneToDir clones the git repository to either the given directory or create a temporary
 func CloneToDir(g Interface, gitURL, dir string) (string, error) {
+	return CloneToDirWithOptions(g, gitURL, dir, nil)
+}
+
+// CloneToDirWithOptions clones the git repository to either the given directory or create a temporary
+// also cloning any submodules and fetching any Git LFS files if enabled in the options.
+// A reference repository from the registered CloneCache is used if there is one
+func CloneToDirWithOptions(g Interface, gitURL, dir string, o *CloneOptions) (string, error) {
 	dir, err := createDir(dir)
 	if err != nil {
 		return "", err
@@ -339,17 +352,21 @@ func CloneToDir(g Interface, gitURL, dir string) (string, error) {
 	log.Logger().Debugf("cloning %s to directory %s", termcolor.ColorInfo(gitURL), termcolor.ColorInfo(dir))
 
 	parentDir := filepath.Dir(dir)
-	_, err = g.Command(parentDir, "clone", gitURL, dir)
+	refArgs, release := referenceArgs(g, gitURL)
+	defer release()
+	args := append(append([]string{"clone"}, refArgs...), gitURL, dir)
+	_, err = g.Command(parentDir, args...)
 	if err != nil {
 		return "", fmt.Errorf("failed to clone repository %s to directory: %s: %w", gitURL, dir, err)
 	}
-	return dir, nil
+	return dir, o.setup(g, gitURL, dir, false)
 }
 
 // PartialCloneToDir Partially clones the git repository to either the given directory or create a temporary one
 // Alternative to SparseCloneToDir when git provider does not support sparse-checkout
 // sparseCheckoutPatterns not supported
-// If shallow is true the clone is made with --depth=1
+// If shallow is true the clone is made with --depth=1. A reference repository from the registered CloneCache is used
+// if there is one
 func PartialCloneToDir(g Interface, gitURL, dir string, shallow bool) (string, error) {
 	dir, err := createDir(dir)
 	if err != nil {
@@ -364,6 +381,9 @@ func PartialCloneToDir(g Interface, gitURL, dir string, shallow bool) (string, e
 		log.Logger().Debugf("setting clone depth to 1")
 		partialCloneArgs = append(partialCloneArgs, "--depth=1")
 	}
+	refArgs, release := referenceArgs(g, gitURL)
+	defer release()
+	partialCloneArgs = append(partialCloneArgs, refArgs...)
 	_, err = g.Command(parentDir, append(partialCloneArgs, gitURL, dir)...)
 	if err != nil {
 		return "", fmt.Errorf("failed to partially clone repository %s to directory: %s: %w", gitURL, dir, err)
@@ -377,6 +397,12 @@ func PartialCloneToDir(g Interface, gitURL, dir string, shallow bool) (string, e
 // NOTE: This functionality is experimental and also the behaviour may vary between different git servers.
 // If shallow is true the clone is made with --depth=1
 func SparseCloneToDir(g Interface, gitURL, dir string, shallow bool, sparseCheckoutPatterns ...string) (string, error) {
+	return SparseCloneToDirWithOptions(g, gitURL, dir, shallow, nil, sparseCheckoutPatterns...)
+}
+
+// SparseCloneToDirWithOptions clones the git repository sparsely like SparseCloneToDir
+// also cloning any submodules and fetching any Git LFS files if enabled in the options
+func SparseCloneToDirWithOptions(g Interface, gitURL, dir string, shallow bool, o *CloneOptions, sparseCheckoutPatterns ...string) (string, error) {
 	dir, err := createDir(dir)
 	if err != nil {
 		return "", err
@@ -402,7 +428,7 @@ func SparseCloneToDir(g Interface, gitURL, dir string, shallow bool, sparseCheck
 	if err != nil {
 		return "", fmt.Errorf("failed to checkout sparsly: %w", err)
 	}
-	return dir, nil
+	return dir, o.setup(g, gitURL, dir, shallow)
 }
 
 // GetLatestCommitSha returns the latest commit sha
@@ -437,15 +463,25 @@ func Branch(g Interface, dir string) (string, error) {
 
 // CloneOrPull performs a clone if the directory is empty otherwise a pull
 func CloneOrPull(g Interface, url string, dir string) error {
+	return CloneOrPullWithOptions(g, url, dir, nil)
+}
+
+// CloneOrPullWithOptions performs a clone if the directory is empty otherwise a pull
+// also updating any submodules and fetching any Git LFS files if enabled in the options
+func CloneOrPullWithOptions(g Interface, url string, dir string, o *CloneOptions) error {
 	empty, err := files.IsEmpty(dir)
 	if err != nil {
 		return err
 	}
 
 	if !empty {
-		return Pull(g, dir)
+		err = Pull(g, dir)
+		if err != nil {
+			return err
+		}
+		return o.setup(g, url, dir, false)
 	}
-	_, err = CloneToDir(g, url, dir)
+	_, err = CloneToDirWithOptions(g, url, dir, o)
 	if err != nil {
 		return fmt.Errorf("failed to clone %s to %s: %w", url, dir, err)
 	}
@@ -627,11 +663,12 @@ func Status(g Interface, dir string) (string, error) {
 	return g.Command(dir, "status")
 }
 
-// Merge merges the commitish into the current branch
+// Merge merges the commitish into the current branch returning a ConflictError if there are conflicts.
+// The merge is left in progress on conflicts so it can be resolved and continued or aborted
 func Merge(g Interface, dir string, commitish string) error {
 	_, err := g.Command(dir, "merge", commitish)
 	if err != nil {
-		return fmt.Errorf("failed to merge %s: %w", commitish, err)
+		return conflictError(g, dir, "merge", commitish, err)
 	}
 	return nil
 }
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"k8s.io/client-go/kubernetes"
@@ -18,7 +17,6 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/files"
-	"github.com/jenkins-x/jx-helpers/v3/pkg/homedir"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 )
@@ -49,40 +47,7 @@ func GetServerCredentials(credentials []Credentials, serverURL string) Credentia
 
 // GitCredentialsFile returns the location of the git credentials file
 func GitCredentialsFile() (string, error) {
-	homeDir := homedir.HomeDir()
-	cfgHome := os.Getenv("XDG_CONFIG_HOME")
-	if cfgHome == "" {
-		cfgHome = homeDir
-	}
-	if cfgHome == "" {
-		cfgHome = "."
-	}
-	paths := []string{
-		filepath.Join(cfgHome, "git", "credentials"),
-		filepath.Join(cfgHome, ".git-credentials"),
-	}
-	if homeDir != "" && homeDir != cfgHome {
-		paths = append(paths,
-			filepath.Join(homeDir, "git", "credentials"),
-			filepath.Join(homeDir, ".git-credentials"),
-		)
-	}
-	paths = append(paths,
-		filepath.Join("git", "credentials"),
-		filepath.Join(".git-credentials"),
-	)
-	for _, path := range paths {
-		exists, err := files.FileExists(path)
-		if err != nil {
-			return path, fmt.Errorf("failed to check if git credentials file exists %s: %w", path, err)
-		}
-		if exists {
-			return path, nil
-		}
-	}
-
-	// lets return the default name we think should be used....
-	return filepath.Join(cfgHome, "git", "credentials"), nil
+	return credentialhelper.GitCredentialsFile()
 }
 
 // LoadGitCredentialsFile loads the git credentials from the `git/credentials` file
@@ -0,0 +1,287 @@
+package gitclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConflictType the kind of merge conflict of a file
+type ConflictType string
+
+const (
+	// ConflictBothModified both sides modified the file
+	ConflictBothModified ConflictType = "both modified"
+	// ConflictBothAdded both sides added the file
+	ConflictBothAdded ConflictType = "both added"
+	// ConflictBothDeleted both sides deleted the file
+	ConflictBothDeleted ConflictType = "both deleted"
+	// ConflictAddedByUs only our side added the file
+	ConflictAddedByUs ConflictType = "added by us"
+	// ConflictAddedByThem only their side added the file
+	ConflictAddedByThem ConflictType = "added by them"
+	// ConflictDeletedByUs our side deleted the file which their side modified
+	ConflictDeletedByUs ConflictType = "deleted by us"
+	// ConflictDeletedByThem their side deleted the file which our side modified
+	ConflictDeletedByThem ConflictType = "deleted by them"
+)
+
+// conflictTypes maps the porcelain status codes of unmerged files to the conflict type
+var conflictTypes = map[string]ConflictType{
+	"UU": ConflictBothModified,
+	"AA": ConflictBothAdded,
+	"DD": ConflictBothDeleted,
+	"AU": ConflictAddedByUs,
+	"UA": ConflictAddedByThem,
+	"DU": ConflictDeletedByUs,
+	"UD": ConflictDeletedByThem,
+}
+
+// ConflictedFile a file with unresolved merge conflicts
+type ConflictedFile struct {
+	// Path the path of the file relative to the root of the repository
+	Path string
+	// Type the kind of conflict
+	Type ConflictType
+}
+
+// hasOurs returns true if our side of the conflict contains the file
+func (f ConflictedFile) hasOurs() bool {
+	switch f.Type {
+	case ConflictBothModified, ConflictBothAdded, ConflictAddedByUs, ConflictDeletedByThem:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasTheirs returns true if their side of the conflict contains the file
+func (f ConflictedFile) hasTheirs() bool {
+	switch f.Type {
+	case ConflictBothModified, ConflictBothAdded, ConflictAddedByThem, ConflictDeletedByUs:
+		return true
+	default:
+		return false
+	}
+}
+
+// ConflictError the error returned when a merge or rebase stops due to conflicts
+type ConflictError struct {
+	// Operation the operation which failed such as 'merge' or 'rebase'
+	Operation string
+	// Revision the revision being merged or rebased onto
+	Revision string
+	// Files the conflicted files
+	Files []ConflictedFile
+	// Err the underlying error from git
+	Err error
+}
+
+// Error returns the error message including the conflicted files
+func (e *ConflictError) Error() string {
+	var paths []string
+	for _, f := range e.Files {
+		paths = append(paths, fmt.Sprintf("%s (%s)", f.Path, f.Type))
+	}
+	return fmt.Sprintf("failed to %s %s due to conflicts in %s", e.Operation, e.Revision, strings.Join(paths, ", "))
+}
+
+// Unwrap returns the underlying error
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// IsConflictError returns true if the error is caused by merge conflicts
+func IsConflictError(err error) bool {
+	var conflictError *ConflictError
+	return errors.As(err, &conflictError)
+}
+
+// ConflictResolution how to resolve a conflicted file
+type ConflictResolution string
+
+const (
+	// ResolveUnresolved leaves the file conflicted
+	ResolveUnresolved ConflictResolution = ""
+	// ResolveOurs uses our side of the conflict. Note that when rebasing our side is the branch being rebased onto
+	ResolveOurs ConflictResolution = "ours"
+	// ResolveTheirs uses their side of the conflict. Note that when rebasing their side is the commit being replayed
+	ResolveTheirs ConflictResolution = "theirs"
+	// ResolveEdited the file has already been resolved by editing it in the working tree so it only needs to be added
+	ResolveEdited ConflictResolution = "edited"
+)
+
+// ConflictResolver returns how to resolve the conflicted file in the given directory. It can edit the file
+// in the working tree and return ResolveEdited
+type ConflictResolver func(dir string, file ConflictedFile) (ConflictResolution, error)
+
+// ResolveAllWith returns a ConflictResolver which resolves all files with the same resolution
+func ResolveAllWith(resolution ConflictResolution) ConflictResolver {
+	return func(string, ConflictedFile) (ConflictResolution, error) {
+		return resolution, nil
+	}
+}
+
+// ConflictedFiles returns the files with unresolved merge conflicts in the given directory
+func ConflictedFiles(g Interface, dir string) ([]ConflictedFile, error) {
+	status, err := GetStatus(g, dir, &StatusOptions{UntrackedFiles: "no"})
+	if err != nil {
+		return nil, err
+	}
+	var answer []ConflictedFile
+	for _, e := range status.Conflicted() {
+		answer = append(answer, ConflictedFile{Path: e.Path, Type: conflictTypes[e.Index.String()+e.WorkTree.String()]})
+	}
+	return answer, nil
+}
+
+// conflictError returns a ConflictError if the failed operation left conflicted files otherwise the error
+func conflictError(g Interface, dir, operation, revision string, err error) error {
+	files, statusErr := ConflictedFiles(g, dir)
+	if statusErr != nil || len(files) == 0 {
+		return fmt.Errorf("failed to %s %s: %w", operation, revision, err)
+	}
+	return &ConflictError{Operation: operation, Revision: revision, Files: files, Err: err}
+}
+
+// MergeAbort aborts the merge in progress restoring the state before the merge
+func MergeAbort(g Interface, dir string) error {
+	_, err := g.Command(dir, "merge", "--abort")
+	if err != nil {
+		return fmt.Errorf("failed to abort the merge in dir %s: %w", dir, err)
+	}
+	return nil
+}
+
+// MergeContinue commits the merge in progress once all conflicts are resolved
+func MergeContinue(g Interface, dir string) error {
+	_, err := g.Command(dir, "commit", "--no-edit")
+	if err != nil {
+		return conflictError(g, dir, "continue", "merge", err)
+	}
+	return nil
+}
+
+// Rebase rebases the current branch onto the upstream revision returning a ConflictError if there are conflicts.
+// The rebase is left in progress on conflicts so it can be resolved and continued or aborted. Rebasing requires
+// the git CLI client
+func Rebase(g Interface, dir, upstream string) error {
+	_, err := g.Command(dir, "rebase", upstream)
+	if err != nil {
+		return conflictError(g, dir, "rebase onto", upstream, err)
+	}
+	return nil
+}
+
+// RebaseAbort aborts the rebase in progress restoring the original branch
+func RebaseAbort(g Interface, dir string) error {
+	_, err := g.Command(dir, "rebase", "--abort")
+	if err != nil {
+		return fmt.Errorf("failed to abort the rebase in dir %s: %w", dir, err)
+	}
+	return nil
+}
+
+// RebaseContinue continues the rebase in progress once all conflicts are resolved returning a ConflictError
+// if replaying a later commit conflicts. Like the other rebase helpers it requires the git CLI client as the
+// other clients do not support rebase. As git opens an editor for the message of the resolved commit the
+// repository is configured with core.editor set to true so that the original message is kept
+func RebaseContinue(g Interface, dir string) error {
+	_, err := g.Command(dir, "config", "core.editor", "true")
+	if err != nil {
+		return fmt.Errorf("failed to configure the editor in dir %s: %w", dir, err)
+	}
+	_, err = g.Command(dir, "rebase", "--continue")
+	if err != nil {
+		return conflictError(g, dir, "continue", "rebase", err)
+	}
+	return nil
+}
+
+// RebaseSkip skips the commit being replayed by the rebase in progress returning a ConflictError
+// if replaying a later commit conflicts
+func RebaseSkip(g Interface, dir string) error {
+	_, err := g.Command(dir, "rebase", "--skip")
+	if err != nil {
+		return conflictError(g, dir, "skip", "rebase commit", err)
+	}
+	return nil
+}
+
+// ResolveConflicts resolves the conflicted files using the resolver returning the files which remain conflicted
+func ResolveConflicts(g Interface, dir string, resolver ConflictResolver) ([]ConflictedFile, error) {
+	files, err := ConflictedFiles(g, dir)
+	if err != nil {
+		return nil, err
+	}
+	var unresolved []ConflictedFile
+	for _, f := range files {
+		resolution, err := resolver(dir, f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve conflicts in file %s: %w", f.Path, err)
+		}
+		switch resolution {
+		case ResolveUnresolved:
+			unresolved = append(unresolved, f)
+			continue
+		case ResolveOurs, ResolveTheirs:
+			exists := f.hasOurs()
+			if resolution == ResolveTheirs {
+				exists = f.hasTheirs()
+			}
+			if !exists {
+				_, err = g.Command(dir, "rm", "--quiet", "--", f.Path)
+				break
+			}
+			_, err = g.Command(dir, "checkout", "--"+string(resolution), "--", f.Path)
+			if err == nil {
+				_, err = g.Command(dir, "add", "--", f.Path)
+			}
+		case ResolveEdited:
+			_, err = g.Command(dir, "add", "--", f.Path)
+		default:
+			return nil, fmt.Errorf("unknown conflict resolution '%s' for file %s", resolution, f.Path)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve file %s using %s: %w", f.Path, resolution, err)
+		}
+	}
+	return unresolved, nil
+}
+
+// RebaseWithResolver rebases the current branch onto the upstream revision resolving any conflicts with the resolver
+// and continuing. If any conflicts cannot be resolved the rebase is aborted and a ConflictError is returned
+func RebaseWithResolver(g Interface, dir, upstream string, resolver ConflictResolver) error {
+	err := Rebase(g, dir, upstream)
+	for err != nil {
+		var conflictErr *ConflictError
+		if !errors.As(err, &conflictErr) {
+			return err
+		}
+		unresolved, resolveErr := ResolveConflicts(g, dir, resolver)
+		if resolveErr == nil && len(unresolved) > 0 {
+			conflictErr.Operation = "rebase onto"
+			conflictErr.Revision = upstream
+			conflictErr.Files = unresolved
+			resolveErr = conflictErr
+		}
+		if resolveErr != nil {
+			abortErr := RebaseAbort(g, dir)
+			if abortErr != nil {
+				return fmt.Errorf("%s: %w", abortErr.Error(), resolveErr)
+			}
+			return resolveErr
+		}
+		// the commit is empty if the resolution discarded all of its changes
+		status, statusErr := GetStatus(g, dir, &StatusOptions{UntrackedFiles: "no"})
+		if statusErr != nil {
+			return statusErr
+		}
+		if len(status.Staged()) == 0 {
+			err = RebaseSkip(g, dir)
+		} else {
+			err = RebaseContinue(g, dir)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+//go:build unit
+// +build unit
+
+package gitclient_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeConflicts(t *testing.T) {
+	g, dir := createConflictingBranches(t)
+
+	err := gitclient.Merge(g, dir, "feature")
+	require.Error(t, err, "merge should fail")
+	require.True(t, gitclient.IsConflictError(err), "should be a conflict error: %s", err.Error())
+
+	var conflictErr *gitclient.ConflictError
+	require.True(t, errors.As(err, &conflictErr))
+	assert.Equal(t, []gitclient.ConflictedFile{
+		{Path: "a.txt", Type: gitclient.ConflictBothModified},
+		{Path: "b.txt", Type: gitclient.ConflictDeletedByThem},
+	}, conflictErr.Files, "conflicted files")
+	assert.Equal(t, "failed to merge feature due to conflicts in a.txt (both modified), b.txt (deleted by them)", err.Error(), "error message")
+
+	require.NoError(t, gitclient.MergeAbort(g, dir), "failed to abort merge")
+	status, err := gitclient.GetStatus(g, dir, nil)
+	require.NoError(t, err, "failed to get status")
+	assert.True(t, status.IsClean(), "should be clean after abort")
+
+	err = gitclient.Merge(g, dir, "feature")
+	require.True(t, gitclient.IsConflictError(err), "should be a conflict error")
+
+	unresolved, err := gitclient.ResolveConflicts(g, dir, func(dir string, f gitclient.ConflictedFile) (gitclient.ConflictResolution, error) {
+		if f.Path == "a.txt" {
+			return gitclient.ResolveEdited, os.WriteFile(filepath.Join(dir, f.Path), []byte("merged\n"), 0o600)
+		}
+		return gitclient.ResolveTheirs, nil
+	})
+	require.NoError(t, err, "failed to resolve conflicts")
+	assert.Empty(t, unresolved, "unresolved files")
+	require.NoError(t, gitclient.MergeContinue(g, dir), "failed to continue merge")
+
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "merged\n")
+	assert.NoFileExists(t, filepath.Join(dir, "b.txt"), "b.txt deleted by them")
+	status, err = gitclient.GetStatus(g, dir, nil)
+	require.NoError(t, err, "failed to get status")
+	assert.True(t, status.IsClean(), "should be clean after merge")
+}
+
+func TestRebaseWithResolver(t *testing.T) {
+	g, dir := createConflictingBranches(t)
+	_, err := g.Command(dir, "checkout", "feature")
+	require.NoError(t, err, "failed to checkout feature")
+
+	err = gitclient.RebaseWithResolver(g, dir, "master", func(dir string, f gitclient.ConflictedFile) (gitclient.ConflictResolution, error) {
+		if f.Path == "a.txt" {
+			return gitclient.ResolveTheirs, nil
+		}
+		return gitclient.ResolveUnresolved, nil
+	})
+	require.Error(t, err, "rebase should fail with unresolved conflicts")
+	var conflictErr *gitclient.ConflictError
+	require.True(t, errors.As(err, &conflictErr), "should be a conflict error: %s", err.Error())
+	assert.Equal(t, []gitclient.ConflictedFile{{Path: "b.txt", Type: gitclient.ConflictDeletedByThem}}, conflictErr.Files, "unresolved files")
+
+	branch, err := gitclient.Branch(g, dir)
+	require.NoError(t, err, "failed to get branch")
+	assert.Equal(t, "feature", branch, "branch after aborted rebase")
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "feature\n")
+
+	err = gitclient.RebaseWithResolver(g, dir, "master", gitclient.ResolveAllWith(gitclient.ResolveTheirs))
+	require.NoError(t, err, "failed to rebase")
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "feature\n")
+	assertFileContent(t, filepath.Join(dir, "c.txt"), "master\n")
+	assert.NoFileExists(t, filepath.Join(dir, "b.txt"), "b.txt deleted by the feature commit")
+
+	out, err := g.Command(dir, "rev-list", "--count", "master..feature")
+	require.NoError(t, err)
+	assert.Equal(t, "1", out, "commits on feature after rebase")
+
+	_, err = g.Command(dir, "reset", "--hard", "ORIG_HEAD")
+	require.NoError(t, err, "failed to reset")
+	err = gitclient.RebaseWithResolver(g, dir, "master", gitclient.ResolveAllWith(gitclient.ResolveOurs))
+	require.NoError(t, err, "failed to rebase")
+	out, err = g.Command(dir, "rev-list", "--count", "master..feature")
+	require.NoError(t, err)
+	assert.Equal(t, "0", out, "the feature commit should be skipped when resolving to ours")
+}
+
+func TestRebaseWithFakeGit(t *testing.T) {
+	gitURL := "https://github.com/myorg/myrepo.git"
+	g := fakegit.NewFakeGit()
+	_, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create repository")
+	dir, err := gitclient.CloneToDir(g, gitURL, filepath.Join(t.TempDir(), "myrepo"))
+	require.NoError(t, err, "failed to clone")
+
+	err = gitclient.RebaseWithResolver(g, dir, "origin/master", gitclient.ResolveAllWith(gitclient.ResolveTheirs))
+	require.Error(t, err, "rebase should not be supported")
+	assert.False(t, gitclient.IsConflictError(err), "should not be a conflict error")
+	assert.Contains(t, err.Error(), "'rebase' is not supported")
+
+	err = gitclient.RebaseContinue(g, dir)
+	require.Error(t, err, "rebase should not be supported")
+	assert.Contains(t, err.Error(), "'rebase' is not supported")
+}
+
+// createConflictingBranches creates a repository with a master and feature branch which conflict
+func createConflictingBranches(t *testing.T) (gitclient.Interface, string) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "jenkins-x@googlegroups.com")
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	dir := t.TempDir()
+	_, err := g.Command(dir, "init", "--initial-branch=master")
+	require.NoError(t, err, "failed to init")
+	writeAndCommit(t, g, dir, "initial commit", map[string]string{"a.txt": "initial\n", "b.txt": "initial\n"})
+
+	_, err = g.Command(dir, "checkout", "-b", "feature")
+	require.NoError(t, err, "failed to create branch")
+	require.NoError(t, os.Remove(filepath.Join(dir, "b.txt")))
+	writeAndCommit(t, g, dir, "feature change", map[string]string{"a.txt": "feature\n"})
+
+	_, err = g.Command(dir, "checkout", "master")
+	require.NoError(t, err, "failed to checkout master")
+	writeAndCommit(t, g, dir, "master change", map[string]string{"a.txt": "master\n", "b.txt": "master\n", "c.txt": "master\n"})
+	return g, dir
+}
+
+func writeAndCommit(t *testing.T, g gitclient.Interface, dir, message string, files map[string]string) {
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	_, err := gitclient.AddAndCommitFiles(g, dir, message)
+	require.NoError(t, err, "failed to commit")
+}
+
+func assertFileContent(t *testing.T, path, expected string) {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err, "failed to read file %s", path)
+	assert.Equal(t, expected, string(data), "content of %s", path)
+}
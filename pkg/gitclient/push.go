@@ -0,0 +1,144 @@
+package gitclient
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// DefaultPushRetries the default number of times a rejected push is retried
+	DefaultPushRetries = 3
+)
+
+// pushRejectedMessages the git push output indicating the remote branch has moved on
+var pushRejectedMessages = []string{"[rejected]", "non-fast-forward", "fetch first", "stale info"}
+
+// PushRejectedError the error returned when a push is rejected because the remote branch contains commits
+// which are not in the local branch or does not match the expected lease
+type PushRejectedError struct {
+	// Remote the remote pushed to
+	Remote string
+	// RemoteBranch the remote branch pushed to
+	RemoteBranch string
+	// Err the underlying error from git
+	Err error
+}
+
+// Error returns the error message
+func (e *PushRejectedError) Error() string {
+	return fmt.Sprintf("push to %s branch %s was rejected: %s", e.Remote, e.RemoteBranch, e.Err.Error())
+}
+
+// Unwrap returns the underlying error
+func (e *PushRejectedError) Unwrap() error {
+	return e.Err
+}
+
+// IsPushRejected returns true if the error is caused by a rejected push
+func IsPushRejected(err error) bool {
+	var rejectedError *PushRejectedError
+	return errors.As(err, &rejectedError)
+}
+
+// pushError returns a PushRejectedError if the push output indicates the push was rejected otherwise the error
+func pushError(remote, remoteBranch string, err error) error {
+	text := err.Error()
+	for _, m := range pushRejectedMessages {
+		if strings.Contains(text, m) {
+			return &PushRejectedError{Remote: remote, RemoteBranch: remoteBranch, Err: err}
+		}
+	}
+	return fmt.Errorf("failed to push to %s branch %s: %w", remote, remoteBranch, err)
+}
+
+// PushWithLease pushes the local branch to the remote branch using --force-with-lease so that the remote branch
+// is only overwritten if it still points at the expected SHA. If the expected SHA is empty the remote tracking
+// branch is used as the lease. A PushRejectedError is returned if the lease does not match
+func PushWithLease(g Interface, dir, remote, localBranch, remoteBranch, expectedSHA string) error {
+	lease := "--force-with-lease=" + remoteBranch
+	if expectedSHA != "" {
+		lease += ":" + expectedSHA
+	}
+	_, err := g.Command(dir, "push", lease, remote, localBranch+":"+remoteBranch)
+	if err != nil {
+		return pushError(remote, remoteBranch, err)
+	}
+	return nil
+}
+
+// PushRetryOptions the options for pushing with retries
+type PushRetryOptions struct {
+	// Remote the remote to push to which defaults to origin
+	Remote string
+	// RemoteBranch the remote branch to push to which defaults to the current branch
+	RemoteBranch string
+	// MaxRetries the maximum number of times a rejected push is retried which defaults to DefaultPushRetries
+	MaxRetries int
+	// RetryDelay the optional delay before fetching and rebasing after a rejected push
+	RetryDelay time.Duration
+	// Resolver the optional resolver used for conflicts when rebasing. If nil any conflict fails the push
+	Resolver ConflictResolver
+}
+
+// PushWithRetry pushes the current branch to the remote branch. If the push is rejected because the remote branch has
+// new commits then they are fetched, the local commits are rebased onto them and the push is retried.
+//
+// If the rebase conflicts it is aborted and a ConflictError is returned. If the push is still rejected after
+// the maximum number of retries the PushRejectedError is returned
+func PushWithRetry(g Interface, dir string, o *PushRetryOptions) error {
+	if o == nil {
+		o = &PushRetryOptions{}
+	}
+	remote := o.Remote
+	if remote == "" {
+		remote = "origin"
+	}
+	localBranch, err := Branch(g, dir)
+	if err != nil {
+		return fmt.Errorf("failed to find the current branch in dir %s: %w", dir, err)
+	}
+	remoteBranch := o.RemoteBranch
+	if remoteBranch == "" {
+		remoteBranch = localBranch
+	}
+	maxRetries := o.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultPushRetries
+	}
+	resolver := o.Resolver
+	if resolver == nil {
+		resolver = ResolveAllWith(ResolveUnresolved)
+	}
+
+	for attempt := 0; ; attempt++ {
+		_, err = g.Command(dir, "push", remote, localBranch+":"+remoteBranch)
+		if err == nil {
+			return nil
+		}
+		err = pushError(remote, remoteBranch, err)
+		if !IsPushRejected(err) || attempt >= maxRetries {
+			return err
+		}
+		log.Logger().Infof("push to %s branch %s was rejected so rebasing and retrying", remote, remoteBranch)
+		if o.RetryDelay > 0 {
+			time.Sleep(o.RetryDelay)
+		}
+
+		_, err = g.Command(dir, "fetch", remote, remoteBranch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s branch %s: %w", remote, remoteBranch, err)
+		}
+		err = RebaseWithResolver(g, dir, "FETCH_HEAD", resolver)
+		if err != nil {
+			var conflictErr *ConflictError
+			if errors.As(err, &conflictErr) {
+				conflictErr.Revision = remote + "/" + remoteBranch
+			}
+			return err
+		}
+	}
+}
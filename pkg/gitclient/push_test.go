@@ -0,0 +1,167 @@
+//go:build unit
+// +build unit
+
+package gitclient_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushWithLease(t *testing.T) {
+	g, dir, otherDir := createClones(t)
+
+	expectedSHA, err := gitclient.GetLatestCommitSha(g, dir)
+	require.NoError(t, err)
+
+	writeAndCommit(t, g, otherDir, "other change", map[string]string{"other.txt": "other\n"})
+	require.NoError(t, gitclient.Push(g, otherDir, "origin", false, "master"), "failed to push other change")
+
+	_, err = g.Command(dir, "commit", "--amend", "-m", "rewritten")
+	require.NoError(t, err, "failed to amend")
+
+	err = gitclient.PushWithLease(g, dir, "origin", "master", "master", expectedSHA)
+	require.Error(t, err, "push with stale lease should fail")
+	assert.True(t, gitclient.IsPushRejected(err), "should be rejected: %s", err.Error())
+
+	otherSHA, err := gitclient.GetLatestCommitSha(g, otherDir)
+	require.NoError(t, err)
+	err = gitclient.PushWithLease(g, dir, "origin", "master", "master", otherSHA)
+	require.NoError(t, err, "push with the current lease should overwrite the remote branch")
+
+	remoteSHA, err := g.Command(dir, "ls-remote", "origin", "refs/heads/master")
+	require.NoError(t, err)
+	localSHA, err := gitclient.GetLatestCommitSha(g, dir)
+	require.NoError(t, err)
+	assert.Contains(t, remoteSHA, localSHA, "remote branch should be the rewritten commit")
+}
+
+func TestPushWithRetry(t *testing.T) {
+	g, dir, otherDir := createClones(t)
+
+	writeAndCommit(t, g, otherDir, "other change", map[string]string{"other.txt": "other\n"})
+	require.NoError(t, gitclient.Push(g, otherDir, "origin", false, "master"), "failed to push other change")
+	writeAndCommit(t, g, dir, "local change", map[string]string{"local.txt": "local\n"})
+
+	err := gitclient.PushWithRetry(g, dir, nil)
+	require.NoError(t, err, "failed to push with retry")
+
+	out, err := g.Command(dir, "log", "--format=%s", "origin/master")
+	require.NoError(t, err)
+	assert.Equal(t, "local change\nother change\ninitial commit", out, "remote history")
+
+	// a competing pipeline pushes before every push so the retries are exhausted
+	racer := &racingGit{Interface: g, dir: otherDir, t: t}
+	writeAndCommit(t, g, dir, "another change", map[string]string{"local.txt": "another\n"})
+	err = gitclient.PushWithRetry(racer, dir, &gitclient.PushRetryOptions{MaxRetries: 2})
+	require.Error(t, err, "push should fail after the retries")
+	assert.True(t, gitclient.IsPushRejected(err), "should be rejected: %s", err.Error())
+	assert.Equal(t, 3, racer.pushes, "number of pushes")
+}
+
+func TestPushWithRetryConflict(t *testing.T) {
+	g, dir, otherDir := createClones(t)
+
+	writeAndCommit(t, g, otherDir, "other change", map[string]string{"a.txt": "other\n"})
+	require.NoError(t, gitclient.Push(g, otherDir, "origin", false, "master"), "failed to push other change")
+	writeAndCommit(t, g, dir, "local change", map[string]string{"a.txt": "local\n"})
+
+	err := gitclient.PushWithRetry(g, dir, nil)
+	require.Error(t, err, "push should fail due to the conflict")
+	var conflictErr *gitclient.ConflictError
+	require.True(t, errors.As(err, &conflictErr), "should be a conflict error: %s", err.Error())
+	assert.Equal(t, "origin/master", conflictErr.Revision, "Revision")
+	assert.Equal(t, []gitclient.ConflictedFile{{Path: "a.txt", Type: gitclient.ConflictBothModified}}, conflictErr.Files, "Files")
+	assertFileContent(t, filepath.Join(dir, "a.txt"), "local\n")
+
+	err = gitclient.PushWithRetry(g, dir, &gitclient.PushRetryOptions{Resolver: gitclient.ResolveAllWith(gitclient.ResolveTheirs)})
+	require.NoError(t, err, "push should succeed when resolving the conflict")
+	out, err := g.Command(dir, "show", "origin/master:a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "local", out, "remote content")
+}
+
+func TestPushWithRetryHookDeclined(t *testing.T) {
+	g, dir, _ := createClones(t)
+	remoteDir := filepath.Join(filepath.Dir(dir), "remote.git")
+	hook := filepath.Join(remoteDir, "hooks", "pre-receive")
+	require.NoError(t, os.WriteFile(hook, []byte("#!/bin/sh\necho 'branch is protected' >&2\nexit 1\n"), 0o700))
+	writeAndCommit(t, g, dir, "local change", map[string]string{"local.txt": "local\n"})
+
+	counter := &pushCountingGit{Interface: g}
+	err := gitclient.PushWithRetry(counter, dir, nil)
+	require.Error(t, err, "push should be declined by the hook")
+	assert.Contains(t, err.Error(), "pre-receive hook declined")
+	assert.False(t, gitclient.IsPushRejected(err), "a declined push should not be rejected: %s", err.Error())
+	assert.Equal(t, 1, counter.pushes, "a declined push should not be retried")
+}
+
+// pushCountingGit counts the number of pushes
+type pushCountingGit struct {
+	gitclient.Interface
+	pushes int
+}
+
+func (c *pushCountingGit) Command(dir string, args ...string) (string, error) {
+	if len(args) > 0 && args[0] == "push" {
+		c.pushes++
+	}
+	return c.Interface.Command(dir, args...)
+}
+
+// racingGit pushes a new commit from another clone before each push
+type racingGit struct {
+	gitclient.Interface
+	dir    string
+	t      *testing.T
+	pushes int
+}
+
+func (r *racingGit) Command(dir string, args ...string) (string, error) {
+	if len(args) > 0 && args[0] == "push" {
+		r.pushes++
+		_, err := r.Interface.Command(r.dir, "pull", "--rebase")
+		require.NoError(r.t, err, "failed to pull racing clone")
+		writeAndCommit(r.t, r.Interface, r.dir, "racing change", map[string]string{fmt.Sprintf("race-%d.txt", r.pushes): "race\n"})
+		_, err = r.Interface.Command(r.dir, "push", "origin", "master")
+		require.NoError(r.t, err, "failed to push racing change")
+	}
+	return r.Interface.Command(dir, args...)
+}
+
+// createClones creates a bare remote repository and returns two clones of it
+func createClones(t *testing.T) (gitclient.Interface, string, string) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "jenkins-x@googlegroups.com")
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	tmpDir := t.TempDir()
+	remoteDir := filepath.Join(tmpDir, "remote.git")
+	_, err := g.Command(tmpDir, "init", "--bare", "--initial-branch=master", remoteDir)
+	require.NoError(t, err, "failed to init remote")
+
+	dir := filepath.Join(tmpDir, "clone")
+	_, err = g.Command(tmpDir, "clone", remoteDir, dir)
+	require.NoError(t, err, "failed to clone")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("initial\n"), 0o600))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "initial commit")
+	require.NoError(t, err, "failed to commit")
+	_, err = g.Command(dir, "push", "-u", "origin", "master")
+	require.NoError(t, err, "failed to push")
+
+	otherDir := filepath.Join(tmpDir, "other")
+	_, err = g.Command(tmpDir, "clone", remoteDir, otherDir)
+	require.NoError(t, err, "failed to clone")
+	return g, dir, otherDir
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/jenkins-x/jx-helpers/v3/pkg/termcolor"
 
 	"github.com/jenkins-x/jx-api/v4/pkg/util"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitconfig"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/homedir"
 	"github.com/jenkins-x/jx-helpers/v3/pkg/kube"
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
@@ -17,7 +18,8 @@ var info = termcolor.ColorInfo
 
 // EnsureUserAndEmailSetup returns the user name and email for the gitter
 // lazily setting them for git repository in dir if they are blank. The values used are either the given values,
-// if they are empty environment variables `GIT_AUTHOR_NAME` and `GIT_AUTHOR_EMAIL` or default values
+// if they are empty environment variables `GIT_AUTHOR_NAME` and `GIT_AUTHOR_EMAIL` or default values.
+// Commit signing is also configured for the repository if `GIT_SIGNING_KEY` is set
 func EnsureUserAndEmailSetup(gitter Interface, dir string, gitUserName string, gitUserEmail string) (string, string, error) {
 	userName, _ := gitter.Command(dir, "config", "--get", "user.name")
 	userEmail, _ := gitter.Command(dir, "config", "--get", "user.email")
@@ -55,12 +57,14 @@ func EnsureUserAndEmailSetup(gitter Interface, dir string, gitUserName string, g
 			return userName, userEmail, fmt.Errorf("Failed to set the git email to %s: %w", userEmail, err)
 		}
 	}
-	return userName, userEmail, nil
+	return userName, userEmail, setupSigningFromEnvironment(gitter, dir, false)
 }
 
 // SetUserAndEmail sets the user and email globally if they have not been set for dir
 // The values used are either the given values, if they are empty environment variables `GIT_AUTHOR_NAME` and
-// `GIT_AUTHOR_EMAIL` or default values
+// `GIT_AUTHOR_EMAIL` or default values. Commit signing is also configured globally if `GIT_SIGNING_KEY` is set.
+//
+// If the gitter is nil the global git configuration file is updated directly so that no git binary is required
 func SetUserAndEmail(gitter Interface, dir string, gitUserName string, gitUserEmail string, assumeInCluster bool) (string, string, error) {
 	userName := ""
 	userEmail := ""
@@ -69,12 +73,12 @@ func SetUserAndEmail(gitter Interface, dir string, gitUserName string, gitUserEm
 		userEmail = gitUserEmail
 	} else {
 		// lets load the current values and if they are specified lets not modify them as they are probably correct
-		userName, _ = gitter.Command(dir, "config", "--global", "--get", "user.name")
-		userEmail, _ = gitter.Command(dir, "config", "--global", "--get", "user.email")
+		userName = getGlobalConfig(gitter, dir, "user.name")
+		userEmail = getGlobalConfig(gitter, dir, "user.email")
 
 		if userName != "" && userEmail != "" {
 			log.Logger().Infof("have git user name %s and email %s setup already so not going to modify them", userName, userEmail)
-			return userName, userEmail, nil
+			return userName, userEmail, setupSigningFromEnvironment(gitter, dir, true)
 		}
 	}
 	if userName == "" {
@@ -92,12 +96,12 @@ func SetUserAndEmail(gitter Interface, dir string, gitUserName string, gitUserEm
 			}
 		}
 	}
-	_, err := gitter.Command(dir, "config", "--global", "--add", "user.name", userName)
+	err := setGlobalConfig(gitter, dir, "user.name", userName)
 	if err != nil {
 		return userName, userEmail, fmt.Errorf("Failed to set the git username to %s: %w", userName, err)
 	}
 	if userEmail == "" {
-		userName = os.Getenv("GIT_USER_EMAIL")
+		userEmail = os.Getenv("GIT_USER_EMAIL")
 		if userEmail == "" {
 			userEmail = os.Getenv("GIT_AUTHOR_EMAIL")
 		}
@@ -105,18 +109,51 @@ func SetUserAndEmail(gitter Interface, dir string, gitUserName string, gitUserEm
 			userEmail = DefaultGitUserEmail
 		}
 	}
-	_, err = gitter.Command(dir, "config", "--global", "--add", "user.email", userEmail)
+	err = setGlobalConfig(gitter, dir, "user.email", userEmail)
 	if err != nil {
 		return userName, userEmail, fmt.Errorf("Failed to set the git email to %s: %w", userEmail, err)
 	}
 	log.Logger().Infof("setup git user %s email %s", info(userName), info(userEmail))
-	return userName, userEmail, nil
+	return userName, userEmail, setupSigningFromEnvironment(gitter, dir, true)
+}
+
+// setupSigningFromEnvironment configures commit signing if a signing key is specified via the environment
+func setupSigningFromEnvironment(gitter Interface, dir string, global bool) error {
+	cfg := SigningConfigFromEnvironment()
+	if cfg == nil {
+		return nil
+	}
+	return SetupSigning(gitter, dir, global, cfg)
+}
+
+// getGlobalConfig returns the global git configuration value. If the gitter is nil the configuration files are read
+func getGlobalConfig(gitter Interface, dir, key string) string {
+	if gitter == nil {
+		value, _, err := gitconfig.Lookup(dir, key, gitconfig.ScopeXDG, gitconfig.ScopeGlobal)
+		if err != nil {
+			log.Logger().Warnf("failed to read the global git configuration: %s", err.Error())
+		}
+		return value
+	}
+	value, _ := gitter.Command(dir, "config", "--global", "--get", key)
+	return value
+}
+
+// setGlobalConfig sets the global git configuration value replacing any existing values. If the gitter is nil the
+// global configuration file is updated directly
+func setGlobalConfig(gitter Interface, dir, key, value string) error {
+	if gitter == nil {
+		return gitconfig.SetValue(gitconfig.ScopeGlobal, dir, key, value)
+	}
+	_, err := gitter.Command(dir, "config", "--global", "--replace-all", key, value)
+	return err
 }
 
 // SetCredentialHelper sets the credential store so that we detect the ~/git/credentials file for
 // defaulting access tokens.
 //
-// If the dir parameter is blank we will use the home dir
+// If the dir parameter is blank we will use the home dir. If the gitter is nil the global git configuration
+// file is updated directly so that no git binary is required
 func SetCredentialHelper(gitter Interface, dir string) error {
 	if dir == "" {
 		dir = homedir.HomeDir()
@@ -126,7 +163,11 @@ func SetCredentialHelper(gitter Interface, dir string) error {
 		return fmt.Errorf("failed to make sure the home directory %s was created: %w", dir, err)
 	}
 
-	_, err = gitter.Command(dir, "config", "--global", "credential.helper", "store")
+	if gitter == nil {
+		err = gitconfig.SetValue(gitconfig.ScopeGlobal, dir, "credential.helper", DefaultCredentialHelper)
+	} else {
+		_, err = gitter.Command(dir, "config", "--global", "credential.helper", DefaultCredentialHelper)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to setup git: %w", err)
 	}
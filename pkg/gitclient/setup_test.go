@@ -0,0 +1,87 @@
+//go:build unit
+// +build unit
+
+package gitclient_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetupWithoutGitBinary(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_GLOBAL", "")
+	t.Setenv("GIT_USER_NAME", "")
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv(gitclient.EnvSigningKey, "/secrets/signing-key")
+	t.Setenv(gitclient.EnvSigningFormat, string(gitclient.SigningFormatSSH))
+
+	globalConfig := filepath.Join(home, ".gitconfig")
+	require.NoError(t, os.WriteFile(globalConfig, []byte("# my settings\n[core]\n\teditor = vim\n"), 0o600))
+
+	userName, userEmail, err := gitclient.SetUserAndEmail(nil, home, "", "", false)
+	require.NoError(t, err, "failed to set user and email")
+	assert.Equal(t, "jenkins-x-bot", userName)
+	assert.Equal(t, "jenkins-x@googlegroups.com", userEmail)
+
+	require.NoError(t, gitclient.SetCredentialHelper(nil, home), "failed to set credential helper")
+
+	f, err := gitconfig.Load(globalConfig)
+	require.NoError(t, err)
+	name, email := f.User()
+	assert.Equal(t, "jenkins-x-bot", name)
+	assert.Equal(t, "jenkins-x@googlegroups.com", email)
+	assert.Equal(t, []string{gitclient.DefaultCredentialHelper}, f.CredentialHelpers())
+	value, _ := f.Get("gpg.format")
+	assert.Equal(t, "ssh", value, "gpg.format")
+	value, _ = f.Get("user.signingkey")
+	assert.Equal(t, "/secrets/signing-key", value, "user.signingkey")
+	value, _ = f.Get("core.editor")
+	assert.Equal(t, "vim", value, "existing values should be kept")
+	assert.Contains(t, string(f.Bytes()), "# my settings\n[core]\n\teditor = vim\n", "existing formatting should be kept")
+
+	// the existing values are not modified
+	userName, _, err = gitclient.SetUserAndEmail(nil, home, "", "", false)
+	require.NoError(t, err)
+	assert.Equal(t, "jenkins-x-bot", userName)
+}
+
+func TestSetUserAndEmailReplacesValues(t *testing.T) {
+	testCases := []struct {
+		name   string
+		gitter gitclient.Interface
+	}{
+		{name: "git", gitter: cli.NewCLIClient("", cmdrunner.QuietCommandRunner)},
+		{name: "nil"},
+	}
+	for _, tc := range testCases {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+		t.Setenv("GIT_CONFIG_GLOBAL", "")
+		require.NoError(t, os.Unsetenv("GIT_CONFIG_GLOBAL"))
+		t.Setenv(gitclient.EnvSigningKey, "")
+
+		globalConfig := filepath.Join(home, ".gitconfig")
+		require.NoError(t, os.WriteFile(globalConfig, []byte("[user]\n\tname = old\n\tname = older\n"), 0o600))
+
+		_, _, err := gitclient.SetUserAndEmail(tc.gitter, home, "new", "new@example.com", true)
+		require.NoError(t, err, "failed to set user and email for %s", tc.name)
+
+		f, err := gitconfig.Load(globalConfig)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"new"}, f.GetAll("user.name"), "user.name for %s", tc.name)
+		assert.Equal(t, []string{"new@example.com"}, f.GetAll("user.email"), "user.email for %s", tc.name)
+	}
+}
@@ -0,0 +1,275 @@
+package gitclient
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitconfig"
+	"github.com/jenkins-x/jx-logging/v3/pkg/log"
+)
+
+const (
+	// EnvSigningKey the environment variable for the key used to sign commits which is a GPG key ID,
+	// the path of a SSH key or a literal SSH public key
+	EnvSigningKey = "GIT_SIGNING_KEY"
+
+	// EnvSigningFormat the environment variable for the format of the signing key which defaults to openpgp
+	EnvSigningFormat = "GIT_SIGNING_FORMAT"
+
+	// EnvSigningProgram the environment variable for an optional external program used to sign commits
+	EnvSigningProgram = "GIT_SIGNING_PROGRAM"
+
+	// EnvSigningAllowedSigners the environment variable for the SSH allowed signers file used to verify signatures
+	EnvSigningAllowedSigners = "GIT_SIGNING_ALLOWED_SIGNERS"
+)
+
+// SigningFormat the format of the signatures created by git
+type SigningFormat string
+
+const (
+	// SigningFormatOpenPGP signs using GPG
+	SigningFormatOpenPGP SigningFormat = "openpgp"
+
+	// SigningFormatSSH signs using a SSH key
+	SigningFormatSSH SigningFormat = "ssh"
+
+	// SigningFormatX509 signs using a X.509 certificate such as via gpgsm or gitsign
+	SigningFormatX509 SigningFormat = "x509"
+)
+
+// SigningConfig the configuration for signing commits and tags
+type SigningConfig struct {
+	// Format the format of the signatures which defaults to SigningFormatOpenPGP
+	Format SigningFormat
+	// Key the GPG key ID, the path of the SSH private or public key or a literal SSH public key
+	Key string
+	// Program the optional external program used to create and verify the signatures instead of gpg or ssh-keygen
+	Program string
+	// AllowedSignersFile the SSH allowed signers file used to verify SSH signatures
+	AllowedSignersFile string
+}
+
+// SigningConfigFromEnvironment returns the signing configuration from the $GIT_SIGNING_KEY,
+// $GIT_SIGNING_FORMAT, $GIT_SIGNING_PROGRAM and $GIT_SIGNING_ALLOWED_SIGNERS environment variables
+// or nil if no signing key is specified
+func SigningConfigFromEnvironment() *SigningConfig {
+	key := os.Getenv(EnvSigningKey)
+	if key == "" {
+		return nil
+	}
+	return &SigningConfig{
+		Format:             SigningFormat(os.Getenv(EnvSigningFormat)),
+		Key:                key,
+		Program:            os.Getenv(EnvSigningProgram),
+		AllowedSignersFile: os.Getenv(EnvSigningAllowedSigners),
+	}
+}
+
+// format returns the signing format defaulting to openpgp
+func (c *SigningConfig) format() SigningFormat {
+	if c.Format == "" {
+		return SigningFormatOpenPGP
+	}
+	return c.Format
+}
+
+// Validate returns an error if the configuration is invalid
+func (c *SigningConfig) Validate() error {
+	switch c.format() {
+	case SigningFormatOpenPGP, SigningFormatSSH, SigningFormatX509:
+	default:
+		return fmt.Errorf("unsupported git signing format %s: must be one of %s, %s or %s", c.Format,
+			SigningFormatOpenPGP, SigningFormatSSH, SigningFormatX509)
+	}
+	if c.Key == "" {
+		return fmt.Errorf("missing git signing key")
+	}
+	return nil
+}
+
+// ConfigValues returns the git configuration values to enable signing of commits and tags
+func (c *SigningConfig) ConfigValues() map[string]string {
+	format := c.format()
+	values := map[string]string{
+		"gpg.format":      string(format),
+		"user.signingkey": c.Key,
+		"commit.gpgsign":  "true",
+		"tag.gpgsign":     "true",
+	}
+	if c.Program != "" {
+		values["gpg."+string(format)+".program"] = c.Program
+	}
+	if c.AllowedSignersFile != "" {
+		values["gpg.ssh.allowedSignersFile"] = c.AllowedSignersFile
+	}
+	return values
+}
+
+// SetupSigning configures the repository in dir, or the global configuration if global is true,
+// to sign commits and tags. If the gitter is nil the configuration file is updated directly
+func SetupSigning(gitter Interface, dir string, global bool, cfg *SigningConfig) error {
+	err := cfg.Validate()
+	if err != nil {
+		return err
+	}
+	values := cfg.ConfigValues()
+	if gitter == nil {
+		err = setupSigningConfigFile(dir, global, values)
+		if err != nil {
+			return err
+		}
+		log.Logger().Infof("setup git %s commit signing with key %s", info(string(cfg.format())), info(cfg.Key))
+		return nil
+	}
+	for _, k := range sortedKeys(values) {
+		args := []string{"config"}
+		if global {
+			args = append(args, "--global")
+		}
+		args = append(args, k, values[k])
+		_, err = gitter.Command(dir, args...)
+		if err != nil {
+			return fmt.Errorf("failed to set git %s: %w", k, err)
+		}
+	}
+	log.Logger().Infof("setup git %s commit signing with key %s", info(string(cfg.format())), info(cfg.Key))
+	return nil
+}
+
+// setupSigningConfigFile sets the signing configuration values in the local or global configuration file
+func setupSigningConfigFile(dir string, global bool, values map[string]string) error {
+	scope := gitconfig.ScopeLocal
+	if global {
+		scope = gitconfig.ScopeGlobal
+	}
+	f, err := gitconfig.LoadScope(scope, dir)
+	if err != nil {
+		return err
+	}
+	for _, k := range sortedKeys(values) {
+		err = f.Set(k, values[k])
+		if err != nil {
+			return err
+		}
+	}
+	return f.Save()
+}
+
+// SignatureStatus the status of a commit signature as reported by the git %G? format
+type SignatureStatus string
+
+const (
+	// SignatureGood a good and valid signature
+	SignatureGood SignatureStatus = "G"
+	// SignatureBad a bad signature
+	SignatureBad SignatureStatus = "B"
+	// SignatureUnknownValidity a good signature with unknown validity
+	SignatureUnknownValidity SignatureStatus = "U"
+	// SignatureExpired a good signature that has expired
+	SignatureExpired SignatureStatus = "X"
+	// SignatureExpiredKey a good signature made by an expired key
+	SignatureExpiredKey SignatureStatus = "Y"
+	// SignatureRevokedKey a good signature made by a revoked key
+	SignatureRevokedKey SignatureStatus = "R"
+	// SignatureCannotCheck the signature cannot be checked such as due to a missing key
+	SignatureCannotCheck SignatureStatus = "E"
+	// SignatureNone the commit is not signed
+	SignatureNone SignatureStatus = "N"
+)
+
+// CommitSignature the signature of a commit
+type CommitSignature struct {
+	// SHA the SHA of the commit
+	SHA string
+	// Status the status of the signature
+	Status SignatureStatus
+	// Signer the name of the signer
+	Signer string
+	// Key the key used to sign the commit
+	Key string
+	// Fingerprint the fingerprint of the key used to sign the commit
+	Fingerprint string
+}
+
+// IsSigned returns true if the commit is signed
+func (s *CommitSignature) IsSigned() bool {
+	return s.Status != SignatureNone && s.Status != ""
+}
+
+// IsValid returns true if the signature is good and made by a trusted key
+func (s *CommitSignature) IsValid() bool {
+	return s.Status == SignatureGood
+}
+
+// IsValidAllowingUnknownTrust returns true if the signature is good even if the trust of the GPG key is unknown
+// such as when the key has been imported into the keyring without being trusted
+func (s *CommitSignature) IsValidAllowingUnknownTrust() bool {
+	return s.IsValid() || s.Status == SignatureUnknownValidity
+}
+
+// SignatureError the error returned when a commit does not have a valid signature
+type SignatureError struct {
+	// Signature the signature of the commit
+	Signature *CommitSignature
+}
+
+// Error returns the error message
+func (e *SignatureError) Error() string {
+	if !e.Signature.IsSigned() {
+		return fmt.Sprintf("commit %s is not signed", e.Signature.SHA)
+	}
+	return fmt.Sprintf("commit %s does not have a valid signature: status %s key %s", e.Signature.SHA, e.Signature.Status, e.Signature.Key)
+}
+
+// IsSignatureError returns true if the error is caused by a commit not having a valid signature
+func IsSignatureError(err error) bool {
+	var signatureErr *SignatureError
+	return errors.As(err, &signatureErr)
+}
+
+// GetCommitSignature returns the signature of the given revision
+func GetCommitSignature(gitter Interface, dir, rev string) (*CommitSignature, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	out, err := gitter.Command(dir, "log", "-1", "--format=%H%x00%G?%x00%GS%x00%GK%x00%GF", rev, "--")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the signature of %s: %w", rev, err)
+	}
+	fields := strings.Split(out, "\x00")
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("failed to parse the signature of %s from %q", rev, out)
+	}
+	return &CommitSignature{
+		SHA:         fields[0],
+		Status:      SignatureStatus(fields[1]),
+		Signer:      fields[2],
+		Key:         fields[3],
+		Fingerprint: fields[4],
+	}, nil
+}
+
+// VerifyCommit returns the signature of the given revision or a SignatureError if it does not have a valid signature
+func VerifyCommit(gitter Interface, dir, rev string) (*CommitSignature, error) {
+	signature, err := GetCommitSignature(gitter, dir, rev)
+	if err != nil {
+		return nil, err
+	}
+	if !signature.IsValid() {
+		return signature, &SignatureError{Signature: signature}
+	}
+	return signature, nil
+}
+
+// sortedKeys returns the keys of the map in order
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,216 @@
+//go:build unit
+// +build unit
+
+package gitclient_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gogit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const signerEmail = "jenkins-x@googlegroups.com"
+
+func TestSSHSigning(t *testing.T) {
+	g, dir := createSigningRepo(t)
+	keyFile, allowedSigners := createSSHKey(t)
+
+	writeAndCommit(t, g, dir, "unsigned commit", map[string]string{"a.txt": "a\n"})
+	_, err := gitclient.VerifyCommit(g, dir, "")
+	require.Error(t, err, "unsigned commit should not be valid")
+	assert.True(t, gitclient.IsSignatureError(err), "should be a signature error: %s", err.Error())
+	assert.Contains(t, err.Error(), "is not signed")
+
+	t.Setenv(gitclient.EnvSigningKey, keyFile)
+	t.Setenv(gitclient.EnvSigningFormat, string(gitclient.SigningFormatSSH))
+	t.Setenv(gitclient.EnvSigningAllowedSigners, allowedSigners)
+	_, _, err = gitclient.EnsureUserAndEmailSetup(g, dir, "jenkins-x-bot", signerEmail)
+	require.NoError(t, err, "failed to setup git")
+	out, err := g.Command(dir, "config", "--get", "commit.gpgsign")
+	require.NoError(t, err)
+	assert.Equal(t, "true", out, "commit.gpgsign")
+
+	writeAndCommit(t, g, dir, "signed commit", map[string]string{"a.txt": "b\n"})
+	signature, err := gitclient.VerifyCommit(g, dir, "HEAD")
+	require.NoError(t, err, "commit should be signed")
+	assert.Equal(t, gitclient.SignatureGood, signature.Status, "Status")
+	assert.Equal(t, signerEmail, signature.Signer, "Signer")
+	assert.NotEmpty(t, signature.Fingerprint, "Fingerprint")
+
+	_, err = gitclient.VerifyCommit(g, dir, "HEAD~1")
+	assert.True(t, gitclient.IsSignatureError(err), "previous commit should not be signed")
+}
+
+func TestSigningProgramFromEnvironment(t *testing.T) {
+	g, dir := createSigningRepo(t)
+	keyFile, allowedSigners := createSSHKey(t)
+
+	// an external program which wraps ssh-keygen
+	program := filepath.Join(t.TempDir(), "sign.sh")
+	require.NoError(t, os.WriteFile(program, []byte("#!/bin/sh\nexec ssh-keygen \"$@\"\n"), 0o700))
+
+	t.Setenv(gitclient.EnvSigningKey, keyFile)
+	t.Setenv(gitclient.EnvSigningFormat, string(gitclient.SigningFormatSSH))
+	t.Setenv(gitclient.EnvSigningProgram, program)
+	t.Setenv(gitclient.EnvSigningAllowedSigners, allowedSigners)
+	_, _, err := gitclient.EnsureUserAndEmailSetup(g, dir, "jenkins-x-bot", signerEmail)
+	require.NoError(t, err, "failed to setup git")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o600))
+	_, err = g.Command(dir, "add", "a.txt")
+	require.NoError(t, err)
+	require.NoError(t, gitclient.CommitIfChanges(g, dir, "signed commit"))
+
+	signature, err := gitclient.VerifyCommit(g, dir, "")
+	require.NoError(t, err, "commit should be signed")
+	assert.Equal(t, signerEmail, signature.Signer, "Signer")
+}
+
+func TestCommitWithSigningKeyInEnvironment(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", signerEmail)
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", signerEmail)
+	t.Setenv(gitclient.EnvSigningKey, "/secrets/signing-key")
+
+	gitURL := "https://github.com/myorg/myrepo.git"
+	fake := fakegit.NewFakeGit()
+	_, err := fake.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create repository")
+	fakeDir, err := gitclient.CloneToDir(fake, gitURL, filepath.Join(t.TempDir(), "myrepo"))
+	require.NoError(t, err, "failed to clone")
+
+	goGit := gogit.NewGoGitClient(nil)
+	goGitDir := t.TempDir()
+	_, err = goGit.Command(goGitDir, "init")
+	require.NoError(t, err, "failed to init")
+
+	clients := map[string]struct {
+		g   gitclient.Interface
+		dir string
+	}{
+		"fakegit": {g: fake, dir: fakeDir},
+		"gogit":   {g: goGit, dir: goGitDir},
+	}
+	for name, c := range clients {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(filepath.Join(c.dir, "a.txt"), []byte("a\n"), 0o600))
+			changed, err := gitclient.AddAndCommitFiles(c.g, c.dir, "add a")
+			require.NoError(t, err, "failed to commit")
+			assert.True(t, changed, "changed")
+
+			require.NoError(t, os.WriteFile(filepath.Join(c.dir, "b.txt"), []byte("b\n"), 0o600))
+			_, err = c.g.Command(c.dir, "add", "b.txt")
+			require.NoError(t, err)
+			require.NoError(t, gitclient.CommitIfChanges(c.g, c.dir, "add b"))
+		})
+	}
+}
+
+func TestGPGSigning(t *testing.T) {
+	if _, err := exec.LookPath("gpg"); err != nil {
+		t.Skip("gpg is not installed")
+	}
+	g, dir := createSigningRepo(t)
+	gnupgHome, err := os.MkdirTemp("", "gnupg-")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		exec.Command("gpgconf", "--homedir", gnupgHome, "--kill", "gpg-agent").Run() //nolint:errcheck
+		os.RemoveAll(gnupgHome)                                                      //nolint:errcheck
+	})
+	t.Setenv("GNUPGHOME", gnupgHome)
+	out, err := exec.Command("gpg", "--batch", "--passphrase", "", "--quick-gen-key", "jenkins-x-bot <"+signerEmail+">", "ed25519", "sign", "never").CombinedOutput()
+	if err != nil {
+		t.Skipf("failed to generate gpg key: %s", string(out))
+	}
+	out, err = exec.Command("gpg", "--batch", "--list-secret-keys", "--with-colons").Output()
+	require.NoError(t, err)
+	keyID := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Split(line, ":")
+		if fields[0] == "fpr" {
+			keyID = fields[9]
+			break
+		}
+	}
+	require.NotEmpty(t, keyID, "failed to find the gpg key")
+
+	err = gitclient.SetupSigning(g, dir, false, &gitclient.SigningConfig{Key: keyID})
+	require.NoError(t, err)
+	writeAndCommit(t, g, dir, "signed commit", map[string]string{"a.txt": "a\n"})
+
+	signature, err := gitclient.VerifyCommit(g, dir, "")
+	require.NoError(t, err, "commit should be signed")
+	assert.Equal(t, keyID, signature.Fingerprint, "Fingerprint")
+	assert.Equal(t, "jenkins-x-bot <"+signerEmail+">", signature.Signer, "Signer")
+}
+
+func TestCommitSignatureIsValid(t *testing.T) {
+	testCases := []struct {
+		status               gitclient.SignatureStatus
+		valid                bool
+		allowingUnknownTrust bool
+	}{
+		{status: gitclient.SignatureGood, valid: true, allowingUnknownTrust: true},
+		{status: gitclient.SignatureUnknownValidity, valid: false, allowingUnknownTrust: true},
+		{status: gitclient.SignatureBad},
+		{status: gitclient.SignatureExpired},
+		{status: gitclient.SignatureNone},
+	}
+	for _, tc := range testCases {
+		s := &gitclient.CommitSignature{Status: tc.status}
+		assert.Equal(t, tc.valid, s.IsValid(), "IsValid for status %s", tc.status)
+		assert.Equal(t, tc.allowingUnknownTrust, s.IsValidAllowingUnknownTrust(), "IsValidAllowingUnknownTrust for status %s", tc.status)
+	}
+}
+
+func TestSigningConfigValidate(t *testing.T) {
+	err := gitclient.SetupSigning(nil, "", false, &gitclient.SigningConfig{Format: "pgp", Key: "ABC"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported git signing format pgp")
+
+	err = gitclient.SetupSigning(nil, "", false, &gitclient.SigningConfig{Format: gitclient.SigningFormatSSH})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "missing git signing key")
+}
+
+// createSigningRepo creates a repository to commit to
+func createSigningRepo(t *testing.T) (gitclient.Interface, string) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", signerEmail)
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", signerEmail)
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	dir := t.TempDir()
+	_, err := g.Command(dir, "init", "--initial-branch=master")
+	require.NoError(t, err, "failed to init")
+	return g, dir
+}
+
+// createSSHKey generates a SSH key returning the private key file and an allowed signers file for it
+func createSSHKey(t *testing.T) (string, string) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	tmpDir := t.TempDir()
+	keyFile := filepath.Join(tmpDir, "id_ed25519")
+	out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", signerEmail, "-f", keyFile).CombinedOutput()
+	require.NoError(t, err, "failed to generate ssh key: %s", string(out))
+
+	publicKey, err := os.ReadFile(keyFile + ".pub")
+	require.NoError(t, err)
+	allowedSigners := filepath.Join(tmpDir, "allowed_signers")
+	require.NoError(t, os.WriteFile(allowedSigners, []byte(signerEmail+" "+string(publicKey)), 0o600))
+	return keyFile, allowedSigners
+}
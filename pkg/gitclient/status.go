@@ -0,0 +1,323 @@
+package gitclient
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusEntryKind the kind of an entry in the git status
+type StatusEntryKind string
+
+const (
+	// StatusEntryChanged a tracked file which is changed in the index or the working tree
+	StatusEntryChanged StatusEntryKind = "changed"
+	// StatusEntryRenamed a tracked file which is renamed or copied in the index
+	StatusEntryRenamed StatusEntryKind = "renamed"
+	// StatusEntryUnmerged a file with unresolved merge conflicts
+	StatusEntryUnmerged StatusEntryKind = "unmerged"
+	// StatusEntryUntracked a file which is not tracked
+	StatusEntryUntracked StatusEntryKind = "untracked"
+	// StatusEntryIgnored a file which is ignored
+	StatusEntryIgnored StatusEntryKind = "ignored"
+)
+
+// StatusCode the git status code of a file in the index or the working tree
+type StatusCode byte
+
+const (
+	// StatusUnmodified the file is not modified
+	StatusUnmodified StatusCode = '.'
+	// StatusModified the file is modified
+	StatusModified StatusCode = 'M'
+	// StatusTypeChanged the type of the file changed
+	StatusTypeChanged StatusCode = 'T'
+	// StatusAdded the file is added
+	StatusAdded StatusCode = 'A'
+	// StatusDeleted the file is deleted
+	StatusDeleted StatusCode = 'D'
+	// StatusRenamed the file is renamed
+	StatusRenamed StatusCode = 'R'
+	// StatusCopied the file is copied
+	StatusCopied StatusCode = 'C'
+	// StatusUpdatedUnmerged the file is updated but unmerged
+	StatusUpdatedUnmerged StatusCode = 'U'
+)
+
+// String returns the status code as a string
+func (c StatusCode) String() string {
+	return string(c)
+}
+
+// StatusEntry a file in the git status
+type StatusEntry struct {
+	// Kind the kind of entry
+	Kind StatusEntryKind
+	// Path the path of the file relative to the root of the repository
+	Path string
+	// OriginalPath the path of the file before it was renamed or copied
+	OriginalPath string
+	// Index the status of the file in the index compared to HEAD
+	Index StatusCode
+	// WorkTree the status of the file in the working tree compared to the index
+	WorkTree StatusCode
+	// Score the similarity percentage of a renamed or copied file
+	Score int
+	// Submodule true if the file is a submodule
+	Submodule bool
+}
+
+// IsStaged returns true if the file has changes in the index
+func (e *StatusEntry) IsStaged() bool {
+	return (e.Kind == StatusEntryChanged || e.Kind == StatusEntryRenamed) && e.Index != StatusUnmodified
+}
+
+// IsUnstaged returns true if the file has changes in the working tree which are not in the index
+func (e *StatusEntry) IsUnstaged() bool {
+	return (e.Kind == StatusEntryChanged || e.Kind == StatusEntryRenamed) && e.WorkTree != StatusUnmodified
+}
+
+// IsRenamed returns true if the file is renamed or copied
+func (e *StatusEntry) IsRenamed() bool {
+	return e.Kind == StatusEntryRenamed
+}
+
+// IsConflicted returns true if the file has unresolved merge conflicts
+func (e *StatusEntry) IsConflicted() bool {
+	return e.Kind == StatusEntryUnmerged
+}
+
+// IsUntracked returns true if the file is not tracked
+func (e *StatusEntry) IsUntracked() bool {
+	return e.Kind == StatusEntryUntracked
+}
+
+// IsIgnored returns true if the file is ignored
+func (e *StatusEntry) IsIgnored() bool {
+	return e.Kind == StatusEntryIgnored
+}
+
+// BranchStatus the branch information of the git status
+type BranchStatus struct {
+	// OID the commit sha of HEAD which is empty if there are no commits yet
+	OID string
+	// Head the name of the current branch which is empty if the HEAD is detached
+	Head string
+	// Detached true if the HEAD is detached
+	Detached bool
+	// Upstream the upstream branch such as 'origin/master' or empty if there is no upstream
+	Upstream string
+	// Ahead the number of commits on the current branch which are not on the upstream branch
+	Ahead int
+	// Behind the number of commits on the upstream branch which are not on the current branch
+	Behind int
+}
+
+// RepositoryStatus the typed git status of a repository
+type RepositoryStatus struct {
+	// Branch the branch information
+	Branch BranchStatus
+	// Entries the files which are changed, untracked or ignored
+	Entries []StatusEntry
+}
+
+// StatusOptions the options for querying the git status
+type StatusOptions struct {
+	// Paths limits the status to the given paths if specified
+	Paths []string
+	// Ignored includes ignored files
+	Ignored bool
+	// UntrackedFiles the mode of showing untracked files which is one of 'no', 'normal' or 'all' and defaults to 'all'
+	UntrackedFiles string
+}
+
+// GetStatus returns the typed status of the repository in the given directory using 'git status --porcelain=v2'
+func GetStatus(g Interface, dir string, o *StatusOptions) (*RepositoryStatus, error) {
+	if o == nil {
+		o = &StatusOptions{}
+	}
+	untracked := o.UntrackedFiles
+	if untracked == "" {
+		untracked = "all"
+	}
+	args := []string{"status", "--porcelain=v2", "--branch", "-z", "--untracked-files=" + untracked}
+	if o.Ignored {
+		args = append(args, "--ignored")
+	}
+	if len(o.Paths) > 0 {
+		args = append(args, "--")
+		args = append(args, o.Paths...)
+	}
+	text, err := g.Command(dir, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the git status in dir %s: %w", dir, err)
+	}
+	return ParseStatus(text)
+}
+
+// ParseStatus parses the NUL terminated output of 'git status --porcelain=v2 --branch -z'
+func ParseStatus(text string) (*RepositoryStatus, error) {
+	answer := &RepositoryStatus{}
+	records := strings.Split(text, "\x00")
+	for i := 0; i < len(records); i++ {
+		record := strings.TrimPrefix(records[i], "\n")
+		if record == "" {
+			continue
+		}
+		switch record[0] {
+		case '#':
+			err := parseBranchHeader(&answer.Branch, record)
+			if err != nil {
+				return answer, err
+			}
+		case '1':
+			e, err := parseStatusEntry(record, 9, StatusEntryChanged)
+			if err != nil {
+				return answer, err
+			}
+			answer.Entries = append(answer.Entries, e)
+		case '2':
+			e, err := parseStatusEntry(record, 10, StatusEntryRenamed)
+			if err != nil {
+				return answer, err
+			}
+			fields := strings.SplitN(record, " ", 10)
+			e.Score, _ = strconv.Atoi(fields[8][1:])
+			if i+1 >= len(records) {
+				return answer, fmt.Errorf("missing original path of renamed file %s", e.Path)
+			}
+			i++
+			e.OriginalPath = records[i]
+			answer.Entries = append(answer.Entries, e)
+		case 'u':
+			e, err := parseStatusEntry(record, 11, StatusEntryUnmerged)
+			if err != nil {
+				return answer, err
+			}
+			answer.Entries = append(answer.Entries, e)
+		case '?':
+			answer.Entries = append(answer.Entries, StatusEntry{Kind: StatusEntryUntracked, Path: strings.TrimPrefix(record, "? ")})
+		case '!':
+			answer.Entries = append(answer.Entries, StatusEntry{Kind: StatusEntryIgnored, Path: strings.TrimPrefix(record, "! ")})
+		default:
+			return answer, fmt.Errorf("unexpected git status line: '%s'", record)
+		}
+	}
+	return answer, nil
+}
+
+// parseStatusEntry parses an entry with the given number of space separated fields where the last field is the path
+func parseStatusEntry(record string, fieldCount int, kind StatusEntryKind) (StatusEntry, error) {
+	fields := strings.SplitN(record, " ", fieldCount)
+	if len(fields) != fieldCount || len(fields[1]) != 2 {
+		return StatusEntry{}, fmt.Errorf("unexpected git status line: '%s'", record)
+	}
+	return StatusEntry{
+		Kind:      kind,
+		Path:      fields[fieldCount-1],
+		Index:     StatusCode(fields[1][0]),
+		WorkTree:  StatusCode(fields[1][1]),
+		Submodule: strings.HasPrefix(fields[2], "S"),
+	}, nil
+}
+
+// parseBranchHeader parses a '# branch.*' header line
+func parseBranchHeader(b *BranchStatus, record string) error {
+	fields := strings.Fields(strings.TrimPrefix(record, "#"))
+	if len(fields) < 2 {
+		return nil
+	}
+	switch fields[0] {
+	case "branch.oid":
+		if fields[1] != "(initial)" {
+			b.OID = fields[1]
+		}
+	case "branch.head":
+		if fields[1] == "(detached)" {
+			b.Detached = true
+		} else {
+			b.Head = fields[1]
+		}
+	case "branch.upstream":
+		b.Upstream = fields[1]
+	case "branch.ab":
+		if len(fields) != 3 {
+			return fmt.Errorf("unexpected git status branch line: '%s'", record)
+		}
+		var err error
+		b.Ahead, err = strconv.Atoi(strings.TrimPrefix(fields[1], "+"))
+		if err != nil {
+			return fmt.Errorf("failed to parse ahead count of '%s': %w", record, err)
+		}
+		b.Behind, err = strconv.Atoi(strings.TrimPrefix(fields[2], "-"))
+		if err != nil {
+			return fmt.Errorf("failed to parse behind count of '%s': %w", record, err)
+		}
+	}
+	return nil
+}
+
+// IsClean returns true if there are no changed, unmerged or untracked files
+func (s *RepositoryStatus) IsClean() bool {
+	for i := range s.Entries {
+		if !s.Entries[i].IsIgnored() {
+			return false
+		}
+	}
+	return true
+}
+
+// HasOnlyUntracked returns true if there are untracked files but no changes to tracked files
+func (s *RepositoryStatus) HasOnlyUntracked() bool {
+	answer := false
+	for i := range s.Entries {
+		e := &s.Entries[i]
+		switch {
+		case e.IsUntracked():
+			answer = true
+		case !e.IsIgnored():
+			return false
+		}
+	}
+	return answer
+}
+
+// Staged returns the files with changes in the index
+func (s *RepositoryStatus) Staged() []StatusEntry {
+	return s.filter((*StatusEntry).IsStaged)
+}
+
+// Unstaged returns the files with changes in the working tree which are not in the index
+func (s *RepositoryStatus) Unstaged() []StatusEntry {
+	return s.filter((*StatusEntry).IsUnstaged)
+}
+
+// Renamed returns the renamed or copied files
+func (s *RepositoryStatus) Renamed() []StatusEntry {
+	return s.filter((*StatusEntry).IsRenamed)
+}
+
+// Conflicted returns the files with unresolved merge conflicts
+func (s *RepositoryStatus) Conflicted() []StatusEntry {
+	return s.filter((*StatusEntry).IsConflicted)
+}
+
+// Untracked returns the untracked files
+func (s *RepositoryStatus) Untracked() []StatusEntry {
+	return s.filter((*StatusEntry).IsUntracked)
+}
+
+// Ignored returns the ignored files
+func (s *RepositoryStatus) Ignored() []StatusEntry {
+	return s.filter((*StatusEntry).IsIgnored)
+}
+
+func (s *RepositoryStatus) filter(fn func(*StatusEntry) bool) []StatusEntry {
+	var answer []StatusEntry
+	for i := range s.Entries {
+		if fn(&s.Entries[i]) {
+			answer = append(answer, s.Entries[i])
+		}
+	}
+	return answer
+}
@@ -0,0 +1,156 @@
+//go:build unit
+// +build unit
+
+package gitclient_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jenkins-x/jx-helpers/v3/pkg/cmdrunner"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/cli"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const porcelainV2Output = "# branch.oid 39b478f8ae18864b39b478f8ae18864b39b478f8\x00" +
+	"# branch.head master\x00" +
+	"# branch.upstream origin/master\x00" +
+	"# branch.ab +2 -1\x00" +
+	"1 M. N... 100644 100644 100644 e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 e69de29bb2d1d6434b8b29ae775ad8c2e48c5392 staged.txt\x00" +
+	"1 .M N... 100644 100644 100644 e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 dir/unstaged file.txt\x00" +
+	"2 R. N... 100644 100644 100644 e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 R95 new.txt\x00old.txt\x00" +
+	"u UU N... 100644 100644 100644 100644 e69de29bb2d1d6434b8b29ae775ad8c2e48c5391 e69de29bb2d1d6434b8b29ae775ad8c2e48c5392 e69de29bb2d1d6434b8b29ae775ad8c2e48c5393 conflict.txt\x00" +
+	"? generated.yaml\x00" +
+	"! build/output.bin\x00"
+
+func TestParseStatus(t *testing.T) {
+	s, err := gitclient.ParseStatus(porcelainV2Output)
+	require.NoError(t, err, "failed to parse status")
+
+	assert.Equal(t, gitclient.BranchStatus{
+		OID:      "39b478f8ae18864b39b478f8ae18864b39b478f8",
+		Head:     "master",
+		Upstream: "origin/master",
+		Ahead:    2,
+		Behind:   1,
+	}, s.Branch, "Branch")
+	require.Len(t, s.Entries, 6, "Entries")
+
+	assert.Equal(t, []string{"staged.txt", "new.txt"}, statusPaths(s.Staged()), "Staged")
+	assert.Equal(t, []string{"dir/unstaged file.txt"}, statusPaths(s.Unstaged()), "Unstaged")
+	assert.Equal(t, []string{"conflict.txt"}, statusPaths(s.Conflicted()), "Conflicted")
+	assert.Equal(t, []string{"generated.yaml"}, statusPaths(s.Untracked()), "Untracked")
+	assert.Equal(t, []string{"build/output.bin"}, statusPaths(s.Ignored()), "Ignored")
+
+	renamed := s.Renamed()
+	require.Len(t, renamed, 1, "Renamed")
+	assert.Equal(t, "old.txt", renamed[0].OriginalPath, "OriginalPath")
+	assert.Equal(t, 95, renamed[0].Score, "Score")
+	assert.Equal(t, gitclient.StatusRenamed, renamed[0].Index, "Index")
+	assert.False(t, s.IsClean(), "IsClean")
+	assert.False(t, s.HasOnlyUntracked(), "HasOnlyUntracked")
+
+	s, err = gitclient.ParseStatus("# branch.oid (initial)\x00# branch.head (detached)\x00? a.txt\x00! b.txt\x00")
+	require.NoError(t, err, "failed to parse status")
+	assert.Empty(t, s.Branch.OID, "OID of initial commit")
+	assert.True(t, s.Branch.Detached, "Detached")
+	assert.True(t, s.HasOnlyUntracked(), "HasOnlyUntracked")
+
+	_, err = gitclient.ParseStatus("1 M. invalid")
+	assert.Error(t, err, "should fail to parse an invalid entry")
+}
+
+func TestGetStatus(t *testing.T) {
+	t.Setenv("GIT_AUTHOR_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_AUTHOR_EMAIL", "jenkins-x@googlegroups.com")
+	t.Setenv("GIT_COMMITTER_NAME", "jenkins-x-bot")
+	t.Setenv("GIT_COMMITTER_EMAIL", "jenkins-x@googlegroups.com")
+
+	g := cli.NewCLIClient("", cmdrunner.QuietCommandRunner)
+	dir := t.TempDir()
+	require.NoError(t, gitclient.Init(g, dir), "failed to init")
+
+	s, err := gitclient.GetStatus(g, dir, nil)
+	require.NoError(t, err, "failed to get status")
+	assert.Empty(t, s.Branch.OID, "OID before the first commit")
+	assert.True(t, s.IsClean(), "IsClean")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".gitignore"), []byte("*.log\n"), 0o600))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "initial commit")
+	require.NoError(t, err, "failed to commit")
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "generated"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "generated", "c.yaml"), []byte("c: 1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "build.log"), []byte("log\n"), 0o600))
+
+	s, err = gitclient.GetStatus(g, dir, nil)
+	require.NoError(t, err, "failed to get status")
+	assert.NotEmpty(t, s.Branch.OID, "OID")
+	assert.True(t, s.HasOnlyUntracked(), "HasOnlyUntracked")
+	assert.Equal(t, []string{"generated/c.yaml"}, statusPaths(s.Untracked()), "Untracked")
+	assert.Empty(t, s.Ignored(), "Ignored without the option")
+
+	s, err = gitclient.GetStatus(g, dir, &gitclient.StatusOptions{Ignored: true})
+	require.NoError(t, err, "failed to get status")
+	assert.Equal(t, []string{"build.log"}, statusPaths(s.Ignored()), "Ignored")
+
+	_, err = g.Command(dir, "mv", "a.txt", "renamed.txt")
+	require.NoError(t, err, "failed to rename")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b2\n"), 0o600))
+
+	s, err = gitclient.GetStatus(g, dir, nil)
+	require.NoError(t, err, "failed to get status")
+	assert.False(t, s.HasOnlyUntracked(), "HasOnlyUntracked")
+	renamed := s.Renamed()
+	require.Len(t, renamed, 1, "Renamed")
+	assert.Equal(t, "renamed.txt", renamed[0].Path, "Path")
+	assert.Equal(t, "a.txt", renamed[0].OriginalPath, "OriginalPath")
+	assert.Equal(t, []string{"b.txt"}, statusPaths(s.Unstaged()), "Unstaged")
+
+	s, err = gitclient.GetStatus(g, dir, &gitclient.StatusOptions{Paths: []string{"b.txt"}})
+	require.NoError(t, err, "failed to get status")
+	assert.Equal(t, []string{"b.txt"}, statusPaths(s.Entries), "Entries for paths")
+}
+
+func TestGetStatusWithFakeGit(t *testing.T) {
+	gitURL := "https://github.com/myorg/myrepo.git"
+	g := fakegit.NewFakeGit()
+	remote, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create remote repository")
+
+	dir, err := gitclient.CloneToDir(g, gitURL, filepath.Join(t.TempDir(), "myrepo"))
+	require.NoError(t, err, "failed to clone")
+
+	_, err = remote.CommitFiles("remote change", map[string]string{"remote.txt": "r\n"})
+	require.NoError(t, err)
+	_, err = g.Command(dir, "fetch", "origin")
+	require.NoError(t, err, "failed to fetch")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "local.txt"), []byte("l\n"), 0o600))
+	_, err = gitclient.AddAndCommitFiles(g, dir, "local change")
+	require.NoError(t, err, "failed to commit")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.txt"), []byte("n\n"), 0o600))
+
+	s, err := gitclient.GetStatus(g, dir, nil)
+	require.NoError(t, err, "failed to get status")
+	assert.Equal(t, "master", s.Branch.Head, "Head")
+	assert.Equal(t, "origin/master", s.Branch.Upstream, "Upstream")
+	assert.Equal(t, 1, s.Branch.Ahead, "Ahead")
+	assert.Equal(t, 1, s.Branch.Behind, "Behind")
+	assert.Equal(t, []string{"README.md"}, statusPaths(s.Unstaged()), "Unstaged")
+	assert.Equal(t, []string{"new.txt"}, statusPaths(s.Untracked()), "Untracked")
+}
+
+func statusPaths(entries []gitclient.StatusEntry) []string {
+	var answer []string
+	for _, e := range entries {
+		answer = append(answer, e.Path)
+	}
+	return answer
+}
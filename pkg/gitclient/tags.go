@@ -0,0 +1,153 @@
+package gitclient
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blang/semver"
+)
+
+// VersionTag a git tag whose name is an optional prefix followed by a semantic version
+type VersionTag struct {
+	// Name the name of the tag such as 'v1.2.3' or 'chart-1.2.3'
+	Name string
+	// Prefix the prefix of the tag name before the version such as 'v' or 'chart-'
+	Prefix string
+	// Version the semantic version of the tag
+	Version semver.Version
+}
+
+// IsPreRelease returns true if the version is a pre-release
+func (t *VersionTag) IsPreRelease() bool {
+	return len(t.Version.Pre) > 0
+}
+
+// VersionTagName returns the name of the tag for the version with the prefix
+func VersionTagName(prefix string, version semver.Version) string {
+	return prefix + version.String()
+}
+
+// ParseVersionTag parses the tag name as the prefix followed by a semantic version returning false if it
+// does not have the prefix or is not a valid version
+func ParseVersionTag(name, prefix string) (*VersionTag, bool) {
+	if !strings.HasPrefix(name, prefix) {
+		return nil, false
+	}
+	v, err := semver.Parse(strings.TrimPrefix(name, prefix))
+	if err != nil {
+		return nil, false
+	}
+	return &VersionTag{Name: name, Prefix: prefix, Version: v}, true
+}
+
+// SortVersionTags sorts the tags into ascending semantic version order so that pre-releases come before the release.
+// Tags with the same version, which only differ by their build metadata, are sorted by name
+func SortVersionTags(tags []*VersionTag) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		c := tags[i].Version.Compare(tags[j].Version)
+		if c == 0 {
+			return tags[i].Name < tags[j].Name
+		}
+		return c < 0
+	})
+}
+
+// VersionTagOptions the options for finding version tags
+type VersionTagOptions struct {
+	// Prefix the prefix of the tag names before the version such as 'v' or 'chart-'
+	Prefix string
+	// Constraint an optional semantic version range such as '>=1.2.0 <2.0.0' or '1.x' the versions must match
+	Constraint string
+	// IncludePreReleases includes pre-release versions
+	IncludePreReleases bool
+}
+
+// VersionTags returns the tags in the repository at the given directory with the prefix and a semantic version
+// matching the options in ascending version order
+func VersionTags(g Interface, dir string, o *VersionTagOptions) ([]*VersionTag, error) {
+	if o == nil {
+		o = &VersionTagOptions{}
+	}
+	var constraint semver.Range
+	if o.Constraint != "" {
+		var err error
+		constraint, err = semver.ParseRange(o.Constraint)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse version constraint %s: %w", o.Constraint, err)
+		}
+	}
+	names, err := FilterTags(g, dir, o.Prefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the tags in dir %s: %w", dir, err)
+	}
+	var answer []*VersionTag
+	for _, name := range names {
+		tag, ok := ParseVersionTag(name, o.Prefix)
+		if !ok {
+			continue
+		}
+		if tag.IsPreRelease() && !o.IncludePreReleases {
+			continue
+		}
+		if constraint != nil && !constraint(tag.Version) {
+			continue
+		}
+		answer = append(answer, tag)
+	}
+	SortVersionTags(answer)
+	return answer, nil
+}
+
+// LatestVersionTag returns the tag with the highest semantic version matching the options or nil if there is none
+func LatestVersionTag(g Interface, dir string, o *VersionTagOptions) (*VersionTag, error) {
+	tags, err := VersionTags(g, dir, o)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	return tags[len(tags)-1], nil
+}
+
+// CreateAnnotatedTag creates an annotated tag with the message for the revision or HEAD if it is empty
+func CreateAnnotatedTag(g Interface, dir, tag, message, rev string) error {
+	if message == "" {
+		message = tag
+	}
+	args := []string{"tag", "-a", tag, "-m", message}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	_, err := g.Command(dir, args...)
+	if err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", tag, err)
+	}
+	return nil
+}
+
+// CreateVersionTag creates an annotated tag for the version with the prefix on HEAD returning the tag name
+func CreateVersionTag(g Interface, dir, prefix string, version semver.Version, message string) (string, error) {
+	tag := VersionTagName(prefix, version)
+	return tag, CreateAnnotatedTag(g, dir, tag, message, "")
+}
+
+// PushTags pushes the tags to the remote which defaults to origin
+func PushTags(g Interface, dir, remote string, tags ...string) error {
+	if remote == "" {
+		remote = "origin"
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	args := []string{"push", remote}
+	for _, tag := range tags {
+		args = append(args, "refs/tags/"+tag)
+	}
+	_, err := g.Command(dir, args...)
+	if err != nil {
+		return fmt.Errorf("failed to push tags %s to %s: %w", strings.Join(tags, ", "), remote, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+//go:build unit
+// +build unit
+
+package gitclient_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/fakegit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersionTag(t *testing.T) {
+	testCases := []struct {
+		name     string
+		prefix   string
+		expected string
+	}{
+		{name: "1.2.3", expected: "1.2.3"},
+		{name: "v1.2.3", prefix: "v", expected: "1.2.3"},
+		{name: "chart-1.2.3-rc.1", prefix: "chart-", expected: "1.2.3-rc.1"},
+		{name: "v1.2.3", expected: ""},
+		{name: "chart-1.2.3", prefix: "v", expected: ""},
+		{name: "v1.2", prefix: "v", expected: ""},
+		{name: "vnext", prefix: "v", expected: ""},
+	}
+	for _, tc := range testCases {
+		tag, ok := gitclient.ParseVersionTag(tc.name, tc.prefix)
+		if tc.expected == "" {
+			assert.False(t, ok, "should not parse %s with prefix %q", tc.name, tc.prefix)
+			continue
+		}
+		require.True(t, ok, "should parse %s with prefix %q", tc.name, tc.prefix)
+		assert.Equal(t, tc.expected, tag.Version.String(), "version of %s", tc.name)
+		assert.Equal(t, tc.prefix, tag.Prefix, "prefix of %s", tc.name)
+	}
+}
+
+func TestVersionTags(t *testing.T) {
+	g, dir := createTaggedRepo(t, "v1.9.0", "v1.10.0", "v1.10.0-rc.1", "v1.10.0-rc.10", "v1.10.0-rc.2", "v1.2.0", "v2.0.0-beta.1",
+		"chart-0.3.0", "chart-0.12.0", "vnext", "other")
+
+	tags, err := gitclient.VersionTags(g, dir, &gitclient.VersionTagOptions{Prefix: "v", IncludePreReleases: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"v1.2.0", "v1.9.0", "v1.10.0-rc.1", "v1.10.0-rc.2", "v1.10.0-rc.10", "v1.10.0", "v2.0.0-beta.1"}, tagNames(tags))
+
+	testCases := []struct {
+		options  gitclient.VersionTagOptions
+		expected string
+	}{
+		{options: gitclient.VersionTagOptions{Prefix: "v"}, expected: "v1.10.0"},
+		{options: gitclient.VersionTagOptions{Prefix: "v", IncludePreReleases: true}, expected: "v2.0.0-beta.1"},
+		{options: gitclient.VersionTagOptions{Prefix: "v", Constraint: "<1.10.0"}, expected: "v1.9.0"},
+		{options: gitclient.VersionTagOptions{Prefix: "v", Constraint: "<1.10.0", IncludePreReleases: true}, expected: "v1.10.0-rc.10"},
+		{options: gitclient.VersionTagOptions{Prefix: "v", Constraint: ">=1.2.0 <1.9.0"}, expected: "v1.2.0"},
+		{options: gitclient.VersionTagOptions{Prefix: "v", Constraint: ">=3.0.0"}, expected: ""},
+		{options: gitclient.VersionTagOptions{Prefix: "chart-"}, expected: "chart-0.12.0"},
+		{options: gitclient.VersionTagOptions{Prefix: "chart-", Constraint: "<0.10.0"}, expected: "chart-0.3.0"},
+		{options: gitclient.VersionTagOptions{}, expected: ""},
+	}
+	for _, tc := range testCases {
+		o := tc.options
+		tag, err := gitclient.LatestVersionTag(g, dir, &o)
+		require.NoError(t, err, "for options %#v", tc.options)
+		if tc.expected == "" {
+			assert.Nil(t, tag, "for options %#v", tc.options)
+			continue
+		}
+		require.NotNil(t, tag, "for options %#v", tc.options)
+		assert.Equal(t, tc.expected, tag.Name, "for options %#v", tc.options)
+	}
+
+	_, err = gitclient.LatestVersionTag(g, dir, &gitclient.VersionTagOptions{Constraint: "not a range"})
+	require.Error(t, err, "should fail to parse the constraint")
+}
+
+func TestCreateAndPushVersionTag(t *testing.T) {
+	g, dir := createTaggedRepo(t, "chart-1.9.0")
+	gitURL := "https://github.com/myorg/myrepo.git"
+
+	latest, err := gitclient.LatestVersionTag(g, dir, &gitclient.VersionTagOptions{Prefix: "chart-"})
+	require.NoError(t, err)
+	require.NotNil(t, latest)
+	next := latest.Version
+	next.Minor++
+
+	tag, err := gitclient.CreateVersionTag(g, dir, "chart-", next, "release chart 1.10.0")
+	require.NoError(t, err, "failed to create tag")
+	assert.Equal(t, "chart-1.10.0", tag)
+	repo := g.Repository(dir)
+	require.NotNil(t, repo.Tags[tag], "tag should be created")
+	assert.True(t, repo.Tags[tag].Annotated, "tag should be annotated")
+	assert.Equal(t, "release chart 1.10.0", repo.Tags[tag].Message, "tag message")
+
+	err = gitclient.PushTags(g, dir, "", tag)
+	require.NoError(t, err, "failed to push tag")
+	remote := g.Repository(gitURL)
+	require.NotNil(t, remote.Tags[tag], "tag should be pushed")
+	assert.Equal(t, repo.Tags[tag].SHA, remote.Tags[tag].SHA, "pushed tag SHA")
+
+	latest, err = gitclient.LatestVersionTag(g, dir, &gitclient.VersionTagOptions{Prefix: "chart-"})
+	require.NoError(t, err)
+	assert.Equal(t, semver.MustParse("1.10.0"), latest.Version, "latest version")
+}
+
+// createTaggedRepo clones a fake repository and creates the tags
+func createTaggedRepo(t *testing.T, tags ...string) (*fakegit.FakeGit, string) {
+	g := fakegit.NewFakeGit()
+	gitURL := "https://github.com/myorg/myrepo.git"
+	_, err := g.CreateRepository(gitURL, map[string]string{"README.md": "hello\n"})
+	require.NoError(t, err, "failed to create remote repository")
+
+	dir, err := gitclient.CloneToDir(g, gitURL, filepath.Join(t.TempDir(), "myrepo"))
+	require.NoError(t, err, "failed to clone")
+	for _, tag := range tags {
+		_, err = g.Command(dir, "tag", tag)
+		require.NoError(t, err, "failed to create tag %s", tag)
+	}
+	return g, dir
+}
+
+func tagNames(tags []*gitclient.VersionTag) []string {
+	var answer []string
+	for _, tag := range tags {
+		answer = append(answer, tag.Name)
+	}
+	return answer
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/jenkins-x/jx-logging/v3/pkg/log"
 
 	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/credentialhelper"
+	"github.com/jenkins-x/jx-helpers/v3/pkg/gitclient/gitcache"
 
 	jxcore "github.com/jenkins-x/jx-api/v4/pkg/apis/core/v4beta1"
 	"github.com/jenkins-x/jx-api/v4/pkg/client/clientset/versioned"
@@ -61,6 +62,8 @@ func GetRequirementsAndGit(g gitclient.Interface, gitURL string) (*jxcore.Requir
 	return &requirements.Spec, dir, nil
 }
 
+// CloneClusterRepo clones the cluster repo to a temporary directory and returns the directory path.
+// The git mirror cache is used if the $JX_GIT_CACHE environment variable is true
 func CloneClusterRepo(g gitclient.Interface, gitURL string) (string, error) {
 	// if we have a kubernetes secret with git auth mounted to the filesystem when running in cluster
 	// we need to turn it into a git credentials file see https://git-scm.com/docs/git-credential-store
@@ -69,8 +72,17 @@ func CloneClusterRepo(g gitclient.Interface, gitURL string) (string, error) {
 		return "", err
 	}
 
-	// clone cluster repo to a temp dir and load the requirements
-	dir, err := gitclient.CloneToDir(g, gitURL, "")
+	// clone cluster repo to a temp dir and load the requirements using the mirror cache if it is enabled
+	cache, err := gitcache.FromEnvironment()
+	if err != nil {
+		return "", fmt.Errorf("failed to create the git mirror cache: %w", err)
+	}
+	var dir string
+	if cache != nil {
+		dir, err = cache.CloneToDir(g, gitURL, "")
+	} else {
+		dir, err = gitclient.CloneToDir(g, gitURL, "")
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to clone cluster git repo %s: %w", gitURL, err)
 	}